@@ -3,6 +3,7 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,7 +12,10 @@ import (
 	"time"
 
 	"account/backend/database"
+	"account/backend/middleware"
 	"account/backend/models"
+	"account/backend/services"
+	"account/backend/utils"
 
 	"github.com/gorilla/mux"
 )
@@ -21,11 +25,12 @@ type AccountHandler struct{}
 
 // 添加账务记录请求结构
 type CreateAccountRequest struct {
-	StoreID         int64   `json:"store_id"`
-	TypeID          int64   `json:"type_id"`
-	Amount          float64 `json:"amount"`
-	Remark          string  `json:"remark"`
-	TransactionTime string  `json:"transaction_time,omitempty"` // 可选，默认为当前时间
+	StoreID         int64        `json:"store_id"`
+	TypeID          int64        `json:"type_id"`
+	Amount          models.Money `json:"amount"`
+	Currency        string       `json:"currency,omitempty"` // 可选，默认为店铺的币种
+	Remark          string       `json:"remark"`
+	TransactionTime string       `json:"transaction_time,omitempty"` // 可选，默认为当前时间
 }
 
 // 添加账务记录
@@ -35,16 +40,16 @@ func (h *AccountHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 从请求中解析用户ID
-	userIDStr := r.Header.Get("X-User-ID")
-	if userIDStr == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未经授权的请求", nil)
-		return
-	}
+	// 记录人为当前登录用户
+	userID := middleware.CurrentUserID(r)
 
 	// 解析请求体
 	var req CreateAccountRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if errors.Is(err, models.ErrInvalidMoney) {
+			SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
+			return
+		}
 		log.Printf("解析请求体失败: %v", err)
 		SendResponse(w, http.StatusBadRequest, 400, "请求参数错误", nil)
 		return
@@ -61,43 +66,19 @@ func (h *AccountHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 转换用户ID
-	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	formattedTime, err := parseTransactionTime(req.TransactionTime, storeLocationOrDefault(req.StoreID))
 	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "用户ID格式错误", nil)
+		SendResponse(w, http.StatusBadRequest, 400, "交易日期格式错误", nil)
 		return
 	}
 
-	// 处理日期格式转换
-	layout := "2006-01-02 15:04"
-	layoutWithSeconds := "2006-01-02 15:04:05"
-
-	var formattedTime string
-
-	// 尝试解析完整格式（带秒）
-	_, err = time.Parse(layoutWithSeconds, req.TransactionTime)
-	if err == nil {
-		// 如果是完整格式，直接使用
-		formattedTime = req.TransactionTime
-	} else {
-		// 尝试解析不带秒的格式
-		t, err := time.Parse(layout, req.TransactionTime)
-		if err == nil {
-			// 转换为标准格式（带秒）
-			formattedTime = t.Format(layoutWithSeconds)
-		} else {
-			// 尝试解析仅日期格式
-			layoutDate := "2006-01-02"
-			t, err = time.Parse(layoutDate, req.TransactionTime)
-			if err == nil {
-				// 添加默认时间 00:00:00
-				formattedTime = t.Format(layoutWithSeconds)
-			} else {
-				// 格式无效
-				SendResponse(w, http.StatusBadRequest, 400, "交易日期格式错误", nil)
-				return
-			}
-		}
+	amount, isExpense, ok := resolveAccountAmount(w, req.TypeID, req.Amount)
+	if !ok {
+		return
+	}
+	currency, ok := parseAccountCurrency(w, req.Currency)
+	if !ok {
+		return
 	}
 
 	// 使用格式化后的日期时间
@@ -105,7 +86,9 @@ func (h *AccountHandler) Create(w http.ResponseWriter, r *http.Request) {
 		StoreID:         req.StoreID,
 		UserID:          userID,
 		TypeID:          req.TypeID,
-		Amount:          req.Amount,
+		Amount:          amount,
+		Currency:        currency,
+		IsExpense:       isExpense,
 		Remark:          req.Remark,
 		TransactionTime: formattedTime,
 		CreateTime:      time.Now(),
@@ -115,6 +98,10 @@ func (h *AccountHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// 保存到数据库
 	id, err := database.CreateAccount(account)
 	if err != nil {
+		if errors.Is(err, models.ErrPeriodClosed) {
+			SendResponse(w, http.StatusConflict, 409, err.Error(), nil)
+			return
+		}
 		log.Printf("创建账务记录失败: %v", err)
 		SendResponse(w, http.StatusInternalServerError, 500, "创建账务记录失败", nil)
 
@@ -125,6 +112,305 @@ func (h *AccountHandler) Create(w http.ResponseWriter, r *http.Request) {
 	SendResponse(w, http.StatusOK, 200, "创建账务记录成功", account)
 }
 
+// 修改账务记录请求结构
+type UpdateAccountRequest struct {
+	ID int64 `json:"id"`
+	CreateAccountRequest
+}
+
+// Update 修改账务记录，记录人和创建时间保持不变，修改前的内容保存为历史版本
+func (h *AccountHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "仅支持PUT请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UpdateAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if errors.Is(err, models.ErrInvalidMoney) {
+			SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
+			return
+		}
+		log.Printf("解析请求体失败: %v", err)
+		SendResponse(w, http.StatusBadRequest, 400, "请求参数错误", nil)
+		return
+	}
+
+	// 参数验证，与创建时一致
+	if req.ID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "账目ID无效", nil)
+		return
+	}
+	if req.StoreID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "店铺ID无效", nil)
+		return
+	}
+	if req.TypeID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "账务类型ID无效", nil)
+		return
+	}
+	formattedTime, err := parseTransactionTime(req.TransactionTime, storeLocationOrDefault(req.StoreID))
+	if err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "交易日期格式错误", nil)
+		return
+	}
+
+	account, err := database.GetAccountByID(req.ID)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "账目不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("获取账目%d失败: %v", req.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改账务记录失败", nil)
+		return
+	}
+
+	// 转账记录只能修改金额、备注和交易时间，并同步到对方店铺的记录
+	if account.TransferPeerID != 0 {
+		if req.StoreID != account.StoreID || req.TypeID != account.TypeID {
+			SendResponse(w, http.StatusBadRequest, 400, "转账记录不能修改店铺和类型，请删除后重新转账", nil)
+			return
+		}
+		if req.Amount <= 0 {
+			SendResponse(w, http.StatusBadRequest, 400, "转账金额必须大于0", nil)
+			return
+		}
+
+		account.Amount = req.Amount
+		account.Remark = req.Remark
+		account.TransactionTime = formattedTime
+		account.UpdateTime = time.Now()
+
+		// 对方记录属于另一个店铺，单独记录审计日志
+		peerBefore := middleware.LoadAuditSnapshot(middleware.AuditAccount, account.TransferPeerID)
+		if err := database.UpdateTransfer(account, middleware.CurrentUserID(r)); err != nil {
+			if errors.Is(err, models.ErrPeriodClosed) {
+				SendResponse(w, http.StatusConflict, 409, err.Error(), nil)
+				return
+			}
+			log.Printf("修改转账记录%d失败: %v", req.ID, err)
+			SendResponse(w, http.StatusInternalServerError, 500, "修改账务记录失败", nil)
+			return
+		}
+		if peerStoreID, err := database.GetAccountStoreID(int(account.TransferPeerID)); err == nil {
+			middleware.RecordAudit(r, models.AuditActionUpdate, middleware.AuditAccount, account.TransferPeerID, int64(peerStoreID),
+				peerBefore, middleware.LoadAuditSnapshot(middleware.AuditAccount, account.TransferPeerID))
+		} else {
+			log.Printf("获取转账对方记录%d的店铺失败: %v", account.TransferPeerID, err)
+		}
+
+		SendResponse(w, http.StatusOK, 200, "修改账务记录成功", account)
+		return
+	}
+
+	amount, isExpense, ok := resolveAccountAmount(w, req.TypeID, req.Amount)
+	if !ok {
+		return
+	}
+	// 未传币种时保持原币种
+	if req.Currency != "" {
+		if account.Currency, ok = parseAccountCurrency(w, req.Currency); !ok {
+			return
+		}
+	}
+
+	account.StoreID = req.StoreID
+	account.TypeID = req.TypeID
+	account.Amount = amount
+	account.IsExpense = isExpense
+	account.Remark = req.Remark
+	account.TransactionTime = formattedTime
+	account.UpdateTime = time.Now()
+
+	if err := database.UpdateAccount(account, middleware.CurrentUserID(r)); err != nil {
+		if errors.Is(err, models.ErrPeriodClosed) {
+			SendResponse(w, http.StatusConflict, 409, err.Error(), nil)
+			return
+		}
+		log.Printf("修改账目%d失败: %v", req.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改账务记录失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "修改账务记录成功", account)
+}
+
+// History 获取账务记录的当前内容和全部历史版本
+func (h *AccountHandler) History(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "账目ID无效", nil)
+		return
+	}
+
+	account, err := database.GetAccountByID(id)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "账目不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("获取账目%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取修改历史失败", nil)
+		return
+	}
+
+	history, err := database.GetAccountHistory(id)
+	if err != nil {
+		log.Printf("获取账目%d的修改历史失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取修改历史失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取修改历史成功", map[string]interface{}{
+		"current": account,
+		"history": history,
+	})
+}
+
+// RecycleBin 分页获取回收站中的账务记录，可按店铺筛选
+func (h *AccountHandler) RecycleBin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	storeID, _ := strconv.ParseInt(query.Get("store_id"), 10, 64)
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	accounts, total, err := database.GetDeletedAccounts(storeID, page, pageSize)
+	if err != nil {
+		log.Printf("获取回收站记录失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取回收站记录失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取回收站记录成功", map[string]interface{}{
+		"data":           accounts,
+		"total":          total,
+		"retention_days": services.RecycleBinRetentionDays(),
+	})
+}
+
+// Restore 将回收站中的账务记录恢复
+func (h *AccountHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "账目ID无效", nil)
+		return
+	}
+
+	if err := database.RestoreAccount(req.ID); err != nil {
+		if err == sql.ErrNoRows {
+			SendResponse(w, http.StatusNotFound, 404, "回收站中没有该账目", nil)
+			return
+		}
+		if errors.Is(err, models.ErrPeriodClosed) {
+			SendResponse(w, http.StatusConflict, 409, err.Error(), nil)
+			return
+		}
+		log.Printf("恢复账目%d失败: %v", req.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "恢复账目失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "恢复账目成功", nil)
+}
+
+// resolveAccountAmount 金额统一保存为正数，收支方向由账务类型决定。
+// 兼容以负数表示支出的旧客户端，支出类型的负数金额按绝对值保存；校验失败时已写入响应，返回ok为false
+func resolveAccountAmount(w http.ResponseWriter, typeID int64, amount models.Money) (models.Money, bool, bool) {
+	isExpense, err := database.IsExpenseAccountType(typeID)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusBadRequest, 400, "账务类型不存在", nil)
+		return 0, false, false
+	}
+	if err != nil {
+		log.Printf("查询账务类型%d失败: %v", typeID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "查询账务类型失败", nil)
+		return 0, false, false
+	}
+
+	amount, msg := checkAccountAmount(amount, isExpense)
+	if msg != "" {
+		SendResponse(w, http.StatusBadRequest, 400, msg, nil)
+		return 0, false, false
+	}
+	return amount, isExpense, true
+}
+
+// checkAccountAmount 检查金额是否有效，支出类型允许传入负数并转为正数；无效时返回错误提示
+func checkAccountAmount(amount models.Money, isExpense bool) (models.Money, string) {
+	if amount < 0 && isExpense {
+		amount = -amount
+	}
+	if amount <= 0 {
+		return 0, "金额必须大于0，收支方向由账务类型决定"
+	}
+	return amount, ""
+}
+
+// parseAccountCurrency 检查并统一账务记录的币种代码，为空表示使用店铺的币种；无效时已写入响应
+func parseAccountCurrency(w http.ResponseWriter, value string) (string, bool) {
+	if value == "" {
+		return "", true
+	}
+	currency, ok := models.NormalizeCurrency(value)
+	if !ok {
+		SendResponse(w, http.StatusBadRequest, 400, "币种应为三位字母代码，如CNY、USD", nil)
+		return "", false
+	}
+	return currency, true
+}
+
+// parseTransactionTime 将交易时间统一为店铺时区loc的本地时间，格式为"2006-01-02 15:04:05"。
+// 不带时区的时间按店铺时区理解，支持省略秒或只有日期；带时区的RFC3339时间换算到店铺时区；为空时使用当前时间
+func parseTransactionTime(value string, loc *time.Location) (string, error) {
+	if value == "" {
+		return time.Now().In(loc).Format(models.DateTimeLayout), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc).Format(models.DateTimeLayout), nil
+	}
+	layouts := []string{models.DateTimeLayout, "2006-01-02 15:04", "2006-01-02"}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.Format(models.DateTimeLayout), nil
+		}
+	}
+	return "", fmt.Errorf("无效的交易时间: %s", value)
+}
+
+// storeLocationOrDefault 获取店铺的时区，查询失败时记录日志并使用业务时区
+func storeLocationOrDefault(storeID int64) *time.Location {
+	loc, err := database.StoreLocation(storeID)
+	if err != nil {
+		log.Printf("获取店铺%d的时区失败，使用业务时区: %v", storeID, err)
+		return utils.BusinessLocation()
+	}
+	return loc
+}
+
 func (h *AccountHandler) Test(w http.ResponseWriter, r *http.Request) {
 	SendResponse(w, http.StatusOK, 200, "测试成功", "")
 }
@@ -147,22 +433,8 @@ func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
 	maxAmount := r.URL.Query().Get("max_amount")
 	keyword := r.URL.Query().Get("keyword")
 
-	// 从URL参数或Header中获取用户ID
-	userIDStr := r.URL.Query().Get("userId")
-	if userIDStr == "" {
-		userIDStr = r.Header.Get("X-User-ID")
-	}
-
-	if userIDStr == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未经授权的请求", nil)
-		return
-	}
-
-	userID, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-		return
-	}
+	// 当前登录用户，用于店铺权限过滤
+	userID := middleware.CurrentUserID(r)
 
 	// 增强店铺ID处理
 	if storeID != "" {
@@ -212,7 +484,7 @@ func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
 
 				// 测试该店铺的账目数量
 				var accountCount int
-				countErr := database.DB.QueryRow("SELECT COUNT(*) FROM accounts WHERE store_id = ?", storeIDInt).Scan(&accountCount)
+				countErr := database.DB.QueryRow("SELECT COUNT(*) FROM accounts WHERE store_id = ? AND deleted_at IS NULL", storeIDInt).Scan(&accountCount)
 				if countErr != nil {
 					log.Printf("【API筛选调试】查询store_id=%d的账目数量失败: %v", storeIDInt, countErr)
 				} else {
@@ -273,22 +545,8 @@ func (h *AccountHandler) GetStatistics(w http.ResponseWriter, r *http.Request) {
 	minAmount := r.URL.Query().Get("min_amount")
 	maxAmount := r.URL.Query().Get("max_amount")
 
-	// 从URL参数或Header中获取用户ID
-	userIDStr := r.URL.Query().Get("userId")
-	if userIDStr == "" {
-		userIDStr = r.Header.Get("X-User-ID")
-	}
-
-	if userIDStr == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未经授权的请求", nil)
-		return
-	}
-
-	userID, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-		return
-	}
+	// 当前登录用户，用于店铺权限过滤
+	userID := middleware.CurrentUserID(r)
 
 	// 增强店铺ID处理
 	if storeID != "" {
@@ -336,8 +594,14 @@ func (h *AccountHandler) GetStatistics(w http.ResponseWriter, r *http.Request) {
 	log.Printf("统计请求参数: storeID=%s, typeID=%s, startDate=%s, endDate=%s, minAmount=%s, maxAmount=%s",
 		storeID, typeID, startDate, endDate, minAmount, maxAmount)
 
+	storeIDInt, _ := strconv.ParseInt(storeID, 10, 64)
+	currency, ok := resolveReportCurrency(w, r, storeIDInt)
+	if !ok {
+		return
+	}
+
 	// 调用GetAccountStatistics函数，传入所有筛选参数
-	statistics, err := database.GetAccountStatistics(storeID, typeID, startDate, endDate, minAmount, maxAmount, userID)
+	statistics, err := database.GetAccountStatistics(storeID, typeID, startDate, endDate, minAmount, maxAmount, userID, currency)
 	if err != nil {
 		log.Printf("获取账务统计失败: %v", err)
 		SendResponse(w, http.StatusInternalServerError, 500, "获取账务统计失败", nil)
@@ -365,13 +629,17 @@ func DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 调用数据库函数删除账目
-	err = database.DeleteAccount(id)
+	err = database.DeleteAccount(id, middleware.CurrentUserID(r))
 	if err != nil {
 		// 如果是记录不存在
 		if err == sql.ErrNoRows {
 			SendResponse(w, http.StatusNotFound, 404, "账目不存在", nil)
 			return
 		}
+		if errors.Is(err, models.ErrPeriodClosed) {
+			SendResponse(w, http.StatusConflict, 409, err.Error(), nil)
+			return
+		}
 
 		// 其他数据库错误
 		SendResponse(w, http.StatusInternalServerError, 500, "删除账目失败: "+err.Error(), nil)
@@ -404,12 +672,16 @@ func DeleteAccountByQuery(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 复用删除逻辑
-	err = database.DeleteAccount(id)
+	err = database.DeleteAccount(id, middleware.CurrentUserID(r))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			SendResponse(w, http.StatusNotFound, 404, "账目不存在", nil)
 			return
 		}
+		if errors.Is(err, models.ErrPeriodClosed) {
+			SendResponse(w, http.StatusConflict, 409, err.Error(), nil)
+			return
+		}
 
 		SendResponse(w, http.StatusInternalServerError, 500, "删除账目失败: "+err.Error(), nil)
 		return
@@ -486,7 +758,7 @@ func (h *AccountHandler) Statistics(w http.ResponseWriter, r *http.Request) {
 
 				// 测试该店铺的账目数量
 				var accountCount int
-				countErr := database.DB.QueryRow("SELECT COUNT(*) FROM accounts WHERE store_id = ?", storeIDInt).Scan(&accountCount)
+				countErr := database.DB.QueryRow("SELECT COUNT(*) FROM accounts WHERE store_id = ? AND deleted_at IS NULL", storeIDInt).Scan(&accountCount)
 				if countErr != nil {
 					log.Printf("【API筛选调试】查询store_id=%d的账目数量失败: %v", storeIDInt, countErr)
 				} else {
@@ -500,25 +772,17 @@ func (h *AccountHandler) Statistics(w http.ResponseWriter, r *http.Request) {
 		log.Printf("【统计API调试】店铺ID为空")
 	}
 
-	// 从URL参数或Header中获取用户ID
-	userIDStr := r.URL.Query().Get("userId")
-	if userIDStr == "" {
-		userIDStr = r.Header.Get("X-User-ID")
-	}
+	// 当前登录用户，用于店铺权限过滤
+	userID := middleware.CurrentUserID(r)
 
-	if userIDStr == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未经授权的请求", nil)
-		return
-	}
-
-	userID, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
+	storeIDInt, _ := strconv.ParseInt(storeID, 10, 64)
+	currency, ok := resolveReportCurrency(w, r, storeIDInt)
+	if !ok {
 		return
 	}
 
 	// 调用数据库函数获取统计数据
-	stats, err := database.GetAccountStatistics(storeID, typeID, startDate, endDate, minAmount, maxAmount, userID)
+	stats, err := database.GetAccountStatistics(storeID, typeID, startDate, endDate, minAmount, maxAmount, userID, currency)
 	if err != nil {
 		SendResponse(w, http.StatusInternalServerError, 500, "获取统计数据失败: "+err.Error(), nil)
 		return
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+	"account/backend/services"
+)
+
+// accountExportHeader 导出文件的表头
+var accountExportHeader = []string{"ID", "交易时间", "店铺", "账务类型", "收支", "金额", "币种", "备注", "记录人", "创建时间"}
+
+// accountExportSummary 导出时按行累计的汇总数据，每个币种一份
+type accountExportSummary struct {
+	currency                string
+	count                   int
+	income, expense         models.Money
+	transferIn, transferOut models.Money
+}
+
+// add 累计一条账目，店铺间转账单独统计，不计入收支
+func (s *accountExportSummary) add(account map[string]interface{}) {
+	amount, _ := account["amount"].(models.Money)
+
+	s.count++
+	switch accountDirection(account) {
+	case "转出":
+		s.transferOut += amount
+	case "转入":
+		s.transferIn += amount
+	case "支出":
+		s.expense += amount
+	default:
+		s.income += amount
+	}
+}
+
+// accountExportSummaries 按币种分别汇总，不同币种的金额不能直接相加
+type accountExportSummaries struct {
+	byCurrency map[string]*accountExportSummary
+	order      []string // 币种首次出现的顺序
+}
+
+// add 将账目累计到所属币种的汇总
+func (s *accountExportSummaries) add(account map[string]interface{}) {
+	currency := fmt.Sprint(account["currency"])
+	if s.byCurrency == nil {
+		s.byCurrency = map[string]*accountExportSummary{}
+	}
+	summary, ok := s.byCurrency[currency]
+	if !ok {
+		summary = &accountExportSummary{currency: currency}
+		s.byCurrency[currency] = summary
+		s.order = append(s.order, currency)
+	}
+	summary.add(account)
+}
+
+// Export 按与账目列表相同的筛选条件和权限导出全部账目，format为csv（默认）或xlsx。
+// XLSX文件的第二个工作表为导出数据按币种的收支汇总
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		SendResponse(w, http.StatusBadRequest, 400, "导出格式只支持csv和xlsx", nil)
+		return
+	}
+
+	userID := strconv.FormatInt(middleware.CurrentUserID(r), 10)
+	storeID := normalizeStoreIDParam(query.Get("store_id"))
+	typeID, keyword := query.Get("type_id"), query.Get("keyword")
+	startDate, endDate := query.Get("start_date"), query.Get("end_date")
+	minAmount, maxAmount := query.Get("min_amount"), query.Get("max_amount")
+
+	export := func(handle func(account map[string]interface{}) error) error {
+		return database.ExportAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount, maxAmount, handle)
+	}
+
+	filename := fmt.Sprintf("accounts_%s.%s", time.Now().Format("20060102_150405"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	// 数据边读边写，开始输出后出错只能记录日志
+	var err error
+	if format == "xlsx" {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		err = exportAccountsXLSX(w, export)
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		err = exportAccountsCSV(w, export)
+	}
+	if err != nil {
+		log.Printf("导出账务记录失败: %v", err)
+	}
+}
+
+// exportAccountsCSV 写出UTF-8编码的CSV，带BOM以便Excel正确识别中文；文本列中可能被当作公式的内容加上'前缀
+func exportAccountsCSV(w http.ResponseWriter, export func(func(map[string]interface{}) error) error) error {
+	if _, err := w.Write([]byte("\xef\xbb\xbf")); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(accountExportHeader); err != nil {
+		return err
+	}
+
+	err := export(func(account map[string]interface{}) error {
+		amount, _ := account["amount"].(models.Money)
+		return writer.Write([]string{
+			fmt.Sprint(account["id"]),
+			fmt.Sprint(account["transaction_time"]),
+			services.EscapeCSVText(fmt.Sprint(account["store_name"])),
+			services.EscapeCSVText(fmt.Sprint(account["type_name"])),
+			accountDirection(account),
+			amount.String(),
+			fmt.Sprint(account["currency"]),
+			services.EscapeCSVText(fmt.Sprint(account["remark"])),
+			services.EscapeCSVText(fmt.Sprint(account["username"])),
+			fmt.Sprint(account["create_time"]),
+		})
+	})
+	writer.Flush()
+	if err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// exportAccountsXLSX 写出XLSX，金额为数字、时间为日期格式，便于在Excel中计算和筛选
+func exportAccountsXLSX(w http.ResponseWriter, export func(func(map[string]interface{}) error) error) error {
+	writer, err := services.NewXLSXWriter(w, "账务记录", "汇总")
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteHeader(accountExportHeader...); err != nil {
+		return err
+	}
+
+	var summaries accountExportSummaries
+	err = export(func(account map[string]interface{}) error {
+		summaries.add(account)
+		return writer.WriteRow(
+			account["id"],
+			exportTime(account["transaction_time"]),
+			account["store_name"],
+			account["type_name"],
+			accountDirection(account),
+			account["amount"],
+			account["currency"],
+			account["remark"],
+			account["username"],
+			exportTime(account["create_time"]),
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writer.NextSheet(); err != nil {
+		return err
+	}
+	if err := writer.WriteHeader("币种", "收入合计", "支出合计", "净收入", "转入合计", "转出合计", "记录数"); err != nil {
+		return err
+	}
+	for _, currency := range summaries.order {
+		summary := summaries.byCurrency[currency]
+		err := writer.WriteRow(
+			summary.currency,
+			summary.income,
+			summary.expense,
+			summary.income-summary.expense,
+			summary.transferIn,
+			summary.transferOut,
+			summary.count,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// accountDirection 账目的收支方向，店铺间转账显示为转入或转出
+func accountDirection(account map[string]interface{}) string {
+	isExpense, _ := account["is_expense"].(bool)
+	isTransfer := account["transfer_peer_id"] != int64(0)
+	switch {
+	case isTransfer && isExpense:
+		return "转出"
+	case isTransfer:
+		return "转入"
+	case isExpense:
+		return "支出"
+	default:
+		return "收入"
+	}
+}
+
+// exportTime 将列表中格式化后的时间转换回time.Time，无法解析时按原文本导出
+func exportTime(value interface{}) interface{} {
+	text, _ := value.(string)
+	if t, err := time.Parse("2006-01-02 15:04:05", text); err == nil {
+		return t
+	}
+	return value
+}
+
+// normalizeStoreIDParam 与账目列表相同地处理店铺ID参数：空值和特殊值视为不筛选，浮点数取整
+func normalizeStoreIDParam(storeID string) string {
+	storeID = strings.TrimSpace(storeID)
+	value, err := strconv.ParseFloat(storeID, 64)
+	if err != nil || value <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(value), 10)
+}
@@ -0,0 +1,503 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+	"account/backend/services"
+)
+
+// maxImportRows 单次导入的最大行数
+const maxImportRows = 5000
+
+// 导入行的处理结果
+const (
+	importRowOK        = "ok"        // 可以导入
+	importRowDuplicate = "duplicate" // 与已有记录或文件中前面的行重复
+	importRowError     = "error"     // 校验失败
+)
+
+// importColumnAliases 导入字段对应的默认表头，表头不区分大小写
+var importColumnAliases = map[string][]string{
+	"store":            {"店铺", "店铺名称", "门店", "store", "store_name"},
+	"type":             {"类型", "账务类型", "类型名称", "type", "type_name"},
+	"amount":           {"金额", "amount"},
+	"transaction_time": {"交易时间", "交易日期", "时间", "日期", "transaction_time", "time", "date"},
+	"remark":           {"备注", "说明", "remark", "note"},
+}
+
+// ImportRow 导入文件中一行数据的解析和校验结果
+type ImportRow struct {
+	Row             int          `json:"row"` // 在表格中的行号，表头为第1行
+	StoreID         int64        `json:"store_id"`
+	StoreName       string       `json:"store_name"`
+	TypeID          int64        `json:"type_id"`
+	TypeName        string       `json:"type_name"`
+	Amount          models.Money `json:"amount"`
+	IsExpense       bool         `json:"is_expense"`
+	TransactionTime string       `json:"transaction_time"`
+	Remark          string       `json:"remark"`
+	Status          string       `json:"status"`
+	Errors          []string     `json:"errors,omitempty"`
+	AccountID       int64        `json:"account_id,omitempty"` // 正式导入后新记录的ID
+}
+
+// ImportResult 导入结果，预览和正式导入返回相同的结构
+type ImportResult struct {
+	DryRun     bool        `json:"dry_run"`
+	Total      int         `json:"total"`
+	Valid      int         `json:"valid"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Imported   int         `json:"imported"`
+	AccountIDs []int64     `json:"account_ids,omitempty"` // 正式导入的记录ID，按行号排列
+	StoreIDs   []int64     `json:"store_ids,omitempty"`   // 正式导入涉及的店铺
+	Rows       []ImportRow `json:"rows"`
+}
+
+// accountImporter 保存导入过程中用到的店铺、类型和权限信息，避免逐行重复查询
+type accountImporter struct {
+	r            *http.Request
+	isAdmin      bool
+	isXLSX       bool
+	defaultStore int64
+	stores       map[int64]string
+	storeIDs     map[string]int64
+	types        map[string]models.AccountType
+	storeAccess  map[int64]bool
+	typeVisible  map[[2]int64]bool
+	accepted     map[importRowKey]bool // 文件中已校验通过的行，用于检查文件内的重复行
+}
+
+// importRowKey 判断导入行是否重复时比较的字段
+type importRowKey struct {
+	storeID         int64
+	typeID          int64
+	transactionTime string
+	amount          models.Money
+	remark          string
+}
+
+// ImportPreview 预览CSV或XLSX文件的导入结果，返回每一行的校验结果，不保存数据。
+// 表单字段：file为导入文件；store_id为文件中没有店铺列时使用的店铺；
+// mapping为JSON对象，指定各字段对应的表头，如{"amount":"收入金额"}
+func (h *AccountHandler) ImportPreview(w http.ResponseWriter, r *http.Request) {
+	importAccounts(w, r, true)
+}
+
+// Import 从CSV或XLSX文件批量导入账务记录，表单字段与ImportPreview相同。
+// 所有行校验通过后才在同一事务中导入；skip_duplicates默认为true，跳过与已有记录或文件中前面的行重复的行。
+// 导入涉及多个记录和店铺，审计日志由处理器按店铺记录，不使用Audited
+func (h *AccountHandler) Import(w http.ResponseWriter, r *http.Request) {
+	importAccounts(w, r, false)
+}
+
+// importAccounts 解析并校验导入文件，dryRun为false时保存全部有效行
+func importAccounts(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		if isRequestTooLarge(err) {
+			SendResponse(w, http.StatusRequestEntityTooLarge, 413, "导入文件大小超过限制", nil)
+			return
+		}
+		SendResponse(w, http.StatusBadRequest, 400, "请选择要导入的文件", nil)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "读取导入文件失败", nil)
+		return
+	}
+	rows, err := services.ReadSpreadsheet(header.Filename, data)
+	if err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
+		return
+	}
+
+	mapping := map[string]string{}
+	if raw := r.FormValue("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			SendResponse(w, http.StatusBadRequest, 400, "列映射格式错误", nil)
+			return
+		}
+	}
+	skipDuplicates := r.FormValue("skip_duplicates") != "false"
+
+	importer, err := newAccountImporter(r, strings.EqualFold(path.Ext(header.Filename), ".xlsx"))
+	if err != nil {
+		log.Printf("准备导入数据失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "导入失败", nil)
+		return
+	}
+	if raw := r.FormValue("store_id"); raw != "" {
+		storeID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || importer.stores[storeID] == "" {
+			SendResponse(w, http.StatusBadRequest, 400, "店铺不存在", nil)
+			return
+		}
+		importer.defaultStore = storeID
+	}
+
+	// 第一个非空行为表头
+	headerRow := -1
+	for i, row := range rows {
+		if !isBlankRow(row) {
+			headerRow = i
+			break
+		}
+	}
+	if headerRow < 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "导入文件中没有数据", nil)
+		return
+	}
+	columns, msg := importColumns(rows[headerRow], mapping)
+	if msg == "" && columns["store"] < 0 && importer.defaultStore == 0 {
+		msg = "文件中没有店铺列，请指定导入的店铺"
+	}
+	if msg != "" {
+		SendResponse(w, http.StatusBadRequest, 400, msg, nil)
+		return
+	}
+
+	result := ImportResult{DryRun: dryRun, Rows: []ImportRow{}}
+	for i := headerRow + 1; i < len(rows); i++ {
+		if isBlankRow(rows[i]) {
+			continue
+		}
+		if result.Total >= maxImportRows {
+			SendResponse(w, http.StatusBadRequest, 400, fmt.Sprintf("单次最多导入%d行", maxImportRows), nil)
+			return
+		}
+
+		row, err := importer.checkRow(i+1, rows[i], columns)
+		if err != nil {
+			log.Printf("校验导入数据第%d行失败: %v", i+1, err)
+			SendResponse(w, http.StatusInternalServerError, 500, "导入失败", nil)
+			return
+		}
+
+		result.Total++
+		switch row.Status {
+		case importRowOK:
+			result.Valid++
+		case importRowDuplicate:
+			result.Duplicates++
+		default:
+			result.Invalid++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	if dryRun {
+		SendResponse(w, http.StatusOK, 200, "导入预览成功", result)
+		return
+	}
+	if result.Invalid > 0 {
+		SendResponse(w, http.StatusBadRequest, 400, fmt.Sprintf("有%d行数据校验失败，未导入任何记录", result.Invalid), result)
+		return
+	}
+
+	now := time.Now()
+	userID := middleware.CurrentUserID(r)
+	accounts := []models.Account{}
+	imported := []int{} // 导入的行在result.Rows中的位置
+	for i, row := range result.Rows {
+		if row.Status == importRowDuplicate && skipDuplicates {
+			continue
+		}
+		imported = append(imported, i)
+		accounts = append(accounts, models.Account{
+			StoreID:         row.StoreID,
+			UserID:          userID,
+			TypeID:          row.TypeID,
+			Amount:          row.Amount,
+			Remark:          row.Remark,
+			TransactionTime: row.TransactionTime,
+			CreateTime:      now,
+			UpdateTime:      now,
+		})
+	}
+
+	if len(accounts) > 0 {
+		ids, err := database.ImportAccounts(accounts)
+		if err != nil {
+			if errors.Is(err, models.ErrPeriodClosed) {
+				SendResponse(w, http.StatusConflict, 409, err.Error(), nil)
+				return
+			}
+			log.Printf("导入账务记录失败: %v", err)
+			SendResponse(w, http.StatusInternalServerError, 500, "导入失败", nil)
+			return
+		}
+		result.AccountIDs = ids
+		for i, id := range ids {
+			result.Rows[imported[i]].AccountID = id
+		}
+		auditImportedAccounts(r, &result, imported)
+	}
+	result.Imported = len(accounts)
+
+	SendResponse(w, http.StatusOK, 200, fmt.Sprintf("成功导入%d条账务记录", result.Imported), result)
+}
+
+// auditImportedAccounts 按店铺记录导入的审计日志，每个店铺一条，列出导入的记录ID并填写result.StoreIDs
+func auditImportedAccounts(r *http.Request, result *ImportResult, imported []int) {
+	idsByStore := map[int64][]int64{}
+	for _, i := range imported {
+		row := result.Rows[i]
+		if _, ok := idsByStore[row.StoreID]; !ok {
+			result.StoreIDs = append(result.StoreIDs, row.StoreID)
+		}
+		idsByStore[row.StoreID] = append(idsByStore[row.StoreID], row.AccountID)
+	}
+	for _, storeID := range result.StoreIDs {
+		middleware.RecordAudit(r, models.AuditActionImport, middleware.AuditAccount, 0, storeID, nil, map[string]interface{}{
+			"account_ids": idsByStore[storeID],
+			"count":       len(idsByStore[storeID]),
+		})
+	}
+}
+
+// newAccountImporter 读取店铺和账务类型，用于按名称匹配
+func newAccountImporter(r *http.Request, isXLSX bool) (*accountImporter, error) {
+	stores, err := database.GetStoreNames()
+	if err != nil {
+		return nil, err
+	}
+	accountTypes, err := database.GetAllAccountTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	importer := &accountImporter{
+		r:           r,
+		isAdmin:     middleware.IsAdmin(r),
+		isXLSX:      isXLSX,
+		stores:      stores,
+		storeIDs:    map[string]int64{},
+		types:       map[string]models.AccountType{},
+		storeAccess: map[int64]bool{},
+		typeVisible: map[[2]int64]bool{},
+		accepted:    map[importRowKey]bool{},
+	}
+	for id, name := range stores {
+		importer.storeIDs[strings.TrimSpace(name)] = id
+	}
+	// 同名类型以排在前面的为准
+	for _, accountType := range accountTypes {
+		name := strings.TrimSpace(accountType.Name)
+		if _, exists := importer.types[name]; !exists {
+			importer.types[name] = accountType
+		}
+	}
+	return importer, nil
+}
+
+// checkRow 按创建账务记录的规则校验一行数据，并检查是否与已有记录或文件中前面的行重复
+func (im *accountImporter) checkRow(rowNumber int, cells []string, columns map[string]int) (ImportRow, error) {
+	cell := func(field string) string {
+		if index := columns[field]; index >= 0 && index < len(cells) {
+			return strings.TrimSpace(cells[index])
+		}
+		return ""
+	}
+	row := ImportRow{Row: rowNumber, Remark: cell("remark")}
+	addError := func(msg string) {
+		row.Errors = append(row.Errors, msg)
+	}
+
+	// 店铺：按名称匹配，也可以直接填写店铺ID
+	row.StoreID = im.defaultStore
+	if name := cell("store"); name != "" {
+		row.StoreID = im.storeIDs[name]
+		if row.StoreID == 0 {
+			if id, err := strconv.ParseInt(name, 10, 64); err == nil && im.stores[id] != "" {
+				row.StoreID = id
+			}
+		}
+		if row.StoreID == 0 {
+			addError("店铺不存在: " + name)
+		}
+	} else if row.StoreID == 0 {
+		addError("店铺不能为空")
+	}
+	if row.StoreID != 0 {
+		row.StoreName = im.stores[row.StoreID]
+		allowed, err := im.canCreate(row.StoreID)
+		if err != nil {
+			return row, err
+		}
+		if !allowed {
+			addError("没有该店铺的记账权限")
+		}
+	}
+
+	// 账务类型：按名称匹配
+	row.TypeName = cell("type")
+	accountType, typeFound := im.types[row.TypeName]
+	if row.TypeName == "" {
+		addError("账务类型不能为空")
+	} else if !typeFound {
+		addError("账务类型不存在: " + row.TypeName)
+	} else {
+		row.TypeID = accountType.ID
+		row.IsExpense = accountType.IsExpense
+		if row.StoreID != 0 {
+			visible, err := im.canUseType(accountType.ID, row.StoreID)
+			if err != nil {
+				return row, err
+			}
+			if !visible {
+				addError("没有使用该账务类型的权限")
+			}
+		}
+	}
+
+	// 金额：与手工记账相同，支出类型的负数金额按绝对值保存
+	amount, err := models.ParseMoney(cell("amount"))
+	if err != nil {
+		addError(models.ErrInvalidMoney.Error())
+	} else if typeFound {
+		var msg string
+		if row.Amount, msg = checkAccountAmount(amount, accountType.IsExpense); msg != "" {
+			addError(msg)
+		}
+	}
+
+	// 交易时间：支持与手工记账相同的格式，XLSX中的日期单元格为序列号
+	rawTime := cell("transaction_time")
+	if rawTime == "" {
+		addError("交易日期不能为空")
+	}
+	formattedTime, err := parseTransactionTime(rawTime, storeLocationOrDefault(row.StoreID))
+	if err != nil && im.isXLSX {
+		if t, ok := services.ExcelSerialTime(rawTime); ok {
+			formattedTime, err = t.Format(models.DateTimeLayout), nil
+		}
+	}
+	if err != nil {
+		addError("交易日期格式错误: " + rawTime)
+	}
+	row.TransactionTime = formattedTime
+
+	// 已结账的月份不能导入
+	if err == nil && row.StoreID > 0 {
+		if err := database.CheckPeriodOpen(row.StoreID, row.TransactionTime); errors.Is(err, models.ErrPeriodClosed) {
+			addError(err.Error())
+		} else if err != nil {
+			return row, err
+		}
+	}
+
+	if len(row.Errors) > 0 {
+		row.Status = importRowError
+		return row, nil
+	}
+
+	duplicate, err := database.AccountExists(row.StoreID, row.TypeID, row.Amount, row.TransactionTime, row.Remark)
+	if err != nil {
+		return row, err
+	}
+	key := importRowKey{row.StoreID, row.TypeID, row.TransactionTime, row.Amount, row.Remark}
+	row.Status = importRowOK
+	if duplicate || im.accepted[key] {
+		row.Status = importRowDuplicate
+	}
+	im.accepted[key] = true
+	return row, nil
+}
+
+// canCreate 检查当前用户能否在店铺中记账
+func (im *accountImporter) canCreate(storeID int64) (bool, error) {
+	if allowed, ok := im.storeAccess[storeID]; ok {
+		return allowed, nil
+	}
+	allowed, err := middleware.CanAccessStore(im.r, storeID, models.CapAccountsCreate)
+	if err != nil {
+		return false, err
+	}
+	im.storeAccess[storeID] = allowed
+	return allowed, nil
+}
+
+// canUseType 检查当前用户能否在店铺中使用该账务类型
+func (im *accountImporter) canUseType(typeID, storeID int64) (bool, error) {
+	if im.isAdmin {
+		return true, nil
+	}
+	key := [2]int64{typeID, storeID}
+	if visible, ok := im.typeVisible[key]; ok {
+		return visible, nil
+	}
+	visible, err := database.IsAccountTypeVisible(middleware.CurrentUserID(im.r), typeID, storeID)
+	if err != nil {
+		return false, err
+	}
+	im.typeVisible[key] = visible
+	return visible, nil
+}
+
+// importColumns 根据表头确定各字段所在的列，未找到的字段为-1；必填列缺失时返回错误提示
+func importColumns(header []string, mapping map[string]string) (map[string]int, string) {
+	positions := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, exists := positions[name]; !exists && name != "" {
+			positions[name] = i
+		}
+	}
+
+	columns := map[string]int{}
+	for field, aliases := range importColumnAliases {
+		columns[field] = -1
+		if name, ok := mapping[field]; ok {
+			index, found := positions[strings.ToLower(strings.TrimSpace(name))]
+			if !found {
+				return nil, "找不到列: " + name
+			}
+			columns[field] = index
+			continue
+		}
+		for _, alias := range aliases {
+			if index, found := positions[strings.ToLower(alias)]; found {
+				columns[field] = index
+				break
+			}
+		}
+	}
+
+	for _, field := range []string{"type", "amount", "transaction_time"} {
+		if columns[field] < 0 {
+			return nil, fmt.Sprintf("缺少%s列", importColumnAliases[field][0])
+		}
+	}
+	return columns, ""
+}
+
+// isBlankRow 判断一行是否全部为空
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
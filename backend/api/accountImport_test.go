@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"account/backend/database"
+	"account/backend/middleware"
+)
+
+func TestImportPreviewDuplicatesInFile(t *testing.T) {
+	useTestDB(t)
+	token := adminToken(t)
+	if _, err := database.DB.Exec("INSERT INTO stores (id, name) VALUES (1, '总店')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.DB.Exec("INSERT INTO account_types (name, category, is_expense) VALUES ('测试房租', 2, 1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "accounts.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("店铺,类型,金额,交易日期,备注\n" +
+		"总店,测试房租,100,2024-01-05,一月\n" +
+		"总店,测试房租,100,2024-01-05,一月\n" + // 与上一行重复
+		"总店,测试房租,100,2024-01-05,二月\n"))
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/accounts/import/preview", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	middleware.Protect((&AccountHandler{}).ImportPreview, middleware.Authenticated)(w, r)
+
+	var resp struct {
+		Code int          `json:"code"`
+		Data ImportResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+	}
+	if resp.Code != http.StatusOK {
+		t.Fatalf("预览状态码 = %d, body: %s", resp.Code, w.Body.String())
+	}
+	if resp.Data.Valid != 2 || resp.Data.Duplicates != 1 {
+		t.Errorf("valid = %d, duplicates = %d, want 2, 1", resp.Data.Valid, resp.Data.Duplicates)
+	}
+	if len(resp.Data.Rows) == 3 && resp.Data.Rows[1].Status != importRowDuplicate {
+		t.Errorf("第3行状态 = %s, want %s", resp.Data.Rows[1].Status, importRowDuplicate)
+	}
+}
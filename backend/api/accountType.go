@@ -1,26 +1,29 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
 
 	"account/backend/database"
+	"account/backend/middleware"
 	"account/backend/models"
 )
 
 // AccountTypeHandler 处理账务类型相关请求
 type AccountTypeHandler struct{}
 
-// GetAll 获取所有账务类型
+// GetAll 获取当前用户可见的账务类型
 func (h *AccountTypeHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
 		return
 	}
 
-	accountTypes, err := database.GetAllAccountTypes()
+	// 非管理员只返回有权查看的账务类型
+	accountTypes, err := database.GetAccountTypesForUser(middleware.CurrentUserID(r))
 	if err != nil {
 		log.Printf("获取账务类型失败: %v", err)
 		SendResponse(w, http.StatusInternalServerError, 500, "获取账务类型失败: "+err.Error(), nil)
@@ -37,20 +40,6 @@ func (h *AccountTypeHandler) CreateAccountType(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 解析请求数据
 	var accountType models.AccountType
 	if err := json.NewDecoder(r.Body).Decode(&accountType); err != nil {
@@ -72,6 +61,7 @@ func (h *AccountTypeHandler) CreateAccountType(w http.ResponseWriter, r *http.Re
 	}
 
 	accountType.ID = id
+	accountType.NormalizeCategory()
 	SendResponse(w, http.StatusOK, 200, "创建账务类型成功", accountType)
 }
 
@@ -82,20 +72,6 @@ func (h *AccountTypeHandler) UpdateAccountType(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 解析请求数据
 	var accountType models.AccountType
 	if err := json.NewDecoder(r.Body).Decode(&accountType); err != nil {
@@ -113,6 +89,29 @@ func (h *AccountTypeHandler) UpdateAccountType(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// 已有账务记录的类型不能修改收支方向，否则历史记录的收支会随之改变
+	accountType.NormalizeCategory()
+	wasExpense, err := database.IsExpenseAccountType(accountType.ID)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "账务类型不存在", nil)
+		return
+	}
+	if err != nil {
+		SendResponse(w, http.StatusInternalServerError, 500, "查询账务类型失败: "+err.Error(), nil)
+		return
+	}
+	if wasExpense != accountType.IsExpense {
+		hasAccounts, err := database.HasAccountTypeRecords(accountType.ID)
+		if err != nil {
+			SendResponse(w, http.StatusInternalServerError, 500, "检查账务类型记录失败: "+err.Error(), nil)
+			return
+		}
+		if hasAccounts {
+			SendResponse(w, http.StatusBadRequest, 400, "该类型已有账务记录，不能修改收支方向", nil)
+			return
+		}
+	}
+
 	// 更新账务类型
 	err = database.UpdateAccountType(accountType)
 	if err != nil {
@@ -130,20 +129,6 @@ func (h *AccountTypeHandler) DeleteAccountType(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 获取账务类型ID
 	typeID := r.URL.Query().Get("id")
 	if typeID == "" {
@@ -177,4 +162,78 @@ func (h *AccountTypeHandler) DeleteAccountType(w http.ResponseWriter, r *http.Re
 	}
 
 	SendResponse(w, http.StatusOK, 200, "删除账务类型成功", nil)
-} 
\ No newline at end of file
+}
+
+// GetPermissions 获取账务类型的查看权限设置
+func (h *AccountTypeHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	typeIDInt, err := strconv.ParseInt(r.URL.Query().Get("type_id"), 10, 64)
+	if err != nil || typeIDInt <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "账务类型ID无效", nil)
+		return
+	}
+
+	permission, err := database.GetAccountTypePermission(typeIDInt)
+	if err != nil {
+		log.Printf("获取账务类型权限失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取账务类型权限失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取账务类型权限成功", permission)
+}
+
+// UpdatePermissions 设置可查看账务类型的用户和店铺角色，两者都为空时对所有人可见
+func (h *AccountTypeHandler) UpdatePermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var permission models.AccountTypePermission
+	if err := json.NewDecoder(r.Body).Decode(&permission); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "请求数据格式错误", nil)
+		return
+	}
+
+	if permission.AccountTypeID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "账务类型ID无效", nil)
+		return
+	}
+	for _, role := range permission.StoreRoles {
+		if !role.IsValid() {
+			SendResponse(w, http.StatusBadRequest, 400, "无效的店铺角色: "+string(role), nil)
+			return
+		}
+	}
+
+	exists, err := database.AccountTypeExists(permission.AccountTypeID)
+	if err != nil {
+		log.Printf("检查账务类型失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "设置账务类型权限失败", nil)
+		return
+	}
+	if !exists {
+		SendResponse(w, http.StatusNotFound, 404, "账务类型不存在", nil)
+		return
+	}
+
+	if err := database.SetAccountTypePermission(permission); err != nil {
+		log.Printf("设置账务类型%d权限失败: %v", permission.AccountTypeID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "设置账务类型权限失败", nil)
+		return
+	}
+
+	updated, err := database.GetAccountTypePermission(permission.AccountTypeID)
+	if err != nil {
+		log.Printf("获取账务类型权限失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取账务类型权限失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "设置账务类型权限成功", updated)
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/services"
+)
+
+// Attachments 获取账务记录的附件列表
+func (h *AccountHandler) Attachments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.URL.Query().Get("account_id"), 10, 64)
+	if err != nil || accountID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "账目ID无效", nil)
+		return
+	}
+
+	attachments, err := database.GetAttachments(accountID)
+	if err != nil {
+		log.Printf("获取账目%d的附件失败: %v", accountID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取附件失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取附件成功", attachments)
+}
+
+// UploadAttachment 上传账务记录的附件，账目ID通过URL参数account_id传递，文件使用multipart表单的file字段
+func (h *AccountHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.URL.Query().Get("account_id"), 10, 64)
+	if err != nil || accountID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "账目ID无效", nil)
+		return
+	}
+	if _, err := database.GetAccountByID(accountID); err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "账目不存在", nil)
+		return
+	} else if err != nil {
+		log.Printf("获取账目%d失败: %v", accountID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "上传附件失败", nil)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		if isRequestTooLarge(err) {
+			SendResponse(w, http.StatusRequestEntityTooLarge, 413, services.ErrAttachmentTooLarge.Error(), nil)
+			return
+		}
+		SendResponse(w, http.StatusBadRequest, 400, "请选择要上传的文件", nil)
+		return
+	}
+	defer file.Close()
+
+	attachment, err := services.SaveAttachment(accountID, middleware.CurrentUserID(r), header.Filename, file)
+	if errors.Is(err, services.ErrAttachmentTooLarge) {
+		SendResponse(w, http.StatusRequestEntityTooLarge, 413, err.Error(), nil)
+		return
+	}
+	if errors.Is(err, services.ErrAttachmentType) {
+		SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		log.Printf("上传账目%d的附件失败: %v", accountID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "上传附件失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "上传附件成功", attachment)
+}
+
+// DownloadAttachment 下载附件，thumbnail=1时返回图片的缩略图
+func (h *AccountHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "附件ID无效", nil)
+		return
+	}
+
+	attachment, err := database.GetAttachment(id)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "附件不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("获取附件%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "下载附件失败", nil)
+		return
+	}
+
+	key, contentType := attachment.StorageKey, attachment.ContentType
+	if r.URL.Query().Get("thumbnail") == "1" {
+		if attachment.ThumbnailKey == "" {
+			SendResponse(w, http.StatusNotFound, 404, "该附件没有缩略图", nil)
+			return
+		}
+		key, contentType = attachment.ThumbnailKey, "image/jpeg"
+	}
+
+	file, err := services.AttachmentStorage().Open(key)
+	if err != nil {
+		log.Printf("打开附件文件%s失败: %v", key, err)
+		SendResponse(w, http.StatusNotFound, 404, "附件文件不存在", nil)
+		return
+	}
+	defer file.Close()
+
+	// 图片和PDF在浏览器中直接显示，其他情况作为下载
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") || contentType == "application/pdf" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("写入附件响应失败: %v", err)
+	}
+}
+
+// DeleteAttachment 删除附件及其文件
+func (h *AccountHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "仅支持DELETE请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "附件ID无效", nil)
+		return
+	}
+
+	err = services.DeleteAttachment(id)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "附件不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("删除附件%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "删除附件失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "删除附件成功", nil)
+}
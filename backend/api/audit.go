@@ -0,0 +1,46 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"account/backend/database"
+	"account/backend/models"
+)
+
+// AuditHandler 处理审计日志查询
+type AuditHandler struct{}
+
+// GetLogs 查询审计日志，可按操作人、店铺、对象类型和ID、操作类型、日期筛选
+func (h *AuditHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.AuditLogFilter{
+		EntityType: query.Get("entity_type"),
+		Action:     query.Get("action"),
+		StartDate:  query.Get("start_date"),
+		EndDate:    query.Get("end_date"),
+	}
+	filter.UserID, _ = strconv.ParseInt(query.Get("user_id"), 10, 64)
+	filter.StoreID, _ = strconv.ParseInt(query.Get("store_id"), 10, 64)
+	filter.EntityID, _ = strconv.ParseInt(query.Get("entity_id"), 10, 64)
+	filter.Page, _ = strconv.Atoi(query.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(query.Get("page_size"))
+
+	logs, total, err := database.GetAuditLogs(filter)
+	if err != nil {
+		log.Printf("查询审计日志失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "查询审计日志失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "查询审计日志成功", map[string]interface{}{
+		"data":  logs,
+		"total": total,
+	})
+}
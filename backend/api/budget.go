@@ -0,0 +1,282 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+	"account/backend/services"
+	"account/backend/utils"
+)
+
+// BudgetHandler 处理预算相关请求
+type BudgetHandler struct{}
+
+// BudgetRequest 创建或修改预算的请求结构
+type BudgetRequest struct {
+	ID      int64        `json:"id"`
+	StoreID int64        `json:"store_id"`
+	TypeID  int64        `json:"type_id"` // 0表示店铺全部支出类型
+	Period  string       `json:"period"`  // 格式 2006-01
+	Amount  models.Money `json:"amount"`
+	Note    string       `json:"note"`
+}
+
+// decodeBudgetRequest 解析并检查预算请求，失败时已写入响应
+func decodeBudgetRequest(w http.ResponseWriter, r *http.Request) (*models.Budget, bool) {
+	var req BudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if errors.Is(err, models.ErrInvalidMoney) {
+			SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
+			return nil, false
+		}
+		SendResponse(w, http.StatusBadRequest, 400, "请求参数错误", nil)
+		return nil, false
+	}
+
+	if req.StoreID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "店铺不能为空", nil)
+		return nil, false
+	}
+	req.Period = strings.TrimSpace(req.Period)
+	if _, err := time.Parse(models.PeriodLayout, req.Period); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "月份格式应为YYYY-MM", nil)
+		return nil, false
+	}
+	if req.Amount <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "预算金额必须大于0", nil)
+		return nil, false
+	}
+
+	// 预算只针对支出类型
+	if req.TypeID > 0 {
+		isExpense, err := database.IsExpenseAccountType(req.TypeID)
+		if err == sql.ErrNoRows {
+			SendResponse(w, http.StatusBadRequest, 400, "账务类型不存在", nil)
+			return nil, false
+		}
+		if err != nil {
+			log.Printf("查询账务类型%d失败: %v", req.TypeID, err)
+			SendResponse(w, http.StatusInternalServerError, 500, "查询账务类型失败", nil)
+			return nil, false
+		}
+		if !isExpense {
+			SendResponse(w, http.StatusBadRequest, 400, "只能为支出类型设置预算", nil)
+			return nil, false
+		}
+	}
+
+	exists, err := database.BudgetExists(req.StoreID, req.TypeID, req.Period, req.ID)
+	if err != nil {
+		log.Printf("检查预算是否存在失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "检查预算失败", nil)
+		return nil, false
+	}
+	if exists {
+		SendResponse(w, http.StatusConflict, 409, "该店铺该月已设置此类型的预算", nil)
+		return nil, false
+	}
+
+	return &models.Budget{
+		ID:      req.ID,
+		StoreID: req.StoreID,
+		TypeID:  req.TypeID,
+		Period:  req.Period,
+		Amount:  req.Amount,
+		Note:    strings.TrimSpace(req.Note),
+	}, true
+}
+
+// budgetStoreIDs 确定查询预算的店铺：指定了店铺时只查该店铺，管理员不限店铺，
+// 其他用户只能查看有报表查看权限的店铺；失败时已写入响应
+func budgetStoreIDs(w http.ResponseWriter, r *http.Request) ([]int64, bool) {
+	if storeID, _ := strconv.ParseInt(r.URL.Query().Get("store_id"), 10, 64); storeID > 0 {
+		return []int64{storeID}, true
+	}
+	if middleware.IsAdmin(r) {
+		return nil, true
+	}
+
+	ids, err := database.GetStoreIDsWithCapability(int(middleware.CurrentUserID(r)), models.CapReportsView)
+	if err != nil {
+		log.Printf("查询用户店铺权限失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "查询用户权限失败", nil)
+		return nil, false
+	}
+	storeIDs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		storeIDs = append(storeIDs, int64(id))
+	}
+	return storeIDs, true
+}
+
+// budgetVisible 检查预算是否存在且当前用户有权查看其类型，否则返回404
+func budgetVisible(w http.ResponseWriter, r *http.Request, id int64) bool {
+	_, err := database.GetBudget(id, middleware.CurrentUserID(r), middleware.IsAdmin(r))
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "预算不存在", nil)
+		return false
+	}
+	if err != nil {
+		log.Printf("查询预算%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "查询预算失败", nil)
+		return false
+	}
+	return true
+}
+
+// List 获取预算列表及各预算已发生的支出，可按店铺和月份筛选
+func (h *BudgetHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	storeIDs, ok := budgetStoreIDs(w, r)
+	if !ok {
+		return
+	}
+
+	budgets, err := database.GetBudgets(storeIDs, r.URL.Query().Get("period"), middleware.CurrentUserID(r), middleware.IsAdmin(r))
+	if err != nil {
+		log.Printf("获取预算失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取预算失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取预算成功", budgets)
+}
+
+// Create 创建预算
+func (h *BudgetHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	budget, ok := decodeBudgetRequest(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	budget.ID = 0
+	budget.CreatedBy = middleware.CurrentUserID(r)
+	budget.CreateTime = now
+	budget.UpdateTime = now
+
+	id, err := database.CreateBudget(budget)
+	if err != nil {
+		log.Printf("创建预算失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "创建预算失败", nil)
+		return
+	}
+	if saved, err := database.GetBudget(id, middleware.CurrentUserID(r), middleware.IsAdmin(r)); err == nil {
+		budget = saved
+	} else {
+		budget.ID = id
+	}
+
+	SendResponse(w, http.StatusOK, 200, "创建预算成功", budget)
+}
+
+// Update 修改预算
+func (h *BudgetHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "仅支持PUT请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	budget, ok := decodeBudgetRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !budgetVisible(w, r, budget.ID) {
+		return
+	}
+
+	budget.UpdateTime = time.Now()
+	err := database.UpdateBudget(budget)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "预算不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("修改预算%d失败: %v", budget.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改预算失败", nil)
+		return
+	}
+	if saved, err := database.GetBudget(budget.ID, middleware.CurrentUserID(r), middleware.IsAdmin(r)); err == nil {
+		budget = saved
+	}
+
+	SendResponse(w, http.StatusOK, 200, "修改预算成功", budget)
+}
+
+// Delete 删除预算
+func (h *BudgetHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "仅支持DELETE请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "预算ID无效", nil)
+		return
+	}
+
+	if !budgetVisible(w, r, id) {
+		return
+	}
+
+	err = database.DeleteBudget(id)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "预算不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("删除预算%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "删除预算失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "删除预算成功", nil)
+}
+
+// Alerts 获取本月已超支或按当前进度预计超支的店铺和类型
+func (h *BudgetHandler) Alerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	storeIDs, ok := budgetStoreIDs(w, r)
+	if !ok {
+		return
+	}
+
+	// 本月和当月进度按业务时区计算，只查询一个店铺时按该店铺的时区
+	loc := utils.BusinessLocation()
+	if len(storeIDs) == 1 {
+		loc = storeLocationOrDefault(storeIDs[0])
+	}
+
+	alerts, err := services.BudgetAlerts(storeIDs, time.Now().In(loc), middleware.CurrentUserID(r), middleware.IsAdmin(r))
+	if err != nil {
+		log.Printf("获取预算预警失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取预算预警失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取预算预警成功", alerts)
+}
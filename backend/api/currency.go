@@ -0,0 +1,216 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+	"account/backend/services"
+)
+
+// ExchangeRateHandler 处理汇率相关请求
+type ExchangeRateHandler struct{}
+
+// resolveReportCurrency 确定统计和报表使用的币种：优先使用currency参数，其次是所查询店铺的币种，
+// 最后是系统默认的报表币种；参数无效时已写入响应
+func resolveReportCurrency(w http.ResponseWriter, r *http.Request, storeID int64) (string, bool) {
+	if value := r.URL.Query().Get("currency"); value != "" {
+		currency, ok := models.NormalizeCurrency(value)
+		if !ok {
+			SendResponse(w, http.StatusBadRequest, 400, "币种应为三位字母代码，如CNY、USD", nil)
+			return "", false
+		}
+		return currency, true
+	}
+
+	if storeID > 0 {
+		currency, err := database.GetStoreCurrency(storeID)
+		if err == nil {
+			return currency, true
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("查询店铺%d币种失败: %v", storeID, err)
+		}
+	}
+	return services.ReportCurrency(), true
+}
+
+// ExchangeRateRequest 创建或修改汇率的请求结构
+type ExchangeRateRequest struct {
+	ID            int64   `json:"id"`
+	FromCurrency  string  `json:"from_currency"`
+	ToCurrency    string  `json:"to_currency"`
+	Rate          float64 `json:"rate"`
+	EffectiveDate string  `json:"effective_date"` // 格式 2006-01-02
+}
+
+// decodeExchangeRateRequest 解析并检查汇率请求，失败时已写入响应
+func decodeExchangeRateRequest(w http.ResponseWriter, r *http.Request) (*models.ExchangeRate, bool) {
+	var req ExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "请求参数错误", nil)
+		return nil, false
+	}
+
+	from, okFrom := models.NormalizeCurrency(req.FromCurrency)
+	to, okTo := models.NormalizeCurrency(req.ToCurrency)
+	if !okFrom || !okTo {
+		SendResponse(w, http.StatusBadRequest, 400, "币种应为三位字母代码，如CNY、USD", nil)
+		return nil, false
+	}
+	if from == to {
+		SendResponse(w, http.StatusBadRequest, 400, "源币种和目标币种不能相同", nil)
+		return nil, false
+	}
+	if req.Rate <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "汇率必须大于0", nil)
+		return nil, false
+	}
+	req.EffectiveDate = strings.TrimSpace(req.EffectiveDate)
+	if _, err := time.Parse(models.DateLayout, req.EffectiveDate); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "生效日期格式应为YYYY-MM-DD", nil)
+		return nil, false
+	}
+
+	exists, err := database.ExchangeRateExists(from, to, req.EffectiveDate, req.ID)
+	if err != nil {
+		log.Printf("检查汇率是否存在失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "检查汇率失败", nil)
+		return nil, false
+	}
+	if exists {
+		SendResponse(w, http.StatusConflict, 409, "该币种对在这一天已设置汇率", nil)
+		return nil, false
+	}
+
+	return &models.ExchangeRate{
+		ID:            req.ID,
+		FromCurrency:  from,
+		ToCurrency:    to,
+		Rate:          req.Rate,
+		EffectiveDate: req.EffectiveDate,
+	}, true
+}
+
+// List 获取汇率列表，可按币种筛选
+func (h *ExchangeRateHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currency := ""
+	if value := r.URL.Query().Get("currency"); value != "" {
+		var ok bool
+		if currency, ok = models.NormalizeCurrency(value); !ok {
+			SendResponse(w, http.StatusBadRequest, 400, "币种应为三位字母代码，如CNY、USD", nil)
+			return
+		}
+	}
+
+	rates, err := database.GetExchangeRates(currency)
+	if err != nil {
+		log.Printf("获取汇率失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取汇率失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取汇率成功", map[string]interface{}{
+		"data":            rates,
+		"report_currency": services.ReportCurrency(),
+	})
+}
+
+// Create 创建汇率
+func (h *ExchangeRateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rate, ok := decodeExchangeRateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	rate.ID = 0
+	rate.CreatedBy = middleware.CurrentUserID(r)
+	rate.CreateTime = now
+	rate.UpdateTime = now
+
+	id, err := database.CreateExchangeRate(rate)
+	if err != nil {
+		log.Printf("创建汇率失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "创建汇率失败", nil)
+		return
+	}
+	rate.ID = id
+
+	SendResponse(w, http.StatusOK, 200, "创建汇率成功", rate)
+}
+
+// Update 修改汇率
+func (h *ExchangeRateHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "仅支持PUT请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rate, ok := decodeExchangeRateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	rate.UpdateTime = time.Now()
+	err := database.UpdateExchangeRate(rate)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "汇率不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("修改汇率%d失败: %v", rate.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改汇率失败", nil)
+		return
+	}
+	if saved, err := database.GetExchangeRate(rate.ID); err == nil {
+		rate = saved
+	}
+
+	SendResponse(w, http.StatusOK, 200, "修改汇率成功", rate)
+}
+
+// Delete 删除汇率
+func (h *ExchangeRateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "仅支持DELETE请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "汇率ID无效", nil)
+		return
+	}
+
+	err = database.DeleteExchangeRate(id)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "汇率不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("删除汇率%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "删除汇率失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "删除汇率成功", nil)
+}
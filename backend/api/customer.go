@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"account/backend/database"
+	"account/backend/middleware"
 	"account/backend/models"
 
 	"github.com/gorilla/mux"
@@ -20,20 +21,11 @@ import (
 
 // GetCustomers 获取客户列表接口
 func GetCustomers(w http.ResponseWriter, r *http.Request) {
-	// 获取用户ID
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "缺少user_id参数", nil)
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的user_id参数", nil)
-		return
-	}
+	// 获取当前用户ID
+	userID := int(middleware.CurrentUserID(r))
 
 	// 获取分页参数
+	var err error
 	page := 1
 	pageSize := 20
 
@@ -82,18 +74,8 @@ func GetCustomers(w http.ResponseWriter, r *http.Request) {
 
 // GetCustomerDetail 获取客户详情接口
 func GetCustomerDetail(w http.ResponseWriter, r *http.Request) {
-	// 获取用户ID和客户ID
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		http.Error(w, "缺少user_id参数", http.StatusBadRequest)
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		http.Error(w, "无效的user_id参数", http.StatusBadRequest)
-		return
-	}
+	// 获取当前用户ID和客户ID
+	userID := int(middleware.CurrentUserID(r))
 
 	customerIDStr := r.URL.Query().Get("customer_id")
 	if customerIDStr == "" {
@@ -123,7 +105,6 @@ func GetCustomerDetail(w http.ResponseWriter, r *http.Request) {
 func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 	// 解析请求数据
 	var requestData struct {
-		UserID        int     `json:"user_id"`
 		Name          string  `json:"name"`
 		Phone         string  `json:"phone"`
 		Gender        int     `json:"gender"`
@@ -148,19 +129,6 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 检查用户是否有权限操作该店铺
-	hasPermission, err := database.UserHasStorePermission(requestData.UserID, requestData.StoreID)
-	if err != nil {
-		log.Printf("检查用户权限失败: %v", err)
-		SendResponse(w, http.StatusInternalServerError, 500, "检查用户权限失败", nil)
-		return
-	}
-
-	if !hasPermission {
-		SendResponse(w, http.StatusForbidden, 403, "无权操作该店铺", nil)
-		return
-	}
-
 	// 创建客户
 	customer := &models.Customer{
 		Name:          requestData.Name,
@@ -192,9 +160,11 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 
 // UpdateCustomer 更新客户接口
 func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	// 当前登录用户ID
+	userID := int(middleware.CurrentUserID(r))
+
 	// 解析请求数据
 	var requestData struct {
-		UserID        int     `json:"user_id"`
 		CustomerID    int     `json:"customer_id"`
 		Name          string  `json:"name"`
 		Phone         string  `json:"phone"`
@@ -221,26 +191,13 @@ func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 检查客户是否存在
-	_, err = database.GetCustomerByID(requestData.UserID, requestData.CustomerID)
+	_, err = database.GetCustomerByID(userID, requestData.CustomerID)
 	if err != nil {
 		log.Printf("获取客户信息失败: %v", err)
 		SendResponse(w, http.StatusInternalServerError, 500, fmt.Sprintf("获取客户信息失败: %v", err), nil)
 		return
 	}
 
-	// 检查用户是否有权限操作该店铺
-	hasPermission, err := database.UserHasStorePermission(requestData.UserID, requestData.StoreID)
-	if err != nil {
-		log.Printf("检查用户权限失败: %v", err)
-		SendResponse(w, http.StatusInternalServerError, 500, "检查用户权限失败", nil)
-		return
-	}
-
-	if !hasPermission {
-		SendResponse(w, http.StatusForbidden, 403, "无权操作该店铺", nil)
-		return
-	}
-
 	// 更新客户信息
 	customer := &models.Customer{
 		ID:            requestData.CustomerID,
@@ -270,18 +227,8 @@ func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 
 // DeleteCustomer 删除客户接口
 func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
-	// 获取用户ID和客户ID
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "缺少user_id参数", nil)
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的user_id参数", nil)
-		return
-	}
+	// 获取当前用户ID和客户ID
+	userID := int(middleware.CurrentUserID(r))
 
 	customerIDStr := r.URL.Query().Get("customer_id")
 	if customerIDStr == "" {
@@ -295,27 +242,14 @@ func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 检查客户是否存在并获取所属店铺ID
-	customer, err := database.GetCustomerByID(userID, customerID)
+	// 检查客户是否存在
+	_, err = database.GetCustomerByID(userID, customerID)
 	if err != nil {
 		log.Printf("获取客户信息失败: %v", err)
 		SendResponse(w, http.StatusInternalServerError, 500, fmt.Sprintf("获取客户信息失败: %v", err), nil)
 		return
 	}
 
-	// 检查用户是否有权限操作该店铺
-	hasPermission, err := database.UserHasStorePermission(userID, customer.StoreID)
-	if err != nil {
-		log.Printf("检查用户权限失败: %v", err)
-		SendResponse(w, http.StatusInternalServerError, 500, "检查用户权限失败", nil)
-		return
-	}
-
-	if !hasPermission {
-		SendResponse(w, http.StatusForbidden, 403, "无权操作该店铺", nil)
-		return
-	}
-
 	// 删除客户
 	err = database.DeleteCustomer(customerID)
 	if err != nil {
@@ -330,18 +264,8 @@ func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 
 // GetWeightRecords 获取体重记录接口
 func GetWeightRecords(w http.ResponseWriter, r *http.Request) {
-	// 获取用户ID和客户ID
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "缺少user_id参数", nil)
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的user_id参数", nil)
-		return
-	}
+	// 获取当前用户ID和客户ID
+	userID := int(middleware.CurrentUserID(r))
 
 	customerIDStr := r.URL.Query().Get("customer_id")
 	if customerIDStr == "" {
@@ -377,9 +301,11 @@ func GetWeightRecords(w http.ResponseWriter, r *http.Request) {
 
 // AddWeightRecord 添加体重记录接口
 func AddWeightRecord(w http.ResponseWriter, r *http.Request) {
+	// 当前登录用户ID
+	userID := int(middleware.CurrentUserID(r))
+
 	// 解析请求体
 	var requestData struct {
-		UserID     int     `json:"user_id"`
 		CustomerID int     `json:"customer_id"`
 		Weight     float64 `json:"weight"`
 		RecordDate string  `json:"record_date"`
@@ -394,11 +320,6 @@ func AddWeightRecord(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 校验数据
-	if requestData.UserID <= 0 {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-		return
-	}
-
 	if requestData.CustomerID <= 0 {
 		SendResponse(w, http.StatusBadRequest, 400, "无效的客户ID", nil)
 		return
@@ -416,7 +337,7 @@ func AddWeightRecord(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 检查用户是否有权限访问该客户
-	_, err = database.GetCustomerByID(requestData.UserID, requestData.CustomerID)
+	_, err = database.GetCustomerByID(userID, requestData.CustomerID)
 	if err != nil {
 		log.Printf("获取客户信息失败: %v", err)
 		SendResponse(w, http.StatusInternalServerError, 500, fmt.Sprintf("获取客户信息失败: %v", err), nil)
@@ -447,18 +368,8 @@ func AddWeightRecord(w http.ResponseWriter, r *http.Request) {
 
 // GetProductUsage 获取产品使用记录接口
 func GetProductUsage(w http.ResponseWriter, r *http.Request) {
-	// 获取用户ID和客户ID
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "缺少user_id参数", nil)
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的user_id参数", nil)
-		return
-	}
+	// 获取当前用户ID和客户ID
+	userID := int(middleware.CurrentUserID(r))
 
 	customerIDStr := r.URL.Query().Get("customer_id")
 	if customerIDStr == "" {
@@ -517,9 +428,11 @@ func GetProductUsage(w http.ResponseWriter, r *http.Request) {
 
 // AddProductUsage 添加产品使用记录接口
 func AddProductUsage(w http.ResponseWriter, r *http.Request) {
+	// 当前登录用户ID
+	userID := int(middleware.CurrentUserID(r))
+
 	// 解析请求体
 	var requestData struct {
-		UserID        int     `json:"user_id"`
 		CustomerID    int     `json:"customer_id"`
 		ProductID     int     `json:"product_id"`
 		ProductName   string  `json:"product_name"`
@@ -537,11 +450,6 @@ func AddProductUsage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 校验数据
-	if requestData.UserID <= 0 {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-		return
-	}
-
 	if requestData.CustomerID <= 0 {
 		SendResponse(w, http.StatusBadRequest, 400, "无效的客户ID", nil)
 		return
@@ -588,7 +496,7 @@ func AddProductUsage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 检查用户是否有权限访问该客户
-	_, err = database.GetCustomerByID(requestData.UserID, requestData.CustomerID)
+	_, err = database.GetCustomerByID(userID, requestData.CustomerID)
 	if err != nil {
 		log.Printf("获取客户信息失败: %v", err)
 		SendResponse(w, http.StatusInternalServerError, 500, fmt.Sprintf("获取客户信息失败: %v", err), nil)
@@ -645,9 +553,11 @@ func AddProductUsage(w http.ResponseWriter, r *http.Request) {
 
 // UpdateProductUsage 更新产品使用记录接口
 func UpdateProductUsage(w http.ResponseWriter, r *http.Request) {
+	// 当前登录用户ID
+	userID := int(middleware.CurrentUserID(r))
+
 	// 解析请求体
 	var requestData struct {
-		UserID        int     `json:"user_id"`
 		CustomerID    int     `json:"customer_id"`
 		ProductID     int     `json:"product_id"`
 		UsageID       int     `json:"usage_id"` // 添加记录ID
@@ -665,11 +575,6 @@ func UpdateProductUsage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 校验数据
-	if requestData.UserID <= 0 {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-		return
-	}
-
 	if requestData.CustomerID <= 0 {
 		SendResponse(w, http.StatusBadRequest, 400, "无效的客户ID", nil)
 		return
@@ -712,7 +617,7 @@ func UpdateProductUsage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 检查用户是否有权限访问该客户
-	_, err = database.GetCustomerByID(requestData.UserID, requestData.CustomerID)
+	_, err = database.GetCustomerByID(userID, requestData.CustomerID)
 	if err != nil {
 		log.Printf("获取客户信息失败: %v", err)
 		SendResponse(w, http.StatusInternalServerError, 500, fmt.Sprintf("获取客户信息失败: %v", err), nil)
@@ -824,18 +729,8 @@ func GetProducts(w http.ResponseWriter, r *http.Request) {
 
 // GetCustomerRecords 获取客户所有记录接口
 func GetCustomerRecords(w http.ResponseWriter, r *http.Request) {
-	// 获取用户ID和客户ID
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "缺少user_id参数", nil)
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的user_id参数", nil)
-		return
-	}
+	// 获取当前用户ID和客户ID
+	userID := int(middleware.CurrentUserID(r))
 
 	customerIDStr := r.URL.Query().Get("customer_id")
 	if customerIDStr == "" {
@@ -894,18 +789,8 @@ func GetCustomerRecords(w http.ResponseWriter, r *http.Request) {
 
 // ExportCustomerReport 导出客户报表接口
 func ExportCustomerReport(w http.ResponseWriter, r *http.Request) {
-	// 获取用户ID和客户ID
-	userIDStr := r.URL.Query().Get("user_id")
-	if userIDStr == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "缺少user_id参数", nil)
-		return
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的user_id参数", nil)
-		return
-	}
+	// 获取当前用户ID和客户ID
+	userID := int(middleware.CurrentUserID(r))
 
 	customerIDStr := r.URL.Query().Get("customer_id")
 	if customerIDStr == "" {
@@ -984,9 +869,11 @@ func DownloadReport(w http.ResponseWriter, r *http.Request) {
 
 // DeleteWeightRecord 删除体重记录接口
 func DeleteWeightRecord(w http.ResponseWriter, r *http.Request) {
+	// 当前登录用户ID
+	userID := int(middleware.CurrentUserID(r))
+
 	// 解析请求体
 	var requestData struct {
-		UserID   int `json:"user_id"`
 		RecordID int `json:"record_id"`
 	}
 
@@ -998,11 +885,6 @@ func DeleteWeightRecord(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 校验数据
-	if requestData.UserID <= 0 {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-		return
-	}
-
 	if requestData.RecordID <= 0 {
 		SendResponse(w, http.StatusBadRequest, 400, "无效的记录ID", nil)
 		return
@@ -1017,7 +899,7 @@ func DeleteWeightRecord(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 检查用户是否有权限删除该记录
-	_, err = database.GetCustomerByID(requestData.UserID, record.CustomerID)
+	_, err = database.GetCustomerByID(userID, record.CustomerID)
 	if err != nil {
 		log.Printf("权限验证失败: %v", err)
 		SendResponse(w, http.StatusForbidden, 403, "无权删除此记录", nil)
@@ -1038,9 +920,11 @@ func DeleteWeightRecord(w http.ResponseWriter, r *http.Request) {
 
 // DeleteProductUsage 删除产品使用记录接口
 func DeleteProductUsage(w http.ResponseWriter, r *http.Request) {
+	// 当前登录用户ID
+	userID := int(middleware.CurrentUserID(r))
+
 	// 解析请求体
 	var requestData struct {
-		UserID  int `json:"user_id"`
 		UsageID int `json:"usage_id"`
 	}
 
@@ -1052,11 +936,6 @@ func DeleteProductUsage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 校验数据
-	if requestData.UserID <= 0 {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-		return
-	}
-
 	if requestData.UsageID <= 0 {
 		SendResponse(w, http.StatusBadRequest, 400, "无效的记录ID", nil)
 		return
@@ -1072,7 +951,7 @@ func DeleteProductUsage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 检查用户是否有权限访问该客户
-	_, err = database.GetCustomerByID(requestData.UserID, customerID)
+	_, err = database.GetCustomerByID(userID, customerID)
 	if err != nil {
 		log.Printf("获取客户信息失败: %v", err)
 		SendResponse(w, http.StatusInternalServerError, 500, fmt.Sprintf("获取客户信息失败: %v", err), nil)
@@ -98,7 +98,7 @@ func DebugHandler(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var id, storeID, typeID int64
 		var storeName, typeName, remark string
-		var amount float64
+		var amount models.Money
 
 		if err := rows.Scan(&id, &storeID, &storeName, &typeID, &typeName, &amount, &remark); err != nil {
 			continue
@@ -434,7 +434,7 @@ func TestStoreData(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var id, acctStoreID int64
 		var acctStoreName, remark, transactionTime string
-		var amount float64
+		var amount models.Money
 
 		err := rows.Scan(&id, &acctStoreID, &acctStoreName, &amount, &remark, &transactionTime)
 		if err != nil {
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"account/backend/database"
+	"account/backend/models"
+	"account/backend/services"
+)
+
+// useTestDB 使用内存数据库创建用户、登录、微信绑定和结账需要的表，测试结束后恢复全局DB
+func useTestDB(t *testing.T) {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接各自独立，只保留一个连接
+	db.SetMaxOpenConns(1)
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+
+	for _, create := range []func() error{
+		database.CreateTables,
+		database.CreateSessionTables,
+		database.CreateLoginLockTables,
+		database.CreateIdentityTables,
+		database.CreatePeriodTables,
+	} {
+		if err := create(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// createTestUser 创建不需要修改密码的用户
+func createTestUser(t *testing.T, username, password string, role models.UserRole) int64 {
+	t.Helper()
+	id, err := database.CreateUser(models.User{Username: username, Password: password, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.DB.Exec("UPDATE users SET must_change_password = 0 WHERE id = ?", id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// testResponse 处理器返回的HTTP状态码和响应内容
+type testResponse struct {
+	Status  int
+	Code    int                    `json:"code"`
+	Message string                 `json:"message"`
+	Data    map[string]interface{} `json:"data"`
+}
+
+// serve 调用处理器并解析响应
+func serve(t *testing.T, handler http.HandlerFunc, method, target, body, token, ip string) testResponse {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.RemoteAddr = ip + ":12345"
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	resp := testResponse{Status: w.Code}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v, body: %s", err, w.Body.String())
+	}
+	if resp.Code != resp.Status {
+		t.Errorf("响应code %d 与HTTP状态码 %d 不一致", resp.Code, resp.Status)
+	}
+	return resp
+}
+
+// adminToken 创建管理员并签发访问令牌
+func adminToken(t *testing.T) string {
+	t.Helper()
+	return userToken(t, createTestUser(t, "admin", "admin123", models.RoleAdmin))
+}
+
+// userToken 为用户签发访问令牌
+func userToken(t *testing.T, id int64) string {
+	t.Helper()
+	user, err := (&services.UserService{}).GetUserByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := issueSession(httptest.NewRequest(http.MethodPost, "/api/login", nil), user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result["token"].(string)
+}
+
+// jsonInt 将ID格式化为JSON数字
+func jsonInt(id int64) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+)
+
+// PeriodHandler 处理店铺按月结账相关请求
+type PeriodHandler struct{}
+
+// PeriodCloseRequest 结账或重新开账的请求结构
+type PeriodCloseRequest struct {
+	StoreID int64  `json:"store_id"`
+	Period  string `json:"period"` // 格式 2006-01
+	Note    string `json:"note"`
+}
+
+// List 获取已结账的月份，可按店铺筛选；非管理员只能看到有权限店铺的结账情况
+func (h *PeriodHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	storeID, _ := strconv.ParseInt(r.URL.Query().Get("store_id"), 10, 64)
+	var userID int64
+	if !middleware.IsAdmin(r) {
+		userID = middleware.CurrentUserID(r)
+	}
+
+	periods, err := database.GetClosedPeriods(storeID, userID)
+	if err != nil {
+		log.Printf("获取已结账月份失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取已结账月份失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取已结账月份成功", periods)
+}
+
+// Close 结账，结账后该店铺该月的账务记录不能再新增、修改或删除
+func (h *PeriodHandler) Close(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, ok := decodePeriodCloseRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Period > time.Now().In(storeLocationOrDefault(req.StoreID)).Format(models.PeriodLayout) {
+		SendResponse(w, http.StatusBadRequest, 400, "不能对未来的月份结账", nil)
+		return
+	}
+
+	logID, changed, err := database.ClosePeriod(req.StoreID, req.Period, middleware.CurrentUserID(r), req.Note)
+	if err != nil {
+		log.Printf("店铺%d结账%s失败: %v", req.StoreID, req.Period, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "结账失败", nil)
+		return
+	}
+	if !changed {
+		SendResponse(w, http.StatusConflict, 409, req.Period+"已结账", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "结账成功", map[string]interface{}{
+		"id":       logID,
+		"store_id": req.StoreID,
+		"period":   req.Period,
+	})
+}
+
+// Reopen 重新开账，允许再次修改该店铺该月的账务记录
+func (h *PeriodHandler) Reopen(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, ok := decodePeriodCloseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	logID, changed, err := database.ReopenPeriod(req.StoreID, req.Period, middleware.CurrentUserID(r), req.Note)
+	if err != nil {
+		log.Printf("店铺%d重新开账%s失败: %v", req.StoreID, req.Period, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "重新开账失败", nil)
+		return
+	}
+	if !changed {
+		SendResponse(w, http.StatusConflict, 409, req.Period+"尚未结账", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "重新开账成功", map[string]interface{}{
+		"id":       logID,
+		"store_id": req.StoreID,
+		"period":   req.Period,
+	})
+}
+
+// Logs 分页获取结账和重新开账的操作记录，可按店铺筛选
+func (h *PeriodHandler) Logs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	storeID, _ := strconv.ParseInt(query.Get("store_id"), 10, 64)
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	logs, total, err := database.GetPeriodCloseLogs(storeID, page, pageSize)
+	if err != nil {
+		log.Printf("获取结账记录失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取结账记录失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取结账记录成功", map[string]interface{}{
+		"data":  logs,
+		"total": total,
+	})
+}
+
+// decodePeriodCloseRequest 读取并校验结账请求，校验失败时已写入响应
+func decodePeriodCloseRequest(w http.ResponseWriter, r *http.Request) (*PeriodCloseRequest, bool) {
+	var req PeriodCloseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "无效的请求数据", nil)
+		return nil, false
+	}
+
+	req.Period = strings.TrimSpace(req.Period)
+	req.Note = strings.TrimSpace(req.Note)
+	if _, err := time.Parse(models.PeriodLayout, req.Period); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "月份格式应为YYYY-MM", nil)
+		return nil, false
+	}
+
+	exists, err := database.CheckStoreExists(req.StoreID)
+	if err != nil {
+		log.Printf("检查店铺%d是否存在失败: %v", req.StoreID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "检查店铺失败", nil)
+		return nil, false
+	}
+	if !exists {
+		SendResponse(w, http.StatusBadRequest, 400, "店铺不存在", nil)
+		return nil, false
+	}
+
+	return &req, true
+}
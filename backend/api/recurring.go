@@ -0,0 +1,328 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+	"account/backend/services"
+)
+
+// RecurringEntryHandler 处理周期账务模板相关请求
+type RecurringEntryHandler struct{}
+
+// RecurringEntryRequest 创建或修改周期账务模板的请求结构
+type RecurringEntryRequest struct {
+	ID         int64        `json:"id"`
+	StoreID    int64        `json:"store_id"`
+	TypeID     int64        `json:"type_id"`
+	Amount     models.Money `json:"amount"`
+	Remark     string       `json:"remark"`
+	Frequency  string       `json:"frequency"`
+	DayOfMonth int          `json:"day_of_month"`
+	Weekday    int          `json:"weekday"`
+	Month      int          `json:"month"`
+	StartDate  string       `json:"start_date"`
+	EndDate    string       `json:"end_date"`
+}
+
+// decodeRecurringEntryRequest 解析并检查模板请求，返回填好内容和重复规则的模板，失败时已写入响应
+func decodeRecurringEntryRequest(w http.ResponseWriter, r *http.Request) (*RecurringEntryRequest, *models.RecurringEntry, bool) {
+	var req RecurringEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if errors.Is(err, models.ErrInvalidMoney) {
+			SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
+			return nil, nil, false
+		}
+		SendResponse(w, http.StatusBadRequest, 400, "请求参数错误", nil)
+		return nil, nil, false
+	}
+
+	if req.StoreID <= 0 || req.TypeID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "店铺和账务类型不能为空", nil)
+		return nil, nil, false
+	}
+	if req.StartDate == "" {
+		req.StartDate = time.Now().Format(models.DateLayout)
+	}
+	if _, err := time.Parse(models.DateLayout, req.StartDate); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "开始日期格式错误", nil)
+		return nil, nil, false
+	}
+	if req.EndDate != "" {
+		if _, err := time.Parse(models.DateLayout, req.EndDate); err != nil {
+			SendResponse(w, http.StatusBadRequest, 400, "结束日期格式错误", nil)
+			return nil, nil, false
+		}
+		if req.EndDate < req.StartDate {
+			SendResponse(w, http.StatusBadRequest, 400, "结束日期不能早于开始日期", nil)
+			return nil, nil, false
+		}
+	}
+
+	entry := &models.RecurringEntry{
+		ID:         req.ID,
+		StoreID:    req.StoreID,
+		TypeID:     req.TypeID,
+		Remark:     req.Remark,
+		Frequency:  req.Frequency,
+		DayOfMonth: req.DayOfMonth,
+		Weekday:    req.Weekday,
+		Month:      req.Month,
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+	}
+	if msg := entry.ValidateSchedule(); msg != "" {
+		SendResponse(w, http.StatusBadRequest, 400, msg, nil)
+		return nil, nil, false
+	}
+
+	// 与普通账务记录相同，收支方向由账务类型决定
+	amount, _, ok := resolveAccountAmount(w, req.TypeID, req.Amount)
+	if !ok {
+		return nil, nil, false
+	}
+	entry.Amount = amount
+
+	return &req, entry, true
+}
+
+// List 获取周期账务模板列表，可按店铺筛选，非管理员只能看到有权限店铺的模板
+func (h *RecurringEntryHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	storeID, _ := strconv.ParseInt(r.URL.Query().Get("store_id"), 10, 64)
+	var userID int64
+	if !middleware.IsAdmin(r) {
+		userID = middleware.CurrentUserID(r)
+	}
+
+	entries, err := database.GetRecurringEntries(storeID, userID)
+	if err != nil {
+		log.Printf("获取周期账务失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取周期账务失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取周期账务成功", entries)
+}
+
+// Create 创建周期账务模板，开始日期早于今天时会补生成开始日期以来的记录
+func (h *RecurringEntryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, entry, ok := decodeRecurringEntryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	entry.ID = 0
+	entry.CreatedBy = middleware.CurrentUserID(r)
+	entry.CreateTime = now
+	entry.UpdateTime = now
+	start, _ := time.ParseInLocation(models.DateLayout, entry.StartDate, time.Local)
+	entry.NextDate = services.FirstRecurringDate(entry, start)
+
+	id, err := database.CreateRecurringEntry(entry)
+	if err != nil {
+		log.Printf("创建周期账务失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "创建周期账务失败", nil)
+		return
+	}
+	entry.ID = id
+
+	// 立即生成已到期的记录，不等待下一次定时检查
+	if _, err := services.MaterializeDueRecurringEntries(now); err != nil {
+		log.Printf("生成周期账务记录失败: %v", err)
+	}
+	if saved, err := database.GetRecurringEntry(id); err == nil {
+		entry = saved
+	}
+
+	SendResponse(w, http.StatusOK, 200, "创建周期账务成功", entry)
+}
+
+// Update 修改周期账务模板，已生成的记录不受影响，之后从今天起按新的规则生成
+func (h *RecurringEntryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "仅支持PUT请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, entry, ok := decodeRecurringEntryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := database.GetRecurringEntry(req.ID)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "周期账务不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("获取周期账务%d失败: %v", req.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改周期账务失败", nil)
+		return
+	}
+
+	now := time.Now()
+	entry.Paused = existing.Paused
+	entry.CreatedBy = existing.CreatedBy
+	entry.CreateTime = existing.CreateTime
+	entry.UpdateTime = now
+	entry.NextDate = services.FirstRecurringDate(entry, now)
+
+	if err := database.UpdateRecurringEntry(entry); err != nil {
+		log.Printf("修改周期账务%d失败: %v", req.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改周期账务失败", nil)
+		return
+	}
+
+	if _, err := services.MaterializeDueRecurringEntries(now); err != nil {
+		log.Printf("生成周期账务记录失败: %v", err)
+	}
+	if saved, err := database.GetRecurringEntry(entry.ID); err == nil {
+		entry = saved
+	}
+
+	SendResponse(w, http.StatusOK, 200, "修改周期账务成功", entry)
+}
+
+// Pause 暂停或恢复周期账务模板，恢复后从今天起继续生成，不补生成暂停期间的记录
+func (h *RecurringEntryHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID     int64 `json:"id"`
+		Paused bool  `json:"paused"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "请求参数错误", nil)
+		return
+	}
+
+	entry, err := database.GetRecurringEntry(req.ID)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "周期账务不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("获取周期账务%d失败: %v", req.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改周期账务失败", nil)
+		return
+	}
+
+	now := time.Now()
+	if entry.Paused && !req.Paused {
+		entry.NextDate = services.FirstRecurringDate(entry, now)
+	}
+	entry.Paused = req.Paused
+	entry.UpdateTime = now
+
+	if err := database.UpdateRecurringEntry(entry); err != nil {
+		log.Printf("修改周期账务%d失败: %v", req.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改周期账务失败", nil)
+		return
+	}
+
+	if !entry.Paused {
+		if _, err := services.MaterializeDueRecurringEntries(now); err != nil {
+			log.Printf("生成周期账务记录失败: %v", err)
+		}
+		if saved, err := database.GetRecurringEntry(entry.ID); err == nil {
+			entry = saved
+		}
+	}
+
+	msg := "已恢复周期账务"
+	if entry.Paused {
+		msg = "已暂停周期账务"
+	}
+	SendResponse(w, http.StatusOK, 200, msg, entry)
+}
+
+// Delete 删除周期账务模板，已生成的账务记录保留
+func (h *RecurringEntryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "仅支持DELETE请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "周期账务ID无效", nil)
+		return
+	}
+
+	err = database.DeleteRecurringEntry(id)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "周期账务不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("删除周期账务%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "删除周期账务失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "删除周期账务成功", nil)
+}
+
+// Preview 预览周期账务模板接下来的生成日期，count默认5，最多24
+func (h *RecurringEntryHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "周期账务ID无效", nil)
+		return
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count <= 0 {
+		count = 5
+	}
+	if count > 24 {
+		count = 24
+	}
+
+	entry, err := database.GetRecurringEntry(id)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "周期账务不存在", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("获取周期账务%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取周期账务失败", nil)
+		return
+	}
+
+	// 暂停的模板按恢复后的日期预览
+	if entry.Paused {
+		entry.NextDate = services.FirstRecurringDate(entry, time.Now())
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取周期账务预览成功", map[string]interface{}{
+		"entry": entry,
+		"dates": services.PreviewRecurringDates(entry, count),
+	})
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+	"account/backend/services"
+	"account/backend/utils"
+)
+
+// SessionHandler 处理登录会话相关请求（刷新令牌、退出登录、设备管理）
+type SessionHandler struct {
+	userService services.UserService
+}
+
+// issueSession 为登录成功的用户创建会话，返回访问令牌和刷新令牌
+func issueSession(r *http.Request, user *models.User, device string) (map[string]interface{}, error) {
+	refreshToken, refreshTokenHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshExpiresAt := time.Now().Add(utils.RefreshTokenTTL())
+	sessionID, err := database.CreateSession(user.ID, refreshTokenHash, device, r.UserAgent(), middleware.ClientIP(r), refreshExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := utils.GenerateToken(user.ID, sessionID, int(user.Role), utils.TokenTTL())
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"token":              token,
+		"expires_at":         expiresAt.Format("2006-01-02 15:04:05"),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": refreshExpiresAt.Format("2006-01-02 15:04:05"),
+		"session_id":         sessionID,
+	}, nil
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌，同时轮换刷新令牌
+func (h *SessionHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		SendResponse(w, http.StatusBadRequest, 400, "缺少刷新令牌", nil)
+		return
+	}
+
+	oldRefreshTokenHash := utils.HashRefreshToken(req.RefreshToken)
+	session, err := database.GetSessionByRefreshTokenHash(oldRefreshTokenHash)
+	if err != nil {
+		log.Printf("查询会话失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "刷新令牌失败", nil)
+		return
+	}
+	if session == nil || session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		SendResponse(w, http.StatusUnauthorized, 401, "登录已失效，请重新登录", nil)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(session.UserID)
+	if err != nil {
+		SendResponse(w, http.StatusUnauthorized, 401, "用户不存在或已被删除", nil)
+		return
+	}
+
+	// 轮换刷新令牌，旧的刷新令牌随即失效
+	refreshToken, refreshTokenHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		log.Printf("生成刷新令牌失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "刷新令牌失败", nil)
+		return
+	}
+	refreshExpiresAt := time.Now().Add(utils.RefreshTokenTTL())
+	err = database.RotateSessionRefreshToken(session.ID, oldRefreshTokenHash, refreshTokenHash, refreshExpiresAt)
+	if errors.Is(err, models.ErrRefreshTokenUsed) {
+		// 同一个刷新令牌已被其他请求使用
+		SendResponse(w, http.StatusUnauthorized, 401, "登录已失效，请重新登录", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("更新会话%d失败: %v", session.ID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "刷新令牌失败", nil)
+		return
+	}
+
+	token, expiresAt, err := utils.GenerateToken(user.ID, session.ID, int(user.Role), utils.TokenTTL())
+	if err != nil {
+		log.Printf("生成令牌失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "刷新令牌失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "刷新成功", map[string]interface{}{
+		"token":              token,
+		"expires_at":         expiresAt.Format("2006-01-02 15:04:05"),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": refreshExpiresAt.Format("2006-01-02 15:04:05"),
+		"session_id":         session.ID,
+	})
+}
+
+// Logout 退出登录，注销当前会话，返回注销的会话ID
+func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID := middleware.CurrentSessionID(r)
+	if _, err := database.RevokeSession(middleware.CurrentUserID(r), sessionID); err != nil {
+		log.Printf("退出登录失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "退出登录失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "已退出登录", map[string]interface{}{"session_id": sessionID})
+}
+
+// GetSessions 获取当前用户已登录的设备列表
+func (h *SessionHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessions, err := database.GetActiveSessions(middleware.CurrentUserID(r))
+	if err != nil {
+		log.Printf("获取会话列表失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取登录设备失败", nil)
+		return
+	}
+
+	// 标记发起请求的设备
+	currentSessionID := middleware.CurrentSessionID(r)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取登录设备成功", sessions)
+}
+
+// RevokeSession 注销当前用户的指定设备
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		SessionID int64 `json:"session_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SessionID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "会话ID无效", nil)
+		return
+	}
+
+	revoked, err := database.RevokeSession(middleware.CurrentUserID(r), req.SessionID)
+	if err != nil {
+		log.Printf("注销会话%d失败: %v", req.SessionID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "注销设备失败", nil)
+		return
+	}
+	if !revoked {
+		SendResponse(w, http.StatusNotFound, 404, "会话不存在或已注销", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "注销设备成功", nil)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"account/backend/database"
+	"account/backend/models"
+	"account/backend/services"
+	"account/backend/utils"
+)
+
+func TestRefreshRotatesToken(t *testing.T) {
+	useTestDB(t)
+	id := createTestUser(t, "clerk", "clerk123", models.RoleStaff)
+	user, err := (&services.UserService{}).GetUserByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := issueSession(httptest.NewRequest(http.MethodPost, "/api/login", nil), user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshToken := session["refresh_token"].(string)
+	h := &SessionHandler{}
+	const ip = "192.0.2.10"
+	body := `{"refresh_token":"` + refreshToken + `"}`
+
+	resp := serve(t, h.Refresh, http.MethodPost, "/api/refresh", body, "", ip)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("第一次刷新状态码 = %d, want 200: %s", resp.Status, resp.Message)
+	}
+	if resp.Data["refresh_token"] == refreshToken {
+		t.Error("刷新后刷新令牌没有轮换")
+	}
+
+	resp = serve(t, h.Refresh, http.MethodPost, "/api/refresh", body, "", ip)
+	if resp.Status != http.StatusUnauthorized {
+		t.Errorf("重复使用旧刷新令牌状态码 = %d, want 401", resp.Status)
+	}
+
+	// 并发请求都查到会话后，只有第一个能替换旧令牌
+	sessionID := session["session_id"].(int64)
+	err = database.RotateSessionRefreshToken(sessionID, utils.HashRefreshToken(refreshToken), "other", time.Now().Add(time.Hour))
+	if !errors.Is(err, models.ErrRefreshTokenUsed) {
+		t.Errorf("使用已轮换的旧令牌更新会话 err = %v, want ErrRefreshTokenUsed", err)
+	}
+}
@@ -3,9 +3,9 @@ package api
 import (
     "encoding/json"
     "net/http"
-    "strconv"
     "log"
     "account/backend/database"
+    "account/backend/middleware"
 )
 
 // DefaultSettings 结构体使用数据库包中定义的
@@ -15,7 +15,7 @@ type SettingsHandler struct {}
 func (h *SettingsHandler) SaveDefaultSettings(w http.ResponseWriter, r *http.Request) {
     // 添加CORS头
     w.Header().Set("Access-Control-Allow-Origin", "*")
-    w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User-ID")
+    w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
     w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
     
     if r.Method == http.MethodOptions {
@@ -28,18 +28,8 @@ func (h *SettingsHandler) SaveDefaultSettings(w http.ResponseWriter, r *http.Req
         return
     }
 
-    // 获取用户ID
-    userID := r.Header.Get("X-User-ID")
-    if userID == "" {
-        SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-        return
-    }
-
-    userIDInt, err := strconv.ParseInt(userID, 10, 64)
-    if err != nil {
-        SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-        return
-    }
+    // 获取当前登录用户ID
+    userIDInt := middleware.CurrentUserID(r)
 
     // 解析请求体
     var settings database.DefaultSettings
@@ -52,7 +42,7 @@ func (h *SettingsHandler) SaveDefaultSettings(w http.ResponseWriter, r *http.Req
         userIDInt, settings.StoreId, settings.IncomeTypeId, settings.ExpenseTypeId)
 
     // 保存到数据库
-    err = database.SaveDefaultSettings(userIDInt, settings.StoreId, settings.IncomeTypeId, settings.ExpenseTypeId)
+    err := database.SaveDefaultSettings(userIDInt, settings.StoreId, settings.IncomeTypeId, settings.ExpenseTypeId)
     if err != nil {
         log.Printf("保存默认设置失败: %v", err)
         SendResponse(w, http.StatusInternalServerError, 500, "保存设置失败: "+err.Error(), nil)
@@ -70,18 +60,8 @@ func (h *SettingsHandler) GetDefaultSettings(w http.ResponseWriter, r *http.Requ
         return
     }
 
-    // 获取用户ID
-    userID := r.Header.Get("X-User-ID")
-    if userID == "" {
-        SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-        return
-    }
-
-    userIDInt, err := strconv.ParseInt(userID, 10, 64)
-    if err != nil {
-        SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-        return
-    }
+    // 获取当前登录用户ID
+    userIDInt := middleware.CurrentUserID(r)
 
     // 从数据库获取设置
     settings, err := database.GetDefaultSettings(userIDInt)
@@ -5,9 +5,12 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
 )
 
 // 添加报表接口 - 使用标准http处理函数而非Gin
@@ -18,10 +21,10 @@ func GetReport(w http.ResponseWriter, r *http.Request) {
 	// 获取查询参数
 	timeRange := r.URL.Query().Get("timeRange")
 	storeIdStr := r.URL.Query().Get("storeId")
-	userIDStr := r.URL.Query().Get("userId")
+	userID := middleware.CurrentUserID(r)
 
 	// 记录请求参数
-	log.Printf("报表请求参数 - timeRange: %s, storeId: %s, userId: %s", timeRange, storeIdStr, userIDStr)
+	log.Printf("报表请求参数 - timeRange: %s, storeId: %s, userId: %d", timeRange, storeIdStr, userID)
 
 	// 转换店铺ID
 	var storeId int64
@@ -36,68 +39,21 @@ func GetReport(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// 转换用户ID
-	var userID int64
-	if userIDStr != "" {
-		var err error
-		userID, err = strconv.ParseInt(userIDStr, 10, 64)
-		if err != nil {
-			errMsg := fmt.Sprintf("无效的用户ID: %s", userIDStr)
-			log.Printf("报表请求错误: %s", errMsg)
-			SendResponse(w, http.StatusBadRequest, 400, errMsg, nil)
-			return
-		}
-	}
-
-	// 验证用户ID是否有效
-	if userID <= 0 {
-		errMsg := "必须提供有效的用户ID"
-		log.Printf("报表请求错误: %s", errMsg)
-		SendResponse(w, http.StatusBadRequest, 400, errMsg, nil)
-		return
-	}
-
-	// 检查用户是否是管理员，以确定默认的店铺访问权限
-	var isAdmin bool
-	err := database.DB.QueryRow("SELECT role = 1 FROM users WHERE id = ?", userID).Scan(&isAdmin)
-	if err != nil {
-		errMsg := fmt.Sprintf("检查用户权限失败: %v", err)
-		log.Printf("报表请求错误: %s", errMsg)
-		SendResponse(w, http.StatusInternalServerError, 500, errMsg, nil)
-		return
-	}
-
+	// 店铺访问权限已由路由中间件校验，这里只需为未指定店铺的店员选择默认店铺
+	isAdmin := middleware.IsAdmin(r)
 	log.Printf("用户ID: %d, 是否管理员: %v", userID, isAdmin)
 
-	// 对于非管理员用户，获取他们有访问权限的店铺ID列表
 	if !isAdmin && storeId <= 0 {
-		// 查询店员有权限的所有店铺
-		rows, err := database.DB.Query(`
-            SELECT store_id FROM user_store_permissions 
-            WHERE user_id = ?
-        `, userID)
+		storeIDs, err := database.GetStoreIDsWithCapability(int(userID), models.CapReportsView)
 		if err != nil {
 			errMsg := fmt.Sprintf("查询用户权限失败: %v", err)
 			log.Printf("报表请求错误: %s", errMsg)
 			SendResponse(w, http.StatusInternalServerError, 500, errMsg, nil)
 			return
 		}
-		defer rows.Close()
-
-		// 如果店员没有指定特定店铺，选择他的第一个有权限的店铺
-		if rows.Next() {
-			err = rows.Scan(&storeId)
-			if err != nil {
-				errMsg := fmt.Sprintf("读取店铺权限失败: %v", err)
-				log.Printf("报表请求错误: %s", errMsg)
-				SendResponse(w, http.StatusInternalServerError, 500, errMsg, nil)
-				return
-			}
-			log.Printf("店员未指定店铺，自动选择第一个有权限的店铺ID: %d", storeId)
-		}
 
-		// 如果店员没有任何店铺权限，返回空数据
-		if storeId <= 0 {
+		// 如果店员没有任何可查看报表的店铺，返回空数据
+		if len(storeIDs) == 0 {
 			log.Printf("店员没有任何店铺权限，返回空数据")
 			emptyReport := database.ReportData{
 				TotalIncome:  0,
@@ -105,58 +61,177 @@ func GetReport(w http.ResponseWriter, r *http.Request) {
 				NetIncome:    0,
 				Trend:        []database.TrendData{},
 				Compare:      []database.CompareData{},
+				Budgets:      []models.BudgetUsage{},
 			}
 			SendResponse(w, http.StatusOK, 200, "成功，但没有数据", emptyReport)
 			return
 		}
-	} else if !isAdmin && storeId > 0 {
-		// 非管理员且指定了店铺ID，检查是否有此店铺的访问权限
-		var hasPermission bool
-		err := database.DB.QueryRow(`
-            SELECT EXISTS (
-                SELECT 1 FROM user_store_permissions
-                WHERE user_id = ? AND store_id = ?
-            )
-        `, userID, storeId).Scan(&hasPermission)
 
-		if err != nil {
-			errMsg := fmt.Sprintf("检查店铺权限失败: %v", err)
-			log.Printf("报表请求错误: %s", errMsg)
-			SendResponse(w, http.StatusInternalServerError, 500, errMsg, nil)
-			return
-		}
-
-		if !hasPermission {
-			errMsg := fmt.Sprintf("用户 %d 无权访问店铺 %d", userID, storeId)
-			log.Printf("报表请求错误: %s", errMsg)
-			SendResponse(w, http.StatusForbidden, 403, errMsg, nil)
-			return
-		}
+		// 如果店员没有指定特定店铺，选择他的第一个有权限的店铺
+		storeId = int64(storeIDs[0])
+		log.Printf("店员未指定店铺，自动选择第一个有权限的店铺ID: %d", storeId)
 	}
 
 	// 记录最终使用的店铺ID
 	log.Printf("最终使用的店铺ID: %d", storeId)
 
-	// 计算时间范围
-	startDate, endDate := calculateTimeRange(timeRange)
+	// 计算时间范围，按所查询店铺的时区确定今天、本周等的起止，未指定店铺时使用业务时区。
+	// 指定了startDate或endDate时使用自定义的日期范围
+	loc := storeLocationOrDefault(storeId)
+	startDate, endDate, ok := parseReportDateRange(w, r, loc)
+	if !ok {
+		return
+	}
+	custom := !startDate.IsZero()
+	if !custom {
+		startDate, endDate = calculateTimeRange(timeRange, time.Now().In(loc))
+	}
 	log.Printf("计算的时间范围: %s 到 %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 
+	granularity, ok := parseGranularity(w, r)
+	if !ok {
+		return
+	}
+
+	// 对比期间：上一期或去年同期
+	compareTo := r.URL.Query().Get("compareTo")
+	var compareStart, compareEnd time.Time
+	switch compareTo {
+	case "":
+	case compareToPrevious:
+		compareStart, compareEnd = previousPeriod(timeRange, custom, startDate, endDate)
+	case compareToLastYear:
+		compareStart, compareEnd = addYearsClamped(startDate, -1), addYearsClamped(endDate, -1)
+	default:
+		SendResponse(w, http.StatusBadRequest, 400, "对比方式只支持previous和lastYear", nil)
+		return
+	}
+
+	currency, ok := resolveReportCurrency(w, r, storeId)
+	if !ok {
+		return
+	}
+
 	// 获取报表数据
-	reportData, err := database.GetReportData(startDate, endDate, storeId, userID)
+	reportData, err := database.GetReportData(startDate, endDate, storeId, userID, currency, granularity, compareStart, compareEnd)
 	if err != nil {
 		errMsg := fmt.Sprintf("获取报表数据失败: %v", err)
 		log.Printf("报表请求错误: %s", errMsg)
 		SendResponse(w, http.StatusInternalServerError, 500, errMsg, nil)
 		return
 	}
+	if reportData.Comparison != nil {
+		reportData.Comparison.CompareTo = compareTo
+	}
 
 	log.Printf("报表数据生成成功, 用户ID: %d, 店铺ID: %d", userID, storeId)
 	SendResponse(w, http.StatusOK, 200, "成功", reportData)
 }
 
-// 计算时间范围
-func calculateTimeRange(timeRange string) (time.Time, time.Time) {
-	now := time.Now()
+// GetConsolidatedReport 获取多个店铺的合并报表。storeIds为逗号分隔的店铺ID，店铺权限已由路由中间件校验；
+// 未指定时管理员统计全部店铺，店员统计有权查看报表的全部店铺
+func GetConsolidatedReport(w http.ResponseWriter, r *http.Request) {
+	log.Printf("收到合并报表请求: %s", r.URL.String())
+	userID := middleware.CurrentUserID(r)
+
+	var storeIDs []int64
+	seen := map[int64]bool{}
+	for _, part := range strings.Split(r.URL.Query().Get("storeIds"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		storeID, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || storeID <= 0 {
+			SendResponse(w, http.StatusBadRequest, 400, fmt.Sprintf("无效的店铺ID: %s", part), nil)
+			return
+		}
+		if seen[storeID] {
+			continue
+		}
+		seen[storeID] = true
+
+		exists, err := database.CheckStoreExists(storeID)
+		if err != nil {
+			SendResponse(w, http.StatusInternalServerError, 500, fmt.Sprintf("查询店铺失败: %v", err), nil)
+			return
+		}
+		if !exists {
+			SendResponse(w, http.StatusNotFound, 404, fmt.Sprintf("店铺%d不存在", storeID), nil)
+			return
+		}
+		storeIDs = append(storeIDs, storeID)
+	}
+
+	if len(storeIDs) == 0 && !middleware.IsAdmin(r) {
+		permitted, err := database.GetStoreIDsWithCapability(int(userID), models.CapReportsView)
+		if err != nil {
+			SendResponse(w, http.StatusInternalServerError, 500, fmt.Sprintf("查询用户权限失败: %v", err), nil)
+			return
+		}
+		if len(permitted) == 0 {
+			SendResponse(w, http.StatusOK, 200, "成功，但没有数据", database.ConsolidatedReport{
+				Stores:  []database.StoreReport{},
+				Ranking: []database.StoreRanking{},
+			})
+			return
+		}
+		for _, storeID := range permitted {
+			storeIDs = append(storeIDs, int64(storeID))
+		}
+	}
+
+	// 只统计一个店铺时按该店铺的时区和币种，否则使用业务时区和报表币种
+	var storeID int64
+	if len(storeIDs) == 1 {
+		storeID = storeIDs[0]
+	}
+	loc := storeLocationOrDefault(storeID)
+	startDate, endDate, ok := parseReportDateRange(w, r, loc)
+	if !ok {
+		return
+	}
+	if startDate.IsZero() {
+		startDate, endDate = calculateTimeRange(r.URL.Query().Get("timeRange"), time.Now().In(loc))
+	}
+
+	granularity, ok := parseGranularity(w, r)
+	if !ok {
+		return
+	}
+	currency, ok := resolveReportCurrency(w, r, storeID)
+	if !ok {
+		return
+	}
+
+	report, err := database.GetConsolidatedReport(startDate, endDate, storeIDs, userID, currency, granularity)
+	if err != nil {
+		errMsg := fmt.Sprintf("获取合并报表数据失败: %v", err)
+		log.Printf("合并报表请求错误: %s", errMsg)
+		SendResponse(w, http.StatusInternalServerError, 500, errMsg, nil)
+		return
+	}
+
+	log.Printf("合并报表数据生成成功, 用户ID: %d, 店铺数: %d", userID, len(report.Stores))
+	SendResponse(w, http.StatusOK, 200, "成功", report)
+}
+
+// parseGranularity 解析趋势数据的粒度，默认按天；参数无效时已写入响应
+func parseGranularity(w http.ResponseWriter, r *http.Request) (string, bool) {
+	granularity := r.URL.Query().Get("granularity")
+	switch granularity {
+	case "":
+		return database.GranularityDay, true
+	case database.GranularityDay, database.GranularityWeek, database.GranularityMonth:
+		return granularity, true
+	default:
+		SendResponse(w, http.StatusBadRequest, 400, "趋势粒度只支持day、week和month", nil)
+		return "", false
+	}
+}
+
+// 计算时间范围，now为所在时区的当前时间，返回的起止时间与now在同一时区
+func calculateTimeRange(timeRange string, now time.Time) (time.Time, time.Time) {
 
 	switch timeRange {
 	case "day":
@@ -184,3 +259,88 @@ func calculateTimeRange(timeRange string) (time.Time, time.Time) {
 		return startDate, now
 	}
 }
+
+// 报表的对比方式
+const (
+	compareToPrevious = "previous" // 与上一期对比
+	compareToLastYear = "lastYear" // 与去年同期对比
+)
+
+// parseReportDateRange 解析自定义的日期范围startDate和endDate（格式2006-01-02），按loc时区取开始日期的0点和结束日期的23:59:59。
+// 只指定开始日期时到今天为止，只指定结束日期时从结束日期当月1日开始；都未指定时返回零值；参数无效时已写入响应
+func parseReportDateRange(w http.ResponseWriter, r *http.Request, loc *time.Location) (time.Time, time.Time, bool) {
+	startStr := r.URL.Query().Get("startDate")
+	endStr := r.URL.Query().Get("endDate")
+	if startStr == "" && endStr == "" {
+		return time.Time{}, time.Time{}, true
+	}
+
+	now := time.Now().In(loc)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if endStr != "" {
+		var err error
+		if end, err = time.ParseInLocation(models.DateLayout, endStr, loc); err != nil {
+			SendResponse(w, http.StatusBadRequest, 400, "结束日期格式应为YYYY-MM-DD", nil)
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	start := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, loc)
+	if startStr != "" {
+		var err error
+		if start, err = time.ParseInLocation(models.DateLayout, startStr, loc); err != nil {
+			SendResponse(w, http.StatusBadRequest, 400, "开始日期格式应为YYYY-MM-DD", nil)
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	if start.After(end) {
+		SendResponse(w, http.StatusBadRequest, 400, "开始日期不能晚于结束日期", nil)
+		return time.Time{}, time.Time{}, false
+	}
+	return start, endOfDay(end), true
+}
+
+// endOfDay 返回t当天的23:59:59
+func endOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+}
+
+// previousPeriod 计算上一期的时间范围。今日、本周、本月、本年分别与前一天、上周、上月、去年的相同时段对比，
+// 自定义范围与紧挨在开始日期之前、天数相同的范围对比
+func previousPeriod(timeRange string, custom bool, start, end time.Time) (time.Time, time.Time) {
+	if custom {
+		// 按日历日计算天数，避免夏令时切换当天不是24小时
+		startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+		days := int(endDay.Sub(startDay).Hours()/24) + 1
+		return start.AddDate(0, 0, -days), endOfDay(start.AddDate(0, 0, -1))
+	}
+
+	switch timeRange {
+	case "day":
+		return start.AddDate(0, 0, -1), end.AddDate(0, 0, -1)
+	case "week":
+		return start.AddDate(0, 0, -7), end.AddDate(0, 0, -7)
+	case "year":
+		return addYearsClamped(start, -1), addYearsClamped(end, -1)
+	default:
+		return addMonthsClamped(start, -1), addMonthsClamped(end, -1)
+	}
+}
+
+// addMonthsClamped 增加n个月，目标月份没有对应日期时取该月最后一天，如3月31日的上个月为2月28日或29日
+func addMonthsClamped(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// addYearsClamped 增加n年，2月29日在平年取2月28日
+func addYearsClamped(t time.Time, n int) time.Time {
+	return addMonthsClamped(t, 12*n)
+}
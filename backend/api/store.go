@@ -2,14 +2,17 @@ package api
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
-	"strconv"
 
 	"account/backend/database"
+	"account/backend/middleware"
 	"account/backend/models"
+	"account/backend/utils"
 )
 
 // StoreHandler 处理店铺相关请求
@@ -22,26 +25,8 @@ func (h *StoreHandler) GetUserStores(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 从请求中获取用户ID
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		userID = r.Header.Get("X-User-ID")
-	}
-
-	if userID == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "用户ID不能为空", nil)
-		return
-	}
-
-	// 转换userID为整数
-	userIDInt, err := strconv.ParseInt(userID, 10, 64)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "用户ID格式错误", nil)
-		return
-	}
-
-	// 调用数据库获取用户可访问的店铺
-	stores, err := database.GetUserStores(userIDInt)
+	// 调用数据库获取当前用户可访问的店铺
+	stores, err := database.GetUserStores(middleware.CurrentUserID(r))
 	if err != nil {
 		SendResponse(w, http.StatusInternalServerError, 500, "获取店铺失败: "+err.Error(), nil)
 		return
@@ -57,20 +42,6 @@ func (h *StoreHandler) CreateStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 解析请求数据
 	var store models.Store
 	if err := json.NewDecoder(r.Body).Decode(&store); err != nil {
@@ -83,6 +54,12 @@ func (h *StoreHandler) CreateStore(w http.ResponseWriter, r *http.Request) {
 		SendResponse(w, http.StatusBadRequest, 400, "店铺名称不能为空", nil)
 		return
 	}
+	if !normalizeStoreCurrency(w, &store) || !checkStoreTimezone(w, store) {
+		return
+	}
+	if store.Currency == "" {
+		store.Currency = models.DefaultCurrency
+	}
 
 	// 创建店铺
 	id, err := database.CreateStore(store)
@@ -102,20 +79,6 @@ func (h *StoreHandler) UpdateStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 解析请求数据
 	var store models.Store
 	if err := json.NewDecoder(r.Body).Decode(&store); err != nil {
@@ -132,9 +95,20 @@ func (h *StoreHandler) UpdateStore(w http.ResponseWriter, r *http.Request) {
 		SendResponse(w, http.StatusBadRequest, 400, "店铺名称不能为空", nil)
 		return
 	}
+	if !normalizeStoreCurrency(w, &store) || !checkStoreTimezone(w, store) {
+		return
+	}
 
 	// 更新店铺
-	err = database.UpdateStore(store)
+	err := database.UpdateStore(store)
+	if err == sql.ErrNoRows {
+		SendResponse(w, http.StatusNotFound, 404, "店铺不存在", nil)
+		return
+	}
+	if errors.Is(err, models.ErrTimezoneLocked) {
+		SendResponse(w, http.StatusConflict, 409, err.Error(), nil)
+		return
+	}
 	if err != nil {
 		SendResponse(w, http.StatusInternalServerError, 500, "更新店铺失败: "+err.Error(), nil)
 		return
@@ -143,11 +117,37 @@ func (h *StoreHandler) UpdateStore(w http.ResponseWriter, r *http.Request) {
 	SendResponse(w, http.StatusOK, 200, "更新店铺成功", store)
 }
 
+// checkStoreTimezone 检查店铺时区是否为有效的IANA时区名称，为空表示使用业务时区或保持原时区；无效时已写入响应
+func checkStoreTimezone(w http.ResponseWriter, store models.Store) bool {
+	if store.Timezone == "" {
+		return true
+	}
+	if _, err := utils.LoadLocation(store.Timezone); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "时区无效，请使用如Asia/Shanghai的时区名称", nil)
+		return false
+	}
+	return true
+}
+
+// normalizeStoreCurrency 检查并统一店铺币种代码，为空表示使用默认币种或保持原币种；无效时已写入响应
+func normalizeStoreCurrency(w http.ResponseWriter, store *models.Store) bool {
+	if store.Currency == "" {
+		return true
+	}
+	currency, ok := models.NormalizeCurrency(store.Currency)
+	if !ok {
+		SendResponse(w, http.StatusBadRequest, 400, "币种应为三位字母代码，如CNY、USD", nil)
+		return false
+	}
+	store.Currency = currency
+	return true
+}
+
 // DeleteStore 处理删除店铺请求
 func (h *StoreHandler) DeleteStore(w http.ResponseWriter, r *http.Request) {
 	// 添加CORS头
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User-ID")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 	
 	// 处理OPTIONS请求
@@ -161,31 +161,8 @@ func (h *StoreHandler) DeleteStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 权限验证
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, err := strconv.ParseInt(userID, 10, 64)
-	if err != nil {
-		SendResponse(w, http.StatusBadRequest, 400, "无效的用户ID", nil)
-		return
-	}
-
-	// 验证是否为管理员
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil {
-		log.Printf("验证管理员权限失败: %v", err)
-		SendResponse(w, http.StatusInternalServerError, 500, "服务器错误", nil)
-		return
-	}
-
-	if !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "您没有权限删除店铺", nil)
-		return
-	}
+	// 当前操作用户，用于记录日志
+	userIDInt := middleware.CurrentUserID(r)
 
 	// 解析请求体
 	var req struct {
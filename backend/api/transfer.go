@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+)
+
+// TransferRequest 店铺间转账请求结构
+type TransferRequest struct {
+	FromStoreID     int64        `json:"from_store_id"`
+	ToStoreID       int64        `json:"to_store_id"`
+	Amount          models.Money `json:"amount"`
+	Remark          string       `json:"remark"`
+	TransactionTime string       `json:"transaction_time"`
+}
+
+// Transfer 店铺间转账，同时创建转出店铺的支出记录和转入店铺的收入记录，不计入双方的收支统计
+func (h *AccountHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if errors.Is(err, models.ErrInvalidMoney) {
+			SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
+			return
+		}
+		SendResponse(w, http.StatusBadRequest, 400, "请求参数错误", nil)
+		return
+	}
+
+	if req.FromStoreID <= 0 || req.ToStoreID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "店铺ID无效", nil)
+		return
+	}
+	if req.FromStoreID == req.ToStoreID {
+		SendResponse(w, http.StatusBadRequest, 400, "转出和转入店铺不能相同", nil)
+		return
+	}
+	if req.Amount <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "转账金额必须大于0", nil)
+		return
+	}
+	// 交易时间按转出店铺的时区理解
+	formattedTime, err := parseTransactionTime(req.TransactionTime, storeLocationOrDefault(req.FromStoreID))
+	if err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "交易日期格式错误", nil)
+		return
+	}
+
+	// 转账两边金额相同，只能在币种相同的店铺之间进行
+	sameCurrency, err := database.StoresShareCurrency(req.FromStoreID, req.ToStoreID)
+	if err != nil {
+		log.Printf("查询店铺币种失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "转账失败", nil)
+		return
+	}
+	if !sameCurrency {
+		SendResponse(w, http.StatusBadRequest, 400, "不同币种的店铺之间不能转账", nil)
+		return
+	}
+
+	now := time.Now()
+	entry := models.Account{
+		UserID:          middleware.CurrentUserID(r),
+		Amount:          req.Amount,
+		Remark:          req.Remark,
+		TransactionTime: formattedTime,
+		CreateTime:      now,
+		UpdateTime:      now,
+	}
+	transfer := &models.Transfer{Out: entry, In: entry}
+	transfer.Out.StoreID = req.FromStoreID
+	transfer.In.StoreID = req.ToStoreID
+
+	if err := database.CreateTransfer(transfer); err != nil {
+		if errors.Is(err, models.ErrPeriodClosed) {
+			SendResponse(w, http.StatusConflict, 409, err.Error(), nil)
+			return
+		}
+		log.Printf("店铺%d转账到店铺%d失败: %v", req.FromStoreID, req.ToStoreID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "转账失败", nil)
+		return
+	}
+
+	// 转入记录属于另一个店铺，单独记录审计日志；id为转出记录的ID，由Audited记录
+	middleware.RecordAudit(r, models.AuditActionCreate, middleware.AuditAccount, transfer.In.ID, transfer.In.StoreID,
+		nil, middleware.LoadAuditSnapshot(middleware.AuditAccount, transfer.In.ID))
+
+	SendResponse(w, http.StatusOK, 200, "转账成功", map[string]interface{}{
+		"id":  transfer.Out.ID,
+		"out": transfer.Out,
+		"in":  transfer.In,
+	})
+}
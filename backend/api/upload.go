@@ -0,0 +1,31 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+// uploadFormOverhead multipart表单中除文件内容外的边界、字段等内容预留的大小
+const uploadFormOverhead = 1 << 20
+
+// LimitUploadSize 限制上传请求的大小，maxSize为文件的最大字节数。
+// 需放在审计等会读取请求体的中间件之前，避免超大请求被整体读入内存
+func LimitUploadSize(next http.HandlerFunc, maxSize int64) http.HandlerFunc {
+	limit := maxSize + uploadFormOverhead
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			SendResponse(w, http.StatusRequestEntityTooLarge, 413, "上传文件大小超过限制", nil)
+			return
+		}
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next(w, r)
+	}
+}
+
+// isRequestTooLarge 判断读取请求体的错误是否因为超过了LimitUploadSize的限制
+func isRequestTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -2,30 +2,37 @@ package api
 
 import (
 	"bytes"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/mail"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"account/backend/database"
+	"account/backend/middleware"
 	"account/backend/models"
 	"account/backend/services"
+	"account/backend/utils"
 )
 
 // UserHandler 处理用户相关请求
 type UserHandler struct {
 	userService services.UserService
+	loginGuard  services.LoginGuard
 }
 
 // 登录请求结构
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Device   string `json:"device"` // 设备名称，用于登录设备列表展示
 }
 
 // 响应结构
@@ -49,7 +56,7 @@ func SendResponse(w http.ResponseWriter, httpStatus, code int, message string, d
 	json.NewEncoder(w).Encode(resp)
 }
 
-// Login 处理用户登录请求
+// Login 处理用户登录请求，验证密码后签发访问令牌
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// 添加详细的请求日志
 	log.Printf("收到登录请求: Method=%s, RemoteAddr=%s, ContentType=%s, ContentLength=%d",
@@ -58,7 +65,7 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// 设置CORS头
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User-ID")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 	// 处理OPTIONS预检请求
 	if r.Method == "OPTIONS" {
@@ -71,79 +78,80 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 打印请求体内容以便调试
-	body, _ := io.ReadAll(r.Body)
-	r.Body = io.NopCloser(bytes.NewBuffer(body)) // 重新设置请求体，因为读取后需要重置
-	log.Printf("登录请求体: %s", string(body))
-
 	// 解析请求
-	var loginRequest struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-
+	var loginRequest LoginRequest
 	err := json.NewDecoder(r.Body).Decode(&loginRequest)
 	if err != nil {
 		SendResponse(w, http.StatusBadRequest, 400, "请求参数错误", nil)
 		return
 	}
 
-	// 获取用户信息
-	var user models.User
-	var hashedPassword string
-	var avatar sql.NullString // 使用 sql.NullString 来处理可能为 NULL 的 avatar 列
-
-	// 修改查询，使用 sql.NullString 接收 avatar
-	err = database.DB.QueryRow(`
-		SELECT id, username, nickname, password, role, avatar
-		FROM users 
-		WHERE username = ?
-	`, loginRequest.Username).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Nickname,
-		&hashedPassword,
-		&user.Role,
-		&avatar, // 使用 NullString 接收
-	)
+	user := authenticate(w, r, &h.userService, &h.loginGuard, loginRequest.Username, loginRequest.Password)
+	if user == nil {
+		return
+	}
+
+	respondLoginSuccess(w, r, user, loginRequest.Device)
+}
 
+// authenticate 校验用户名和密码，失败次数过多时拒绝登录；校验失败时已写入响应并返回nil
+func authenticate(w http.ResponseWriter, r *http.Request, userService *services.UserService, guard *services.LoginGuard, username, password string) *models.User {
+	// 校验密码前先计入一次失败，用户名或IP失败次数过多时拒绝登录，不再校验密码
+	ip := middleware.ClientIP(r)
+	lockedUntil, err := guard.ReserveAttempt(username, ip)
 	if err != nil {
-		log.Printf("获取用户信息失败: %v", err)
-		SendResponse(w, http.StatusUnauthorized, 401, err.Error(), nil)
-		return
+		log.Printf("检查登录锁定状态失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "登录失败，请稍后再试", nil)
+		return nil
+	}
+	if !lockedUntil.IsZero() {
+		log.Printf("用户 %s (IP %s) 处于登录锁定状态，%s解锁", username, ip, lockedUntil.Format("2006-01-02 15:04:05"))
+		respondLoginLocked(w, lockedUntil, "登录失败次数过多，请稍后再试")
+		return nil
 	}
 
-	// 只有当 avatar.Valid 为 true 时才设置 user.Avatar
-	if avatar.Valid {
-		user.Avatar = avatar.String
-	} else {
-		user.Avatar = "" // 如果为 NULL，则设置为空字符串
+	// 验证用户名和密码（同时更新最后登录时间）
+	user, err := userService.Login(username, password)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		log.Printf("用户 %s 登录失败: %v", username, err)
+		SendResponse(w, http.StatusUnauthorized, 401, services.ErrInvalidCredentials.Error(), nil)
+		return nil
+	}
+	if err != nil {
+		log.Printf("用户 %s 登录失败: %v", username, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "登录失败，请稍后再试", nil)
+		return nil
 	}
 
-	// 验证密码
-	// 其余代码保持不变...
+	if err := guard.RecordSuccess(username, ip); err != nil {
+		log.Printf("清除用户 %s 的登录失败记录失败: %v", username, err)
+	}
+	return user
+}
 
-	// 验证密码成功后，尝试更新最后登录时间，但不影响登录结果
-	_, err = database.DB.Exec("UPDATE users SET last_login = ? WHERE id = ?", time.Now(), user.ID)
+// respondLoginLocked 返回429和距离解锁的秒数
+func respondLoginLocked(w http.ResponseWriter, lockedUntil time.Time, message string) {
+	retryAfter := int(math.Ceil(time.Until(lockedUntil).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	SendResponse(w, http.StatusTooManyRequests, 429, message, map[string]interface{}{
+		"retry_after": retryAfter,
+	})
+}
+
+// respondLoginSuccess 创建登录会话并返回令牌和用户信息
+func respondLoginSuccess(w http.ResponseWriter, r *http.Request, user *models.User, device string) {
+	// 创建登录会话并签发令牌，后续请求通过Authorization头携带访问令牌
+	result, err := issueSession(r, user, device)
 	if err != nil {
-		log.Printf("更新登录时间失败: %v", err)
-		// 检查是否是因为缺少列导致的失败
-		if strings.Contains(err.Error(), "no such column: last_login") {
-			// 尝试添加列
-			_, alterErr := database.DB.Exec("ALTER TABLE users ADD COLUMN last_login TIMESTAMP")
-			if alterErr != nil {
-				log.Printf("添加last_login列失败: %v", alterErr)
-			} else {
-				// 重新尝试更新
-				_, _ = database.DB.Exec("UPDATE users SET last_login = ? WHERE id = ?", time.Now(), user.ID)
-				log.Println("已添加last_login列并更新登录时间")
-			}
-		}
-		// 继续处理，不要因为这个错误中断登录流程
+		log.Printf("创建登录会话失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "生成登录令牌失败", nil)
+		return
 	}
 
-	// 登录成功，返回用户信息
-	SendResponse(w, http.StatusOK, 200, "登录成功", user)
+	// 登录成功，返回令牌和用户信息；需要修改密码时前端应跳转到修改密码页面
+	result["user"] = user
+	result["must_change_password"] = user.MustChangePassword
+	SendResponse(w, http.StatusOK, 200, "登录成功", result)
 }
 
 // GetAllUsers 获取所有用户
@@ -153,20 +161,6 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 获取所有用户
 	users, err := database.GetAllUsers()
 	if err != nil {
@@ -189,20 +183,6 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 解析请求数据
 	var user models.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
@@ -215,8 +195,8 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		SendResponse(w, http.StatusBadRequest, 400, "用户名不能为空", nil)
 		return
 	}
-	if user.Password == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "密码不能为空", nil)
+	if err := utils.ValidatePassword(user.Password); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
 		return
 	}
 	if user.Role < 1 || user.Role > 2 {
@@ -245,6 +225,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	// 隐藏密码
 	user.ID = id
 	user.Password = ""
+	user.MustChangePassword = true
 
 	SendResponse(w, http.StatusOK, 200, "创建用户成功", user)
 }
@@ -256,20 +237,6 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 解析请求数据
 	var user models.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
@@ -288,7 +255,7 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 更新用户
-	err = database.UpdateUser(user)
+	err := database.UpdateUser(user)
 	if err != nil {
 		SendResponse(w, http.StatusInternalServerError, 500, "更新用户失败: "+err.Error(), nil)
 		return
@@ -305,20 +272,6 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 获取要删除的用户ID
 	targetUserID := r.URL.Query().Get("id")
 	if targetUserID == "" {
@@ -333,7 +286,7 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 不能删除自己
-	if targetUserIDInt == userIDInt {
+	if targetUserIDInt == middleware.CurrentUserID(r) {
 		SendResponse(w, http.StatusBadRequest, 400, "不能删除当前登录的用户", nil)
 		return
 	}
@@ -355,20 +308,6 @@ func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 解析请求数据
 	var req struct {
 		UserID      int64  `json:"user_id"`
@@ -384,21 +323,229 @@ func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 		SendResponse(w, http.StatusBadRequest, 400, "用户ID无效", nil)
 		return
 	}
-	if req.NewPassword == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "新密码不能为空", nil)
+	if err := utils.ValidatePassword(req.NewPassword); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
 		return
 	}
 
-	// 重置密码
-	err = database.ResetUserPassword(req.UserID, req.NewPassword)
+	// 重置密码，用户下次登录后必须修改密码
+	err := database.ResetUserPassword(req.UserID, req.NewPassword)
 	if err != nil {
 		SendResponse(w, http.StatusInternalServerError, 500, "重置密码失败: "+err.Error(), nil)
 		return
 	}
 
+	// 重置密码后强制该用户所有设备下线
+	if err := database.RevokeUserSessions(req.UserID); err != nil {
+		log.Printf("重置密码后注销用户%d的会话失败: %v", req.UserID, err)
+	}
+
 	SendResponse(w, http.StatusOK, 200, "重置密码成功", nil)
 }
 
+// ChangePassword 当前用户修改自己的密码，修改后其他设备需要重新登录
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "请求数据格式错误", nil)
+		return
+	}
+
+	userID := middleware.CurrentUserID(r)
+	hashedPassword, err := database.GetUserPasswordHash(userID)
+	if err != nil {
+		log.Printf("获取用户%d的密码失败: %v", userID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改密码失败", nil)
+		return
+	}
+
+	// 校验旧密码
+	if err := utils.CompareHashAndPassword([]byte(hashedPassword), []byte(req.OldPassword)); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "原密码不正确", nil)
+		return
+	}
+	if req.NewPassword == req.OldPassword {
+		SendResponse(w, http.StatusBadRequest, 400, "新密码不能与原密码相同", nil)
+		return
+	}
+	if err := utils.ValidatePassword(req.NewPassword); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
+		return
+	}
+
+	if err := database.ChangeUserPassword(userID, req.NewPassword); err != nil {
+		log.Printf("修改用户%d的密码失败: %v", userID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "修改密码失败", nil)
+		return
+	}
+
+	// 保留当前设备的登录状态，其他设备下线
+	if err := database.RevokeOtherSessions(userID, middleware.CurrentSessionID(r)); err != nil {
+		log.Printf("修改密码后注销用户%d的其他会话失败: %v", userID, err)
+	}
+
+	SendResponse(w, http.StatusOK, 200, "修改密码成功", nil)
+}
+
+// GetProfile 获取当前用户的个人资料
+func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(middleware.CurrentUserID(r))
+	if err != nil {
+		SendResponse(w, http.StatusNotFound, 404, err.Error(), nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取个人资料成功", user)
+}
+
+// UpdateProfile 当前用户修改自己的昵称、手机、邮箱和头像，只修改请求中提交了的字段，
+// 提交空字符串表示清空。用户名和角色只能由管理员修改
+func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "仅支持PUT请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Nickname *string `json:"nickname"`
+		Phone    *string `json:"phone"`
+		Email    *string `json:"email"`
+		Avatar   *string `json:"avatar"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "请求数据格式错误", nil)
+		return
+	}
+	for _, field := range []*string{req.Nickname, req.Phone, req.Email, req.Avatar} {
+		if field != nil {
+			*field = strings.TrimSpace(*field)
+		}
+	}
+
+	// 验证提交了的字段
+	if req.Nickname != nil && len([]rune(*req.Nickname)) > 32 {
+		SendResponse(w, http.StatusBadRequest, 400, "昵称不能超过32个字符", nil)
+		return
+	}
+	if req.Phone != nil && *req.Phone != "" && !phonePattern.MatchString(*req.Phone) {
+		SendResponse(w, http.StatusBadRequest, 400, "手机号格式错误", nil)
+		return
+	}
+	if req.Email != nil && *req.Email != "" {
+		if addr, err := mail.ParseAddress(*req.Email); err != nil || addr.Address != *req.Email {
+			SendResponse(w, http.StatusBadRequest, 400, "邮箱格式错误", nil)
+			return
+		}
+	}
+	if req.Avatar != nil && len(*req.Avatar) > 512 {
+		SendResponse(w, http.StatusBadRequest, 400, "头像地址过长", nil)
+		return
+	}
+
+	userID := middleware.CurrentUserID(r)
+	if err := database.UpdateUserProfile(userID, req.Nickname, req.Phone, req.Email, req.Avatar); err != nil {
+		log.Printf("更新用户%d的个人资料失败: %v", userID, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "更新个人资料失败", nil)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		SendResponse(w, http.StatusNotFound, 404, err.Error(), nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "更新个人资料成功", user)
+}
+
+// phonePattern 手机号或座机号，允许国际区号和分隔符
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9\- ]{4,19}$`)
+
+// GetPasswordPolicy 获取密码强度要求，供前端提示
+func (h *UserHandler) GetPasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取密码要求成功", utils.GetPasswordPolicy())
+}
+
+// GetLoginLocks 获取登录失败记录和当前被锁定的用户名、IP
+func (h *UserHandler) GetLoginLocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	locks, err := database.GetLoginLocks()
+	if err != nil {
+		log.Printf("获取登录锁定列表失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取登录锁定列表失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取登录锁定列表成功", locks)
+}
+
+// ClearLoginLock 解除用户名或IP的登录锁定并清空失败次数
+func (h *UserHandler) ClearLoginLock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Scope string `json:"scope"`
+		Key   string `json:"key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "请求数据格式错误", nil)
+		return
+	}
+
+	switch req.Scope {
+	case models.LoginLockScopeUsername:
+		req.Key = services.NormalizeLoginUsername(req.Key)
+	case models.LoginLockScopeIP, models.LoginLockScopeWeChatIP:
+		req.Key = strings.TrimSpace(req.Key)
+	default:
+		SendResponse(w, http.StatusBadRequest, 400, "无效的锁定类型: "+req.Scope, nil)
+		return
+	}
+	if req.Key == "" {
+		SendResponse(w, http.StatusBadRequest, 400, "用户名或IP不能为空", nil)
+		return
+	}
+
+	cleared, err := database.ClearLoginLock(req.Scope, req.Key)
+	if err != nil {
+		log.Printf("解除登录锁定失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "解除登录锁定失败", nil)
+		return
+	}
+	if !cleared {
+		SendResponse(w, http.StatusNotFound, 404, "没有该用户名或IP的登录失败记录", nil)
+		return
+	}
+
+	log.Printf("管理员%d解除了%s %s的登录锁定", middleware.CurrentUserID(r), req.Scope, req.Key)
+	SendResponse(w, http.StatusOK, 200, "解除登录锁定成功", nil)
+}
+
 // GetUserStorePermissions 获取用户的店铺权限
 func (h *UserHandler) GetUserStorePermissions(w http.ResponseWriter, r *http.Request) {
 	log.Printf("收到获取用户权限请求: %s %s", r.Method, r.URL.String())
@@ -418,28 +565,6 @@ func (h *UserHandler) GetUserStorePermissions(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// 验证请求用户是否有权限管理其他用户
-	requestUserID := r.Header.Get("X-User-ID")
-	if requestUserID == "" {
-		log.Printf("未授权的请求，缺少X-User-ID头")
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	requestUserIDInt, _ := strconv.ParseInt(requestUserID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(requestUserIDInt)
-	if err != nil {
-		log.Printf("检查用户权限时出错: %v", err)
-		SendResponse(w, http.StatusInternalServerError, 500, "检查用户权限失败", nil)
-		return
-	}
-
-	if !isAdmin && requestUserIDInt != userID {
-		log.Printf("权限拒绝: 用户%d尝试管理用户%d的权限", requestUserIDInt, userID)
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 获取用户的店铺权限
 	permissions, err := database.GetUserStorePermissions(userID)
 	if err != nil {
@@ -463,10 +588,12 @@ func (h *UserHandler) UpdateUserStorePermissions(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// 解析请求体
+	// 解析请求体，permissions可为每个店铺指定店内角色，store_ids为旧版只分配店铺的格式。
+	// 未指定角色时保留原有角色，新分配的店铺为查看者
 	var req struct {
-		UserId   int64   `json:"user_id"`
-		StoreIds []int64 `json:"store_ids"`
+		UserId      int64                    `json:"user_id"`
+		StoreIds    []int64                  `json:"store_ids"`
+		Permissions []models.StorePermission `json:"permissions"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -474,8 +601,23 @@ func (h *UserHandler) UpdateUserStorePermissions(w http.ResponseWriter, r *http.
 		return
 	}
 
+	permissions := req.Permissions
+	if len(permissions) == 0 {
+		for _, storeID := range req.StoreIds {
+			permissions = append(permissions, models.StorePermission{StoreID: storeID})
+		}
+	}
+
+	// 验证店内角色
+	for _, perm := range permissions {
+		if perm.Role != "" && !perm.Role.IsValid() {
+			SendResponse(w, http.StatusBadRequest, 400, fmt.Sprintf("无效的店铺角色: %s", perm.Role), nil)
+			return
+		}
+	}
+
 	// 更新权限
-	err := database.UpdateUserStorePermissions(req.UserId, req.StoreIds)
+	err := database.UpdateUserStorePermissions(req.UserId, permissions)
 	if err != nil {
 		SendResponse(w, http.StatusInternalServerError, 500, "更新权限失败: "+err.Error(), nil)
 		return
@@ -485,12 +627,22 @@ func (h *UserHandler) UpdateUserStorePermissions(w http.ResponseWriter, r *http.
 	SendResponse(w, http.StatusOK, 200, "权限更新成功", nil)
 }
 
+// GetStoreRoles 获取可分配的店铺角色及其能力
+func (h *UserHandler) GetStoreRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取店铺角色成功", models.StoreRoles())
+}
+
 // CreateUserAlt 使用自定义请求结构创建新用户
 func (h *UserHandler) CreateUserAlt(w http.ResponseWriter, r *http.Request) {
 	// 启用CORS
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User-ID")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 	// 处理OPTIONS预检请求
 	if r.Method == "OPTIONS" {
@@ -503,20 +655,6 @@ func (h *UserHandler) CreateUserAlt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证权限
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		SendResponse(w, http.StatusUnauthorized, 401, "未授权访问", nil)
-		return
-	}
-
-	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
-	isAdmin, err := database.IsUserAdmin(userIDInt)
-	if err != nil || !isAdmin {
-		SendResponse(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
-		return
-	}
-
 	// 打印请求体内容以便调试
 	body, _ := io.ReadAll(r.Body)
 	r.Body = io.NopCloser(bytes.NewBuffer(body)) // 重新设置请求体，因为读取后需要重置
@@ -536,7 +674,7 @@ func (h *UserHandler) CreateUserAlt(w http.ResponseWriter, r *http.Request) {
 		Role          models.UserRole `json:"role"`
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&createUserRequest)
+	err := json.NewDecoder(r.Body).Decode(&createUserRequest)
 	if err != nil {
 		SendResponse(w, http.StatusBadRequest, 400, "请求数据格式错误: "+err.Error(), nil)
 		return
@@ -568,8 +706,8 @@ func (h *UserHandler) CreateUserAlt(w http.ResponseWriter, r *http.Request) {
 		SendResponse(w, http.StatusBadRequest, 400, "用户名不能为空", nil)
 		return
 	}
-	if password == "" {
-		SendResponse(w, http.StatusBadRequest, 400, "密码不能为空", nil)
+	if err := utils.ValidatePassword(password); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, err.Error(), nil)
 		return
 	}
 	if createUserRequest.Role < 1 || createUserRequest.Role > 2 {
@@ -606,6 +744,7 @@ func (h *UserHandler) CreateUserAlt(w http.ResponseWriter, r *http.Request) {
 	// 隐藏密码
 	user.ID = id
 	user.Password = ""
+	user.MustChangePassword = true
 
 	SendResponse(w, http.StatusOK, 200, "创建用户成功", user)
 }
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"account/backend/middleware"
+	"account/backend/models"
+)
+
+func TestUpdateProfile(t *testing.T) {
+	useTestDB(t)
+	id := createTestUser(t, "clerk", "clerk123", models.RoleStaff)
+	token := userToken(t, id)
+	h := &UserHandler{}
+	update := middleware.Protect(h.UpdateProfile, middleware.Authenticated)
+	const ip = "192.0.2.10"
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		want       map[string]string // 修改后的个人资料
+	}{
+		{
+			name:       "填写全部资料",
+			body:       `{"nickname":"小王","phone":"13800138000","email":"clerk@example.com","avatar":"https://example.com/a.png"}`,
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"nickname": "小王", "phone": "13800138000", "email": "clerk@example.com", "avatar": "https://example.com/a.png"},
+		},
+		{
+			name:       "只修改昵称时其他资料不变",
+			body:       `{"nickname":" 王经理 "}`,
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"nickname": "王经理", "phone": "13800138000", "email": "clerk@example.com", "avatar": "https://example.com/a.png"},
+		},
+		{
+			name:       "提交空字符串清空手机号",
+			body:       `{"phone":""}`,
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"nickname": "王经理", "phone": "", "email": "clerk@example.com", "avatar": "https://example.com/a.png"},
+		},
+		{
+			name:       "null与未提交相同",
+			body:       `{"email":null}`,
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"nickname": "王经理", "phone": "", "email": "clerk@example.com", "avatar": "https://example.com/a.png"},
+		},
+		{
+			name:       "校验失败时不修改任何资料",
+			body:       `{"nickname":"新昵称","email":"not-an-email"}`,
+			wantStatus: http.StatusBadRequest,
+			want:       map[string]string{"nickname": "王经理", "phone": "", "email": "clerk@example.com", "avatar": "https://example.com/a.png"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serve(t, update, http.MethodPut, "/api/users/profile", tt.body, token, ip)
+			if resp.Status != tt.wantStatus {
+				t.Fatalf("状态码 = %d, want %d (%s)", resp.Status, tt.wantStatus, resp.Message)
+			}
+
+			user, err := h.userService.GetUserByID(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{"nickname": user.Nickname, "phone": user.Phone, "email": user.Email, "avatar": user.Avatar}
+			for field, want := range tt.want {
+				if got[field] != want {
+					t.Errorf("%s = %q, want %q", field, got[field], want)
+				}
+			}
+		})
+	}
+}
+
+func TestChangePasswordTooLong(t *testing.T) {
+	useTestDB(t)
+	id := createTestUser(t, "clerk", "clerk123", models.RoleStaff)
+	token := userToken(t, id)
+	h := &UserHandler{}
+	change := middleware.Protect(h.ChangePassword, middleware.Authenticated)
+
+	// bcrypt只使用前72个字节，超出的部分不能被静默忽略
+	long := strings.Repeat("密", 24) + "a1"
+	resp := serve(t, change, http.MethodPost, "/api/users/change-password", `{"old_password":"clerk123","new_password":"`+long+`"}`, token, "192.0.2.10")
+	if resp.Status != http.StatusBadRequest {
+		t.Errorf("超过72字节的新密码状态码 = %d, want 400", resp.Status)
+	}
+}
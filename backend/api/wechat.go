@@ -0,0 +1,259 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+	"account/backend/services"
+)
+
+// WeChatHandler 处理微信小程序登录和微信账号绑定审核
+type WeChatHandler struct {
+	Provider    services.IdentityProvider
+	userService services.UserService
+	loginGuard  services.LoginGuard
+}
+
+// WeChatLoginRequest 微信登录请求，未绑定时可以同时提交用户名密码直接绑定，
+// 或只提交用户名等待管理员审核
+type WeChatLoginRequest struct {
+	Code     string `json:"code"` // wx.login获取的登录凭证
+	Device   string `json:"device"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Remark   string `json:"remark"` // 绑定申请备注，如姓名、所在店铺
+}
+
+// Login 使用微信登录凭证登录，openid已绑定用户时直接签发令牌。
+// 同一IP登录凭证无效或提交绑定申请的次数过多时按登录锁定策略暂时拒绝微信登录
+func (h *WeChatHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req WeChatLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		SendResponse(w, http.StatusBadRequest, 400, "缺少微信登录凭证", nil)
+		return
+	}
+
+	ip := middleware.ClientIP(r)
+	lockedUntil, err := h.loginGuard.WeChatLockedUntil(ip)
+	if err != nil {
+		log.Printf("检查微信登录锁定状态失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "登录失败，请稍后再试", nil)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		log.Printf("IP %s 的微信登录处于锁定状态，%s解锁", ip, lockedUntil.Format("2006-01-02 15:04:05"))
+		respondLoginLocked(w, lockedUntil, "微信登录请求过于频繁，请稍后再试")
+		return
+	}
+
+	identity, err := h.Provider.Exchange(r.Context(), req.Code)
+	if errors.Is(err, services.ErrInvalidLoginCode) {
+		if _, err := h.loginGuard.ReserveWeChatAttempt(ip); err != nil {
+			log.Printf("记录微信登录次数失败: %v", err)
+		}
+		SendResponse(w, http.StatusUnauthorized, 401, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		log.Printf("微信登录换取openid失败: %v", err)
+		SendResponse(w, http.StatusBadGateway, 502, "微信登录服务暂不可用，请使用账号密码登录", nil)
+		return
+	}
+
+	binding, err := database.GetIdentity(identity.Provider, identity.Subject)
+	if err != nil {
+		log.Printf("查询微信绑定失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "登录失败，请稍后再试", nil)
+		return
+	}
+
+	// 已绑定：一键登录
+	if binding != nil && binding.Status == models.IdentityStatusActive && binding.UserID != nil {
+		user, err := h.userService.GetUserByID(*binding.UserID)
+		if err != nil {
+			SendResponse(w, http.StatusUnauthorized, 401, "绑定的用户不存在，请重新绑定", nil)
+			return
+		}
+		log.Printf("用户 %s 通过微信登录", user.Username)
+		respondLoginSuccess(w, r, user, req.Device)
+		return
+	}
+
+	// 未绑定但提交了用户名密码：校验通过后直接绑定
+	if req.Username != "" && req.Password != "" {
+		user := authenticate(w, r, &h.userService, &h.loginGuard, req.Username, req.Password)
+		if user == nil {
+			return
+		}
+		if err := database.BindIdentity(models.UserIdentity{
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			UnionID:  identity.UnionID,
+		}, user.ID); err != nil {
+			log.Printf("绑定用户%d的微信失败: %v", user.ID, err)
+			SendResponse(w, http.StatusInternalServerError, 500, "绑定微信失败", nil)
+			return
+		}
+		log.Printf("用户 %s 通过账号密码绑定了微信", user.Username)
+		respondLoginSuccess(w, r, user, req.Device)
+		return
+	}
+
+	if binding != nil && binding.Status == models.IdentityStatusRejected {
+		SendResponse(w, http.StatusForbidden, 403, "微信绑定申请已被拒绝，请联系管理员或使用账号密码绑定", map[string]interface{}{
+			"status": binding.Status,
+		})
+		return
+	}
+
+	// 未绑定：登记绑定申请，等待管理员审核。计数和锁定检查在同一事务中，并发的请求也不会超过次数上限
+	lockedUntil, err = h.loginGuard.ReserveWeChatAttempt(ip)
+	if err != nil {
+		log.Printf("记录微信登录次数失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "提交绑定申请失败", nil)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		respondLoginLocked(w, lockedUntil, "微信登录请求过于频繁，请稍后再试")
+		return
+	}
+	if err := database.CreatePendingIdentity(models.UserIdentity{
+		Provider:          identity.Provider,
+		Subject:           identity.Subject,
+		UnionID:           identity.UnionID,
+		RequestedUsername: req.Username,
+		Remark:            req.Remark,
+	}); err != nil {
+		log.Printf("登记微信绑定申请失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "提交绑定申请失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusAccepted, 202, "微信尚未绑定账号，已提交绑定申请，请等待管理员审核", map[string]interface{}{
+		"status": models.IdentityStatusPending,
+	})
+}
+
+// GetBindings 获取微信绑定记录，可按状态筛选
+func (h *WeChatHandler) GetBindings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持GET请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bindings, err := database.GetIdentities(models.IdentityProviderWeChat, r.URL.Query().Get("status"))
+	if err != nil {
+		log.Printf("获取微信绑定列表失败: %v", err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取微信绑定列表失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "获取微信绑定列表成功", bindings)
+}
+
+// ApproveBinding 通过绑定申请，将微信绑定到指定用户
+func (h *WeChatHandler) ApproveBinding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID     int64 `json:"id"`
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendResponse(w, http.StatusBadRequest, 400, "请求数据格式错误", nil)
+		return
+	}
+	if req.ID <= 0 || req.UserID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "绑定申请ID和用户ID不能为空", nil)
+		return
+	}
+
+	if _, err := h.userService.GetUserByID(req.UserID); err != nil {
+		SendResponse(w, http.StatusNotFound, 404, err.Error(), nil)
+		return
+	}
+
+	h.reviewBinding(w, r, req.ID, models.IdentityStatusActive, &req.UserID)
+}
+
+// RejectBinding 拒绝绑定申请
+func (h *WeChatHandler) RejectBinding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持POST请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "绑定申请ID不能为空", nil)
+		return
+	}
+
+	h.reviewBinding(w, r, req.ID, models.IdentityStatusRejected, nil)
+}
+
+// reviewBinding 保存审核结果并返回更新后的绑定记录
+func (h *WeChatHandler) reviewBinding(w http.ResponseWriter, r *http.Request, id int64, status string, userID *int64) {
+	reviewed, err := database.ReviewIdentity(id, status, userID, middleware.CurrentUserID(r))
+	if err != nil {
+		log.Printf("审核微信绑定申请%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "审核绑定申请失败", nil)
+		return
+	}
+	if !reviewed {
+		SendResponse(w, http.StatusNotFound, 404, "绑定申请不存在或已审核", nil)
+		return
+	}
+
+	binding, err := database.GetIdentityByID(id)
+	if err != nil {
+		log.Printf("获取微信绑定%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "获取绑定记录失败", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "审核绑定申请成功", binding)
+}
+
+// DeleteBinding 解除微信绑定或删除绑定申请
+func (h *WeChatHandler) DeleteBinding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "仅支持DELETE请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		SendResponse(w, http.StatusBadRequest, 400, "绑定记录ID无效", nil)
+		return
+	}
+
+	deleted, err := database.DeleteIdentity(id)
+	if err != nil {
+		log.Printf("解除微信绑定%d失败: %v", id, err)
+		SendResponse(w, http.StatusInternalServerError, 500, "解除绑定失败", nil)
+		return
+	}
+	if !deleted {
+		SendResponse(w, http.StatusNotFound, 404, "绑定记录不存在", nil)
+		return
+	}
+
+	SendResponse(w, http.StatusOK, 200, "解除绑定成功", nil)
+}
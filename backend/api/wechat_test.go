@@ -0,0 +1,175 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"account/backend/database"
+	"account/backend/middleware"
+	"account/backend/models"
+	"account/backend/services"
+)
+
+func TestWeChatLogin(t *testing.T) {
+	useTestDB(t)
+	createTestUser(t, "clerk", "clerk123", models.RoleStaff)
+	h := &WeChatHandler{Provider: &services.FakeWeChatProvider{}}
+	const ip = "192.0.2.1"
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantUser   string // 登录成功时的用户名
+	}{
+		{name: "缺少登录凭证", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "登录凭证无效", body: `{"code":"invalid"}`, wantStatus: http.StatusUnauthorized},
+		{name: "首次登录登记绑定申请", body: `{"code":"alice","username":"clerk","remark":"分店1"}`, wantStatus: http.StatusAccepted},
+		{name: "重复提交绑定申请", body: `{"code":"alice"}`, wantStatus: http.StatusAccepted},
+		{name: "密码错误时不绑定", body: `{"code":"alice","username":"clerk","password":"wrong"}`, wantStatus: http.StatusUnauthorized},
+		{name: "提交用户名密码直接绑定", body: `{"code":"alice","username":"clerk","password":"clerk123"}`, wantStatus: http.StatusOK, wantUser: "clerk"},
+		{name: "已绑定一键登录", body: `{"code":"alice"}`, wantStatus: http.StatusOK, wantUser: "clerk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serve(t, h.Login, http.MethodPost, "/api/login/wechat", tt.body, "", ip)
+			if resp.Status != tt.wantStatus {
+				t.Fatalf("状态码 = %d, want %d (%s)", resp.Status, tt.wantStatus, resp.Message)
+			}
+			if tt.wantUser == "" {
+				return
+			}
+			if token, _ := resp.Data["token"].(string); token == "" {
+				t.Error("登录成功但没有返回令牌")
+			}
+			user, _ := resp.Data["user"].(map[string]interface{})
+			if user["username"] != tt.wantUser {
+				t.Errorf("登录用户 = %v, want %s", user["username"], tt.wantUser)
+			}
+		})
+	}
+
+	// 同一openid只保留一条绑定记录
+	bindings, err := database.GetIdentities(models.IdentityProviderWeChat, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings) != 1 || bindings[0].Status != models.IdentityStatusActive || bindings[0].Username != "clerk" {
+		t.Errorf("绑定记录 = %+v, want 一条绑定到clerk的记录", bindings)
+	}
+}
+
+func TestWeChatBindingReview(t *testing.T) {
+	useTestDB(t)
+	clerkID := createTestUser(t, "clerk", "clerk123", models.RoleStaff)
+	token := adminToken(t)
+	h := &WeChatHandler{Provider: &services.FakeWeChatProvider{}}
+	approve := middleware.Protect(h.ApproveBinding, middleware.AdminOnly)
+	reject := middleware.Protect(h.RejectBinding, middleware.AdminOnly)
+	const ip = "192.0.2.2"
+
+	pendingID := func(code string) int64 {
+		t.Helper()
+		resp := serve(t, h.Login, http.MethodPost, "/api/login/wechat", `{"code":"`+code+`"}`, "", ip)
+		if resp.Status != http.StatusAccepted || resp.Data["status"] != models.IdentityStatusPending {
+			t.Fatalf("首次登录 = %d %v, want 202 pending", resp.Status, resp.Data)
+		}
+		binding, err := database.GetIdentity(models.IdentityProviderWeChat, "fake-"+code)
+		if err != nil || binding == nil {
+			t.Fatalf("未登记绑定申请: %v", err)
+		}
+		return binding.ID
+	}
+
+	t.Run("通过申请后可以一键登录", func(t *testing.T) {
+		id := pendingID("bob")
+		body := `{"id":` + jsonInt(id) + `,"user_id":` + jsonInt(clerkID) + `}`
+
+		// 非管理员不能审核
+		if resp := serve(t, approve, http.MethodPost, "/api/wechat/bindings/approve", body, "", ip); resp.Status != http.StatusUnauthorized {
+			t.Errorf("未登录审核状态码 = %d, want 401", resp.Status)
+		}
+
+		resp := serve(t, approve, http.MethodPost, "/api/wechat/bindings/approve", body, token, ip)
+		if resp.Status != http.StatusOK || resp.Data["status"] != models.IdentityStatusActive {
+			t.Fatalf("通过申请 = %d %v, want 200 active", resp.Status, resp.Data)
+		}
+
+		// 已审核的申请不能再次审核
+		if resp := serve(t, approve, http.MethodPost, "/api/wechat/bindings/approve", body, token, ip); resp.Status != http.StatusNotFound {
+			t.Errorf("重复审核状态码 = %d, want 404", resp.Status)
+		}
+
+		resp = serve(t, h.Login, http.MethodPost, "/api/login/wechat", `{"code":"bob"}`, "", ip)
+		user, _ := resp.Data["user"].(map[string]interface{})
+		if resp.Status != http.StatusOK || user["username"] != "clerk" {
+			t.Errorf("通过后登录 = %d %v, want 200 clerk", resp.Status, resp.Data)
+		}
+	})
+
+	t.Run("通过申请时用户必须存在", func(t *testing.T) {
+		id := pendingID("dave")
+		body := `{"id":` + jsonInt(id) + `,"user_id":9999}`
+		if resp := serve(t, approve, http.MethodPost, "/api/wechat/bindings/approve", body, token, ip); resp.Status != http.StatusNotFound {
+			t.Errorf("绑定到不存在的用户状态码 = %d, want 404", resp.Status)
+		}
+	})
+
+	t.Run("拒绝申请后不能登录也不能重新申请", func(t *testing.T) {
+		id := pendingID("carol")
+		resp := serve(t, reject, http.MethodPost, "/api/wechat/bindings/reject", `{"id":`+jsonInt(id)+`}`, token, ip)
+		if resp.Status != http.StatusOK || resp.Data["status"] != models.IdentityStatusRejected {
+			t.Fatalf("拒绝申请 = %d %v, want 200 rejected", resp.Status, resp.Data)
+		}
+
+		resp = serve(t, h.Login, http.MethodPost, "/api/login/wechat", `{"code":"carol","username":"clerk"}`, "", ip)
+		if resp.Status != http.StatusForbidden {
+			t.Errorf("拒绝后登录状态码 = %d, want 403", resp.Status)
+		}
+		binding, err := database.GetIdentity(models.IdentityProviderWeChat, "fake-carol")
+		if err != nil || binding.Status != models.IdentityStatusRejected {
+			t.Errorf("拒绝后绑定记录 = %+v, %v, want rejected", binding, err)
+		}
+	})
+}
+
+func TestWeChatLoginRateLimit(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "3")
+	h := &WeChatHandler{Provider: &services.FakeWeChatProvider{}}
+	const ip = "192.0.2.3"
+
+	// 无效凭证和绑定申请都计数，达到次数上限后锁定该IP
+	for i, code := range []string{"invalid", "u1", "u2"} {
+		resp := serve(t, h.Login, http.MethodPost, "/api/login/wechat", `{"code":"`+code+`"}`, "", ip)
+		if resp.Status == http.StatusTooManyRequests {
+			t.Fatalf("第%d次请求就被锁定", i+1)
+		}
+	}
+
+	resp := serve(t, h.Login, http.MethodPost, "/api/login/wechat", `{"code":"u3"}`, "", ip)
+	if resp.Status != http.StatusTooManyRequests {
+		t.Fatalf("超过次数后状态码 = %d, want 429", resp.Status)
+	}
+	if retryAfter, _ := resp.Data["retry_after"].(float64); retryAfter <= 0 {
+		t.Errorf("retry_after = %v, want > 0", resp.Data["retry_after"])
+	}
+	if binding, _ := database.GetIdentity(models.IdentityProviderWeChat, "fake-u3"); binding != nil {
+		t.Error("锁定后仍登记了绑定申请")
+	}
+
+	// 其他IP不受影响
+	if resp := serve(t, h.Login, http.MethodPost, "/api/login/wechat", `{"code":"u4"}`, "", "192.0.2.4"); resp.Status != http.StatusAccepted {
+		t.Errorf("其他IP状态码 = %d, want 202", resp.Status)
+	}
+
+	// 微信登录的锁定不影响该IP的账号密码登录
+	until, err := (&services.LoginGuard{}).LockedUntil("", ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !until.IsZero() {
+		t.Error("微信登录锁定影响了账号密码登录")
+	}
+}
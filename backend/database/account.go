@@ -15,46 +15,31 @@ import (
 func CreateAccount(account *models.Account) (int64, error) {
 	// 准备SQL语句
 	query := `
-		INSERT INTO accounts (store_id, user_id, type_id, amount, remark, transaction_time, create_time, update_time)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO accounts (store_id, user_id, type_id, amount, currency, remark, transaction_time, utc_offset, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	// 确保有一个有效的交易日期时间
-	var transactionTimeStr string
-
-	// 检查并处理日期时间格式
+	// 交易时间为店铺时区的本地时间，未提供时使用店铺时区的当前时间
+	loc, err := storeLocation(DB, account.StoreID)
+	if err != nil {
+		return 0, err
+	}
+	var transactionTime accountTime
 	if account.TransactionTime == "" {
-		// 如果未提供日期时间，使用当前时间的完整格式
-		transactionTimeStr = time.Now().Format("2006-01-02 15:04:05")
+		transactionTime = newAccountTime(time.Now(), loc)
 	} else {
-		// 尝试解析各种可能的日期时间格式
-		formats := []string{
-			"2006-01-02T15:04:05", // ISO格式
-			"2006-01-02 15:04:05", // 标准格式
-			"2006-01-02 15:04",    // 没有秒的格式
-			"2006-01-02",          // 仅日期
-			"2006/01/02 15:04:05", // 斜杠分隔的日期时间
-			"2006/01/02",          // 斜杠分隔的日期
-		}
-
-		var t time.Time
-		var err error
-
-		// 尝试所有格式
-		for _, format := range formats {
-			t, err = time.Parse(format, account.TransactionTime)
-			if err == nil {
-				// 转换为标准格式
-				transactionTimeStr = t.Format("2006-01-02 15:04:05")
-				break
-			}
-		}
-
-		// 如果所有格式都解析失败，使用当前时间
+		t, err := time.ParseInLocation(models.DateTimeLayout, account.TransactionTime, loc)
 		if err != nil {
 			log.Printf("无法解析交易时间 '%s', 使用当前时间", account.TransactionTime)
-			transactionTimeStr = time.Now().Format("2006-01-02 15:04:05")
+			t = time.Now()
 		}
+		transactionTime = newAccountTime(t, loc)
+	}
+	account.TransactionTime = transactionTime.local
+
+	// 已结账的月份不能新增记录
+	if err := checkPeriodOpen(DB, account.StoreID, transactionTime.local); err != nil {
+		return 0, err
 	}
 
 	// 执行查询
@@ -63,10 +48,12 @@ func CreateAccount(account *models.Account) (int64, error) {
 		account.UserID,
 		account.TypeID,
 		account.Amount,
+		nullableCurrency(account.Currency),
 		account.Remark,
-		transactionTimeStr, // 使用格式化后的日期时间
-		account.CreateTime,
-		account.UpdateTime)
+		transactionTime.utc,
+		transactionTime.offset,
+		dbTime(account.CreateTime),
+		dbTime(account.UpdateTime))
 
 	if err != nil {
 		return 0, err
@@ -80,8 +67,88 @@ func CreateAccount(account *models.Account) (int64, error) {
 	return id, nil
 }
 
-// GetAccountsEnhanced 增强版获取账目列表，提供更详细的错误处理和调试信息
-func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount, maxAmount, page, limit string) ([]map[string]interface{}, int, error) {
+// GetAccountByID 根据ID获取账务记录，不存在时返回sql.ErrNoRows
+func GetAccountByID(id int64) (*models.Account, error) {
+	var account models.Account
+	var remark sql.NullString
+	err := DB.QueryRow(`
+		SELECT a.id, a.store_id, a.user_id, a.type_id, a.amount, COALESCE(a.currency, ''), `+isExpenseSQL+`, COALESCE(a.transfer_peer_id, 0),
+			a.remark, `+accountLocalTimeSQL+`, a.create_time, a.update_time
+		FROM accounts a
+		LEFT JOIN account_types t ON a.type_id = t.id
+		WHERE a.id = ? AND a.deleted_at IS NULL
+	`, id).Scan(
+		&account.ID, &account.StoreID, &account.UserID, &account.TypeID, &account.Amount, &account.Currency, &account.IsExpense, &account.TransferPeerID,
+		&remark, &account.TransactionTime, &account.CreateTime, &account.UpdateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	account.Remark = remark.String
+	return &account, nil
+}
+
+// UpdateAccount 修改账务记录，修改前的内容保存到历史表。
+// 记录人和创建时间保持不变，changedBy为本次修改的用户；记录不存在时返回sql.ErrNoRows
+func UpdateAccount(account *models.Account, changedBy int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateAccountTx(tx, account, changedBy); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateAccountTx 在事务中保存修改前的版本并更新账务记录
+func updateAccountTx(tx *sql.Tx, account *models.Account, changedBy int64) error {
+	// 修改前和修改后所在的月份都不能已结账
+	if err := checkAccountPeriodOpen(tx, account.ID); err != nil {
+		return err
+	}
+	transactionTime, err := resolveAccountTime(tx, account.StoreID, account.TransactionTime)
+	if err != nil {
+		return err
+	}
+	if err := checkPeriodOpen(tx, account.StoreID, transactionTime.local); err != nil {
+		return err
+	}
+
+	// 保存修改前的版本
+	result, err := tx.Exec(`
+		INSERT INTO account_history (account_id, version, store_id, user_id, type_id, amount, currency, remark, transaction_time, utc_offset, changed_by, change_time)
+		SELECT a.id,
+			(SELECT COALESCE(MAX(h.version), 0) + 1 FROM account_history h WHERE h.account_id = a.id),
+			a.store_id, a.user_id, a.type_id, a.amount, a.currency, a.remark, a.transaction_time, a.utc_offset, ?, ?
+		FROM accounts a WHERE a.id = ? AND a.deleted_at IS NULL
+	`, changedBy, dbTime(time.Now()), account.ID)
+	if err != nil {
+		return fmt.Errorf("保存账务修改历史失败: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.Exec(`
+		UPDATE accounts SET store_id = ?, type_id = ?, amount = ?, currency = ?, remark = ?, transaction_time = ?, utc_offset = ?, update_time = ?
+		WHERE id = ?
+	`, account.StoreID, account.TypeID, account.Amount, nullableCurrency(account.Currency), account.Remark,
+		transactionTime.utc, transactionTime.offset, dbTime(account.UpdateTime), account.ID)
+	if err != nil {
+		return fmt.Errorf("更新账务记录失败: %v", err)
+	}
+	return nil
+}
+
+// accountListQuery 按筛选条件和用户权限构造账目列表查询，不含排序和分页；
+// capability不为空时非管理员只包含其店内角色拥有该能力的店铺
+func accountListQuery(userID, storeID, typeID, keyword, startDate, endDate, minAmount, maxAmount string, capability models.Capability) (string, []interface{}, error) {
 	// 调试日志
 	log.Printf("【调试】GetAccounts 输入参数: storeID=%s, typeID=%s, userID=%s", storeID, typeID, userID)
 
@@ -90,13 +157,15 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 		SELECT
 			a.id, a.store_id, s.name as store_name, 
 			COALESCE(a.user_id, 0) as user_id, COALESCE(u.username, '未知用户') as username,
-			a.type_id, t.name as type_name, a.amount, a.remark, a.transaction_time,
-			a.create_time, a.update_time
+			a.type_id, t.name as type_name, a.amount, COALESCE(a.currency, s.currency, '` + models.DefaultCurrency + `') as currency, ` + isExpenseSQL + ` as is_expense,
+			COALESCE(a.transfer_peer_id, 0) as transfer_peer_id, a.remark, ` + accountLocalTimeSQL + ` as transaction_time,
+			a.create_time, a.update_time,
+			(SELECT COUNT(*) FROM account_attachments att WHERE att.account_id = a.id) as attachment_count
 		FROM accounts a
 		LEFT JOIN stores s ON a.store_id = s.id
 		LEFT JOIN users u ON a.user_id = u.id
 		LEFT JOIN account_types t ON a.type_id = t.id
-		WHERE 1=1
+		WHERE a.deleted_at IS NULL
 	`
 
 	// 基础查询参数
@@ -109,27 +178,33 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 	userIDInt, _ := strconv.ParseInt(userID, 10, 64)
 	if userIDInt <= 0 {
 		log.Printf("无效的用户ID: %s", userID)
-		return nil, 0, fmt.Errorf("无效的用户ID")
+		return "", nil, fmt.Errorf("无效的用户ID")
 	}
 
-	if userIDInt != 1 { // 1号用户(admin)可以看所有店铺
-		// 检查用户是管理员还是普通职员
-		var userRole int
-		if err := DB.QueryRow("SELECT role FROM users WHERE id = ?", userIDInt).Scan(&userRole); err != nil {
-			log.Printf("查询用户角色失败: %v", err)
-			return nil, 0, fmt.Errorf("查询用户权限失败")
-		}
+	// 管理员可以看所有店铺，普通职员只能看到有权限的店铺
+	isAdmin, err := IsUserAdmin(userIDInt)
+	if err != nil {
+		log.Printf("查询用户角色失败: %v", err)
+		return "", nil, fmt.Errorf("查询用户权限失败")
+	}
 
-		if userRole != 1 { // 不是管理员角色，需要过滤店铺权限
-			query += `
-				AND a.store_id IN (
-					SELECT store_id FROM user_store_permissions
-					WHERE user_id = ?
-				)
-			`
-			args = append(args, userIDInt)
-			conditions = append(conditions, fmt.Sprintf("用户ID=%d有权限的店铺", userIDInt))
-		}
+	if !isAdmin {
+		roleFilter, roleArgs := storeRoleCondition("role", capability)
+		query += `
+			AND a.store_id IN (
+				SELECT store_id FROM user_store_permissions
+				WHERE user_id = ?` + roleFilter + `
+			)
+		`
+		args = append(args, userIDInt)
+		args = append(args, roleArgs...)
+		conditions = append(conditions, fmt.Sprintf("用户ID=%d有权限的店铺", userIDInt))
+
+		// 只显示有权查看的账务类型
+		typeFilter, typeArgs := accountTypeVisibilityCondition(userIDInt, "a.type_id", "a.store_id")
+		query += typeFilter
+		args = append(args, typeArgs...)
+		conditions = append(conditions, fmt.Sprintf("用户ID=%d有权查看的账务类型", userIDInt))
 	}
 
 	// 处理店铺筛选
@@ -154,7 +229,7 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 			if err != nil {
 				if err == sql.ErrNoRows {
 					log.Printf("【店铺筛选调试】店铺ID=%d不存在", storeIDInt)
-					return nil, 0, fmt.Errorf("店铺不存在")
+					return "", nil, fmt.Errorf("店铺不存在")
 				}
 				log.Printf("【店铺筛选调试】查询店铺失败: %v", err)
 			} else {
@@ -162,7 +237,7 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 
 				// 检查是否有该店铺的账目
 				var accountCount int
-				err := DB.QueryRow("SELECT COUNT(*) FROM accounts WHERE store_id = ?", storeIDInt).Scan(&accountCount)
+				err := DB.QueryRow("SELECT COUNT(*) FROM accounts WHERE store_id = ? AND deleted_at IS NULL", storeIDInt).Scan(&accountCount)
 				if err != nil {
 					log.Printf("【店铺筛选调试】查询店铺账目数量失败: %v", err)
 				} else {
@@ -197,32 +272,33 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 		}
 	}
 
+	// 日期按店铺时区的本地时间筛选
 	if startDate != "" {
-		query += " AND a.transaction_time >= ?"
+		query += " AND " + accountLocalTimeSQL + " >= ?"
 		args = append(args, startDate+" 00:00:00")
 	}
 
 	if endDate != "" {
-		query += " AND a.transaction_time <= ?"
+		query += " AND " + accountLocalTimeSQL + " <= ?"
 		args = append(args, endDate+" 23:59:59")
 	}
 
-	// 处理金额范围筛选 - 使用绝对值比较
+	// 处理金额范围筛选 - 使用绝对值比较，金额以分为单位
 	if minAmount != "" {
-		minAmountFloat, err := strconv.ParseFloat(minAmount, 64)
+		minAmountCents, err := models.ParseMoney(minAmount)
 		if err == nil {
 			query += " AND ABS(a.amount) >= ?"
-			args = append(args, minAmountFloat)
+			args = append(args, minAmountCents)
 		} else {
 			log.Printf("无效的最小金额: %s, 错误: %v", minAmount, err)
 		}
 	}
 
 	if maxAmount != "" {
-		maxAmountFloat, err := strconv.ParseFloat(maxAmount, 64)
+		maxAmountCents, err := models.ParseMoney(maxAmount)
 		if err == nil {
 			query += " AND ABS(a.amount) <= ?"
-			args = append(args, maxAmountFloat)
+			args = append(args, maxAmountCents)
 		} else {
 			log.Printf("无效的最大金额: %s, 错误: %v", maxAmount, err)
 		}
@@ -230,8 +306,8 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 
 	// 优化关键词搜索逻辑，尤其是金额搜索
 	if keyword != "" {
-		// 尝试将关键词转换为数字，用于精确匹配金额
-		numericValue, err := strconv.ParseFloat(keyword, 64)
+		// 尝试将关键词转换为金额，用于精确匹配
+		numericValue, err := models.ParseMoney(keyword)
 
 		if err == nil {
 			// 如果是数字，添加金额精确匹配条件
@@ -241,7 +317,7 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 				t.name LIKE ? OR 
 				u.username LIKE ? OR
 				ABS(a.amount) = ? OR  
-				CAST(a.amount AS CHAR) LIKE ?
+				PRINTF('%d.%02d', ABS(a.amount) / 100, ABS(a.amount) % 100) LIKE ?
 			)`
 			searchTerm := "%" + keyword + "%"
 			args = append(args, searchTerm, searchTerm, searchTerm, searchTerm, numericValue, searchTerm)
@@ -258,6 +334,16 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 		}
 	}
 
+	return query, args, nil
+}
+
+// GetAccountsEnhanced 增强版获取账目列表，提供更详细的错误处理和调试信息
+func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount, maxAmount, page, limit string) ([]map[string]interface{}, int, error) {
+	query, args, err := accountListQuery(userID, storeID, typeID, keyword, startDate, endDate, minAmount, maxAmount, "")
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// 计算偏移量
 	var offset int
 
@@ -303,33 +389,13 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 	defer rows.Close()
 
 	accounts := []map[string]interface{}{}
+	locations := storeLocations{}
 	for rows.Next() {
-		var id, storeID, typeID, userID int64
-		var storeName, username, typeName, remark string
-		var amount float64
-		var transactionTime time.Time
-		var createTime, updateTime time.Time
-
-		err := rows.Scan(&id, &storeID, &storeName, &userID, &username, &typeID, &typeName, &amount, &remark, &transactionTime, &createTime, &updateTime)
+		account, err := scanAccountListRow(rows, locations)
 		if err != nil {
 			log.Printf("扫描账务记录失败: %v", err)
 			continue
 		}
-
-		account := map[string]interface{}{
-			"id":               id,
-			"store_id":         storeID,
-			"store_name":       storeName,
-			"user_id":          userID,
-			"username":         username,
-			"type_id":          typeID,
-			"type_name":        typeName,
-			"amount":           amount,
-			"remark":           remark,
-			"transaction_time": transactionTime.Format("2006-01-02 15:04:05"),
-			"create_time":      createTime.Format("2006-01-02 15:04:05"),
-			"update_time":      updateTime.Format("2006-01-02 15:04:05"),
-		}
 		accounts = append(accounts, account)
 	}
 
@@ -347,8 +413,71 @@ func GetAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount
 	return accounts, limitInt, nil
 }
 
-// GetAccountStatistics 获取账务统计
-func GetAccountStatistics(storeID, typeID, startDate, endDate, minAmount, maxAmount string, userID int64) (map[string]interface{}, error) {
+// ExportAccounts 按与GetAccounts相同的筛选条件读取全部账目，不分页，逐行交给handle处理；
+// 非管理员只导出可以查看报表的店铺。handle返回错误时停止读取并返回该错误
+func ExportAccounts(userID, storeID, typeID, keyword, startDate, endDate, minAmount, maxAmount string, handle func(account map[string]interface{}) error) error {
+	query, args, err := accountListQuery(userID, storeID, typeID, keyword, startDate, endDate, minAmount, maxAmount, models.CapReportsView)
+	if err != nil {
+		return err
+	}
+
+	rows, err := DB.Query(query+" ORDER BY a.transaction_time DESC, a.id DESC", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	locations := storeLocations{}
+	for rows.Next() {
+		account, err := scanAccountListRow(rows, locations)
+		if err != nil {
+			return fmt.Errorf("读取账务记录失败: %v", err)
+		}
+		if err := handle(account); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// scanAccountListRow 读取账目列表查询的一行
+func scanAccountListRow(rows *sql.Rows, locations storeLocations) (map[string]interface{}, error) {
+	var id, storeID, typeID, userID int64
+	var storeName, username, typeName, currency, remark string
+	var amount models.Money
+	var isExpense bool
+	var transferPeerID, attachmentCount int64
+	var transactionTime string
+	var createTime, updateTime time.Time
+
+	err := rows.Scan(&id, &storeID, &storeName, &userID, &username, &typeID, &typeName, &amount, &currency, &isExpense, &transferPeerID, &remark, &transactionTime, &createTime, &updateTime, &attachmentCount)
+	if err != nil {
+		return nil, err
+	}
+
+	account := map[string]interface{}{
+		"id":               id,
+		"store_id":         storeID,
+		"store_name":       storeName,
+		"user_id":          userID,
+		"username":         username,
+		"type_id":          typeID,
+		"type_name":        typeName,
+		"amount":           amount,
+		"currency":         currency,
+		"is_expense":       isExpense,
+		"transfer_peer_id": transferPeerID,
+		"remark":           remark,
+		"transaction_time": transactionTime,
+		"create_time":      createTime.In(locations.get(storeID)).Format(models.DateTimeLayout),
+		"update_time":      updateTime.In(locations.get(storeID)).Format(models.DateTimeLayout),
+		"attachment_count": attachmentCount,
+	}
+	return account, nil
+}
+
+// GetAccountStatistics 获取账务统计，金额按各记录交易日期的汇率换算为currency币种，缺少汇率的记录不计入金额
+func GetAccountStatistics(storeID, typeID, startDate, endDate, minAmount, maxAmount string, userID int64, currency string) (map[string]interface{}, error) {
 	// 将userID转换为string，保持接口一致性
 	userIDStr := fmt.Sprintf("%d", userID)
 
@@ -359,36 +488,44 @@ func GetAccountStatistics(storeID, typeID, startDate, endDate, minAmount, maxAmo
 	// 检查用户权限
 	userIDInt, _ := strconv.ParseInt(userIDStr, 10, 64)
 	// 检查用户是否是管理员
-	var userRole int
-	err := DB.QueryRow("SELECT role FROM users WHERE id = ?", userIDInt).Scan(&userRole)
+	isAdmin, err := IsUserAdmin(userIDInt)
 	if err != nil {
 		log.Printf("查询用户角色失败: %v", err)
 		return nil, fmt.Errorf("查询用户权限失败")
 	}
 
-	isAdmin := (userRole == 1)
-
 	query := `
 		SELECT 
-			COALESCE(SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), 0) as total_income,
-			COALESCE(SUM(CASE WHEN amount < 0 THEN ABS(amount) ELSE 0 END), 0) as total_expense,
-			COALESCE(SUM(amount), 0) as net_amount
-		FROM accounts
-		WHERE 1=1
+			COALESCE(SUM(` + inCurrency(incomeAmountSQL, currency) + `), 0) as total_income,
+			COALESCE(SUM(` + inCurrency(expenseAmountSQL, currency) + `), 0) as total_expense,
+			COALESCE(SUM(` + inCurrency(signedAmountSQL, currency) + `), 0) as net_amount,
+			COALESCE(SUM(` + inCurrency(transferInAmountSQL, currency) + `), 0) as transfer_in,
+			COALESCE(SUM(` + inCurrency(transferOutAmountSQL, currency) + `), 0) as transfer_out,
+			` + unconvertedCountSQL(currency) + ` as unconverted_count
+		FROM accounts a
+		LEFT JOIN account_types t ON a.type_id = t.id
+		WHERE a.deleted_at IS NULL
 	`
 	var args []interface{}
 
-	// 普通店员需要添加权限过滤
+	// 普通店员只统计可以查看报表的店铺
 	if !isAdmin {
+		roleFilter, roleArgs := storeRoleCondition("role", models.CapReportsView)
 		query += `
 			AND (
 				store_id IN (
 					SELECT store_id FROM user_store_permissions 
-					WHERE user_id = ?
+					WHERE user_id = ?` + roleFilter + `
 				)
 			)
 		`
 		args = append(args, userIDInt)
+		args = append(args, roleArgs...)
+
+		// 只统计有权查看的账务类型
+		typeFilter, typeArgs := accountTypeVisibilityCondition(userIDInt, "a.type_id", "a.store_id")
+		query += typeFilter
+		args = append(args, typeArgs...)
 	}
 
 	// 添加原有的筛选条件
@@ -437,45 +574,46 @@ func GetAccountStatistics(storeID, typeID, startDate, endDate, minAmount, maxAmo
 	}
 
 	if startDate != "" {
-		query += " AND transaction_time >= ?"
+		query += " AND " + accountLocalTimeSQL + " >= ?"
 		args = append(args, startDate+" 00:00:00")
 	}
 
 	if endDate != "" {
-		query += " AND transaction_time <= ?"
+		query += " AND " + accountLocalTimeSQL + " <= ?"
 		args = append(args, endDate+" 23:59:59")
 	}
 
-	// 添加金额范围筛选 - 使用绝对值比较
+	// 添加金额范围筛选 - 使用绝对值比较，金额以分为单位
 	if minAmount != "" {
-		minAmountFloat, err := strconv.ParseFloat(minAmount, 64)
+		minAmountCents, err := models.ParseMoney(minAmount)
 		if err == nil {
 			query += " AND ABS(amount) >= ?"
-			args = append(args, minAmountFloat)
+			args = append(args, minAmountCents)
 		} else {
 			log.Printf("统计查询 - 无效的最小金额: %s, 错误: %v", minAmount, err)
 		}
 	}
 
 	if maxAmount != "" {
-		maxAmountFloat, err := strconv.ParseFloat(maxAmount, 64)
+		maxAmountCents, err := models.ParseMoney(maxAmount)
 		if err == nil {
 			query += " AND ABS(amount) <= ?"
-			args = append(args, maxAmountFloat)
+			args = append(args, maxAmountCents)
 		} else {
 			log.Printf("统计查询 - 无效的最大金额: %s, 错误: %v", maxAmount, err)
 		}
 	}
 
 	// 执行查询
-	var totalIncome, totalExpense, netAmount float64
+	var totalIncome, totalExpense, netAmount, transferIn, transferOut models.Money
+	var unconvertedCount int
 	logSql := query
 	for _, arg := range args {
 		logSql = strings.Replace(logSql, "?", fmt.Sprintf("'%v'", arg), 1)
 	}
 	log.Printf("执行统计SQL: %s", logSql)
 
-	err = DB.QueryRow(query, args...).Scan(&totalIncome, &totalExpense, &netAmount)
+	err = DB.QueryRow(query, args...).Scan(&totalIncome, &totalExpense, &netAmount, &transferIn, &transferOut, &unconvertedCount)
 	if err != nil {
 		return nil, err
 	}
@@ -484,18 +622,27 @@ func GetAccountStatistics(storeID, typeID, startDate, endDate, minAmount, maxAmo
 		"total_income":  totalIncome,
 		"total_expense": totalExpense,
 		"net_amount":    netAmount,
+		"transfer_in":   transferIn,
+		"transfer_out":  transferOut,
+		"currency":      currency,
+		// 缺少汇率、未计入金额的记录数
+		"unconverted_count": unconvertedCount,
 	}
 
 	return stats, nil
 }
 
-// DeleteAccount 从数据库中删除指定ID的账目
-func DeleteAccount(id int) error {
-	// 构建SQL语句
-	query := "DELETE FROM accounts WHERE id = ?"
+// DeleteAccount 将指定ID的账目移入回收站，deletedBy为执行删除的用户；转账记录连同对方店铺的记录一起删除
+func DeleteAccount(id int, deletedBy int64) error {
+	if err := checkAccountPeriodOpen(DB, int64(id)); err != nil {
+		return err
+	}
+
+	// 软删除，记录删除时间和删除人
+	query := "UPDATE accounts SET deleted_at = ?, deleted_by = ? WHERE (id = ? OR transfer_peer_id = ?) AND deleted_at IS NULL"
 
 	// 执行删除操作
-	result, err := DB.Exec(query, id)
+	result, err := DB.Exec(query, dbTime(time.Now()), deletedBy, id, id)
 	if err != nil {
 		return err
 	}
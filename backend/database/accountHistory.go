@@ -0,0 +1,85 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"account/backend/models"
+)
+
+// CreateAccountHistoryTables 创建账务记录修改历史表
+func CreateAccountHistoryTables() error {
+	createHistoryTable := `
+	CREATE TABLE IF NOT EXISTS account_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_id INTEGER NOT NULL,
+		version INTEGER NOT NULL,
+		store_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		type_id INTEGER NOT NULL,
+		amount INTEGER NOT NULL,
+		currency TEXT,
+		remark TEXT,
+		transaction_time TIMESTAMP,
+		utc_offset INTEGER NOT NULL DEFAULT 0,
+		changed_by INTEGER NOT NULL,
+		change_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (account_id, version)
+	);`
+
+	if _, err := DB.Exec(createHistoryTable); err != nil {
+		return fmt.Errorf("创建账务修改历史表失败: %v", err)
+	}
+
+	if err := migrateAmountToCents("account_history"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists("account_history", "currency", "TEXT"); err != nil {
+		return err
+	}
+	if err := migrateTimesToUTC("account_history", "change_time"); err != nil {
+		return err
+	}
+
+	// 与账务记录一致，历史版本的金额也保存为正数
+	if _, err := DB.Exec("UPDATE account_history SET amount = -amount WHERE amount < 0"); err != nil {
+		return fmt.Errorf("转换账务修改历史金额失败: %v", err)
+	}
+
+	log.Println("账务修改历史表初始化完成")
+	return nil
+}
+
+// GetAccountHistory 获取账务记录的历史版本，按版本从旧到新排列
+func GetAccountHistory(accountID int64) ([]models.AccountHistory, error) {
+	rows, err := DB.Query(`
+		SELECT h.id, h.account_id, h.version, h.store_id, h.user_id, h.type_id, h.amount, COALESCE(h.currency, ''),
+			COALESCE(h.remark, ''), `+localTimeSQL("h")+`, h.changed_by, COALESCE(u.username, ''), h.change_time
+		FROM account_history h
+		LEFT JOIN users u ON h.changed_by = u.id
+		WHERE h.account_id = ?
+		ORDER BY h.version
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("查询账务修改历史失败: %v", err)
+	}
+	defer rows.Close()
+
+	history := []models.AccountHistory{}
+	for rows.Next() {
+		var item models.AccountHistory
+		if err := rows.Scan(
+			&item.ID, &item.AccountID, &item.Version, &item.StoreID, &item.UserID, &item.TypeID, &item.Amount, &item.Currency,
+			&item.Remark, &item.TransactionTime, &item.ChangedBy, &item.ChangedByName, &item.ChangeTime,
+		); err != nil {
+			return nil, fmt.Errorf("读取账务修改历史失败: %v", err)
+		}
+		history = append(history, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"fmt"
+
+	"account/backend/models"
+)
+
+// GetStoreNames 获取全部店铺的ID和名称
+func GetStoreNames() (map[int64]string, error) {
+	rows, err := DB.Query("SELECT id, name FROM stores")
+	if err != nil {
+		return nil, fmt.Errorf("查询店铺失败: %v", err)
+	}
+	defer rows.Close()
+
+	stores := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("读取店铺失败: %v", err)
+		}
+		stores[id] = name
+	}
+	return stores, rows.Err()
+}
+
+// AccountExists 检查是否已有店铺、类型、金额、交易时间和备注都相同的账务记录，用于导入时识别重复数据
+func AccountExists(storeID, typeID int64, amount models.Money, transactionTime, remark string) (bool, error) {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM accounts a
+		WHERE a.deleted_at IS NULL AND a.store_id = ? AND a.type_id = ? AND a.amount = ? AND `+accountLocalTimeSQL+` = ? AND COALESCE(a.remark, '') = ?
+	`, storeID, typeID, amount, transactionTime, remark).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("检查重复账务记录失败: %v", err)
+	}
+	return count > 0, nil
+}
+
+// ImportAccounts 在同一事务中保存导入的账务记录，任何一条失败时全部回滚，返回新记录的ID
+func ImportAccounts(accounts []models.Account) ([]int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO accounts (store_id, user_id, type_id, amount, remark, transaction_time, utc_offset, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	ids := make([]int64, 0, len(accounts))
+	for i, account := range accounts {
+		transactionTime, err := resolveAccountTime(tx, account.StoreID, account.TransactionTime)
+		if err != nil {
+			return nil, fmt.Errorf("导入第%d条账务记录失败: %v", i+1, err)
+		}
+		if err := checkPeriodOpen(tx, account.StoreID, transactionTime.local); err != nil {
+			return nil, fmt.Errorf("导入第%d条账务记录失败: %w", i+1, err)
+		}
+		result, err := stmt.Exec(account.StoreID, account.UserID, account.TypeID, account.Amount, account.Remark,
+			transactionTime.utc, transactionTime.offset, dbTime(account.CreateTime), dbTime(account.UpdateTime))
+		if err != nil {
+			return nil, fmt.Errorf("导入第%d条账务记录失败: %v", i+1, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"account/backend/models"
+)
+
+// GetDeletedAccounts 分页获取回收站中的账务记录，按删除时间倒序，storeID为0时不限店铺
+func GetDeletedAccounts(storeID int64, page, limit int) ([]models.DeletedAccount, int, error) {
+	where := "WHERE a.deleted_at IS NOT NULL"
+	args := []interface{}{}
+	if storeID > 0 {
+		where += " AND a.store_id = ?"
+		args = append(args, storeID)
+	}
+
+	var total int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM accounts a "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计回收站记录失败: %v", err)
+	}
+
+	rows, err := DB.Query(`
+		SELECT a.id, a.store_id, COALESCE(s.name, ''), COALESCE(a.user_id, 0), a.type_id, COALESCE(t.name, ''),
+			a.amount, `+isExpenseSQL+`, COALESCE(a.transfer_peer_id, 0), COALESCE(a.remark, ''), `+accountLocalTimeSQL+`, a.create_time, a.update_time,
+			a.deleted_at, COALESCE(a.deleted_by, 0), COALESCE(u.username, '')
+		FROM accounts a
+		LEFT JOIN stores s ON a.store_id = s.id
+		LEFT JOIN account_types t ON a.type_id = t.id
+		LEFT JOIN users u ON a.deleted_by = u.id
+		`+where+`
+		ORDER BY a.deleted_at DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询回收站记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.DeletedAccount{}
+	for rows.Next() {
+		var item models.DeletedAccount
+		if err := rows.Scan(
+			&item.ID, &item.StoreID, &item.StoreName, &item.UserID, &item.TypeID, &item.TypeName,
+			&item.Amount, &item.IsExpense, &item.TransferPeerID, &item.Remark, &item.TransactionTime, &item.CreateTime, &item.UpdateTime,
+			&item.DeletedAt, &item.DeletedBy, &item.DeletedByName,
+		); err != nil {
+			return nil, 0, fmt.Errorf("读取回收站记录失败: %v", err)
+		}
+		accounts = append(accounts, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return accounts, total, nil
+}
+
+// RestoreAccount 从回收站恢复账务记录，转账记录连同对方店铺的记录一起恢复；记录不在回收站中时返回sql.ErrNoRows
+func RestoreAccount(id int64) error {
+	if err := checkAccountPeriodOpen(DB, id); err != nil {
+		return err
+	}
+
+	result, err := DB.Exec(`
+		UPDATE accounts SET deleted_at = NULL, deleted_by = NULL
+		WHERE (id = ? OR transfer_peer_id = ?) AND deleted_at IS NOT NULL
+	`, id, id)
+	if err != nil {
+		return fmt.Errorf("恢复账务记录失败: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeDeletedAccounts 永久删除在before之前移入回收站的账务记录及其修改历史和附件记录，
+// 返回删除前的记录数据用于审计；附件文件由调用方删除
+func PurgeDeletedAccounts(before time.Time) ([]map[string]interface{}, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT * FROM accounts WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY id", dbTime(before))
+	if err != nil {
+		return nil, fmt.Errorf("读取回收站记录失败: %v", err)
+	}
+	purged, err := scanAuditRecords(rows)
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("读取回收站记录失败: %v", err)
+	}
+	if len(purged) == 0 {
+		return purged, nil
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM account_history WHERE account_id IN (
+			SELECT id FROM accounts WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		)
+	`, dbTime(before)); err != nil {
+		return nil, fmt.Errorf("清理账务修改历史失败: %v", err)
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM account_attachments WHERE account_id IN (
+			SELECT id FROM accounts WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		)
+	`, dbTime(before)); err != nil {
+		return nil, fmt.Errorf("清理账务附件失败: %v", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM accounts WHERE deleted_at IS NOT NULL AND deleted_at < ?", dbTime(before)); err != nil {
+		return nil, fmt.Errorf("清理回收站失败: %v", err)
+	}
+
+	return purged, tx.Commit()
+}
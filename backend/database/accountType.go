@@ -8,7 +8,22 @@ import (
 
 // GetAllAccountTypes 获取所有账务类型
 func GetAllAccountTypes() ([]models.AccountType, error) {
-	rows, err := DB.Query("SELECT id, name, category, icon, sort_order, is_expense FROM account_types")
+	return queryAccountTypes("", nil)
+}
+
+// GetAccountTypesForUser 获取用户有权查看的账务类型
+func GetAccountTypesForUser(userID int64) ([]models.AccountType, error) {
+	filter, args, err := AccountTypeVisibilityFilter(userID, "account_types.id", "")
+	if err != nil {
+		return nil, err
+	}
+	return queryAccountTypes(filter, args)
+}
+
+// queryAccountTypes 按附加条件查询账务类型
+func queryAccountTypes(filter string, args []interface{}) ([]models.AccountType, error) {
+	// 系统内置类型不在列表中展示
+	rows, err := DB.Query("SELECT id, name, category, icon, sort_order, is_expense FROM account_types WHERE system_code IS NULL"+filter, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -43,15 +58,8 @@ func GetAllAccountTypes() ([]models.AccountType, error) {
 
 // CreateAccountType 创建新账务类型
 func CreateAccountType(accountType models.AccountType) (int64, error) {
-	// 确保category值有效
-	if accountType.Type <= 0 {
-		// 根据是否为支出设置默认值
-		if accountType.IsExpense {
-			accountType.Type = 2 // 支出
-		} else {
-			accountType.Type = 1 // 收入
-		}
-	}
+	// 确保category值有效且与is_expense一致
+	accountType.NormalizeCategory()
 
 	result, err := DB.Exec(
 		"INSERT INTO account_types (name, category, icon, sort_order, is_expense) VALUES (?, ?, ?, ?, ?)",
@@ -65,15 +73,8 @@ func CreateAccountType(accountType models.AccountType) (int64, error) {
 
 // UpdateAccountType 更新账务类型信息
 func UpdateAccountType(accountType models.AccountType) error {
-	// 确保category值有效
-	if accountType.Type <= 0 {
-		// 根据是否为支出设置默认值
-		if accountType.IsExpense {
-			accountType.Type = 2 // 支出
-		} else {
-			accountType.Type = 1 // 收入
-		}
-	}
+	// 确保category值有效且与is_expense一致
+	accountType.NormalizeCategory()
 
 	_, err := DB.Exec(
 		"UPDATE account_types SET name = ?, category = ?, icon = ?, sort_order = ?, is_expense = ? WHERE id = ?",
@@ -82,9 +83,15 @@ func UpdateAccountType(accountType models.AccountType) error {
 	return err
 }
 
-// DeleteAccountType 删除账务类型
+// DeleteAccountType 删除账务类型，系统内置类型不会被删除
 func DeleteAccountType(typeID int64) error {
-	_, err := DB.Exec("DELETE FROM account_types WHERE id = ?", typeID)
+	// 先删除该类型的查看权限
+	_, err := DB.Exec("DELETE FROM account_type_permissions WHERE account_type_id = ?", typeID)
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.Exec("DELETE FROM account_types WHERE id = ? AND system_code IS NULL", typeID)
 	return err
 }
 
@@ -97,3 +104,23 @@ func HasAccountTypeRecords(typeID int64) (bool, error) {
 	}
 	return count > 0, nil
 }
+
+// AccountTypeExists 检查账务类型是否存在
+func AccountTypeExists(typeID int64) (bool, error) {
+	var count int
+	err := DB.QueryRow("SELECT COUNT(*) FROM account_types WHERE id = ?", typeID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsExpenseAccountType 检查账务类型是否为支出类型，类型不存在或为系统内置类型时返回sql.ErrNoRows
+func IsExpenseAccountType(typeID int64) (bool, error) {
+	var category int
+	err := DB.QueryRow("SELECT category FROM account_types WHERE id = ? AND system_code IS NULL", typeID).Scan(&category)
+	if err != nil {
+		return false, err
+	}
+	return category == models.AccountCategoryExpense, nil
+}
@@ -0,0 +1,168 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"account/backend/models"
+)
+
+// CreateAccountTypePermissionTables 创建账务类型查看权限表
+func CreateAccountTypePermissionTables() error {
+	// 每行授权一个用户或一种店内角色查看某个账务类型，
+	// 没有任何授权记录的账务类型对所有人可见
+	createPermissionTable := `
+	CREATE TABLE IF NOT EXISTS account_type_permissions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_type_id INTEGER NOT NULL,
+		user_id INTEGER,
+		store_role TEXT,
+		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (account_type_id) REFERENCES account_types(id),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`
+
+	if _, err := DB.Exec(createPermissionTable); err != nil {
+		return fmt.Errorf("创建账务类型权限表失败: %v", err)
+	}
+
+	if _, err := DB.Exec(`CREATE INDEX IF NOT EXISTS idx_account_type_permissions_type ON account_type_permissions(account_type_id)`); err != nil {
+		return fmt.Errorf("创建账务类型权限表索引失败: %v", err)
+	}
+
+	log.Println("账务类型权限表初始化完成")
+	return nil
+}
+
+// GetAccountTypePermission 获取账务类型的查看权限设置
+func GetAccountTypePermission(typeID int64) (*models.AccountTypePermission, error) {
+	rows, err := DB.Query(`
+		SELECT user_id, store_role FROM account_type_permissions
+		WHERE account_type_id = ?
+		ORDER BY id
+	`, typeID)
+	if err != nil {
+		return nil, fmt.Errorf("查询账务类型权限失败: %v", err)
+	}
+	defer rows.Close()
+
+	permission := &models.AccountTypePermission{
+		AccountTypeID: typeID,
+		UserIDs:       []int64{},
+		StoreRoles:    []models.StoreRole{},
+	}
+	for rows.Next() {
+		var userID *int64
+		var storeRole *string
+		if err := rows.Scan(&userID, &storeRole); err != nil {
+			return nil, err
+		}
+		if userID != nil {
+			permission.UserIDs = append(permission.UserIDs, *userID)
+		}
+		if storeRole != nil && *storeRole != "" {
+			permission.StoreRoles = append(permission.StoreRoles, models.StoreRole(*storeRole))
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	permission.Restricted = len(permission.UserIDs) > 0 || len(permission.StoreRoles) > 0
+	return permission, nil
+}
+
+// SetAccountTypePermission 替换账务类型的查看权限，用户和角色都为空时取消限制
+func SetAccountTypePermission(permission models.AccountTypePermission) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM account_type_permissions WHERE account_type_id = ?", permission.AccountTypeID); err != nil {
+		return fmt.Errorf("清除账务类型权限失败: %v", err)
+	}
+
+	for _, userID := range permission.UserIDs {
+		if _, err := tx.Exec(
+			"INSERT INTO account_type_permissions (account_type_id, user_id) VALUES (?, ?)",
+			permission.AccountTypeID, userID,
+		); err != nil {
+			return fmt.Errorf("保存账务类型用户权限失败: %v", err)
+		}
+	}
+
+	for _, role := range permission.StoreRoles {
+		if _, err := tx.Exec(
+			"INSERT INTO account_type_permissions (account_type_id, store_role) VALUES (?, ?)",
+			permission.AccountTypeID, role,
+		); err != nil {
+			return fmt.Errorf("保存账务类型角色权限失败: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AccountTypeVisibilityFilter 生成按账务类型查看权限过滤的SQL条件，管理员不受限制，返回空条件
+func AccountTypeVisibilityFilter(userID int64, typeColumn, storeColumn string) (string, []interface{}, error) {
+	isAdmin, err := IsUserAdmin(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if isAdmin {
+		return "", nil, nil
+	}
+
+	filter, args := accountTypeVisibilityCondition(userID, typeColumn, storeColumn)
+	return filter, args, nil
+}
+
+// accountTypeVisibilityCondition 生成非管理员用户的账务类型查看权限条件。
+// typeColumn为账务类型ID列；storeColumn为账目所属店铺列，按用户在该店铺的角色匹配，
+// 为空时只要用户在任一店铺拥有授权角色即可
+func accountTypeVisibilityCondition(userID int64, typeColumn, storeColumn string) (string, []interface{}) {
+	storeCondition := ""
+	if storeColumn != "" {
+		storeCondition = " AND usp.store_id = " + storeColumn
+	}
+
+	filter := fmt.Sprintf(`
+		AND (
+			NOT EXISTS (
+				SELECT 1 FROM account_type_permissions atp
+				WHERE atp.account_type_id = %[1]s
+			)
+			OR EXISTS (
+				SELECT 1 FROM account_type_permissions atp
+				WHERE atp.account_type_id = %[1]s
+				AND (
+					atp.user_id = ?
+					OR atp.store_role IN (
+						SELECT usp.role FROM user_store_permissions usp
+						WHERE usp.user_id = ?%[2]s
+					)
+				)
+			)
+		)
+	`, typeColumn, storeCondition)
+
+	return filter, []interface{}{userID, userID}
+}
+
+// IsAccountTypeVisible 检查用户能否在指定店铺查看和使用该账务类型
+func IsAccountTypeVisible(userID, typeID, storeID int64) (bool, error) {
+	filter, args, err := AccountTypeVisibilityFilter(userID, "t.id", fmt.Sprintf("%d", storeID))
+	if err != nil {
+		return false, err
+	}
+
+	var count int
+	err = DB.QueryRow("SELECT COUNT(*) FROM account_types t WHERE t.id = ?"+filter, append([]interface{}{typeID}, args...)...).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("检查账务类型权限失败: %v", err)
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"account/backend/models"
+)
+
+// CreateAttachmentTables 创建账务附件表
+func CreateAttachmentTables() error {
+	_, err := DB.Exec(`
+	CREATE TABLE IF NOT EXISTS account_attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_id INTEGER NOT NULL,
+		file_name TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		storage_key TEXT NOT NULL,
+		thumbnail_key TEXT,
+		uploaded_by INTEGER NOT NULL,
+		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (account_id) REFERENCES accounts(id),
+		FOREIGN KEY (uploaded_by) REFERENCES users(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_account_attachments_account ON account_attachments(account_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("创建账务附件表失败: %v", err)
+	}
+
+	log.Println("账务附件表初始化完成")
+	return nil
+}
+
+// attachmentColumns 查询附件的列，att为account_attachments表的别名
+const attachmentColumns = `
+	att.id, att.account_id, att.file_name, att.content_type, att.size, att.storage_key, COALESCE(att.thumbnail_key, ''),
+	att.uploaded_by, COALESCE(u.username, ''), att.create_time
+	FROM account_attachments att
+	LEFT JOIN users u ON att.uploaded_by = u.id`
+
+// scanAttachment 从查询结果中读取一条附件记录
+func scanAttachment(scanner rowScanner) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := scanner.Scan(
+		&attachment.ID, &attachment.AccountID, &attachment.FileName, &attachment.ContentType, &attachment.Size,
+		&attachment.StorageKey, &attachment.ThumbnailKey, &attachment.UploadedBy, &attachment.UploadedByName, &attachment.CreateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	attachment.HasThumbnail = attachment.ThumbnailKey != ""
+	return &attachment, nil
+}
+
+// queryAttachments 执行附件查询并读取全部结果
+func queryAttachments(query string, args ...interface{}) ([]models.Attachment, error) {
+	rows, err := DB.Query("SELECT "+attachmentColumns+" "+query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询账务附件失败: %v", err)
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取账务附件失败: %v", err)
+		}
+		attachments = append(attachments, *attachment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// CreateAttachment 保存附件记录，返回附件ID
+func CreateAttachment(attachment *models.Attachment) (int64, error) {
+	result, err := DB.Exec(`
+		INSERT INTO account_attachments (account_id, file_name, content_type, size, storage_key, thumbnail_key, uploaded_by, create_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, attachment.AccountID, attachment.FileName, attachment.ContentType, attachment.Size,
+		attachment.StorageKey, attachment.ThumbnailKey, attachment.UploadedBy, attachment.CreateTime)
+	if err != nil {
+		return 0, fmt.Errorf("保存账务附件失败: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+// GetAttachments 获取账务记录的全部附件
+func GetAttachments(accountID int64) ([]models.Attachment, error) {
+	return queryAttachments("WHERE att.account_id = ? ORDER BY att.id", accountID)
+}
+
+// GetAttachment 获取附件，不存在时返回sql.ErrNoRows
+func GetAttachment(id int64) (*models.Attachment, error) {
+	return scanAttachment(DB.QueryRow("SELECT "+attachmentColumns+" WHERE att.id = ?", id))
+}
+
+// DeleteAttachment 删除附件记录，不存在时返回sql.ErrNoRows
+func DeleteAttachment(id int64) error {
+	result, err := DB.Exec("DELETE FROM account_attachments WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("删除账务附件失败: %v", err)
+	}
+	return requireAffected(result)
+}
+
+// GetPurgeableAttachments 获取回收站中将被永久删除的账务记录的附件
+func GetPurgeableAttachments(before time.Time) ([]models.Attachment, error) {
+	return queryAttachments(`
+		WHERE att.account_id IN (
+			SELECT id FROM accounts WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		)
+	`, before)
+}
+
+// GetAttachmentStoreID 获取附件所属账务记录的店铺，附件或账务记录不存在时返回sql.ErrNoRows
+func GetAttachmentStoreID(id int) (int, error) {
+	var storeID int
+	err := DB.QueryRow(`
+		SELECT a.store_id FROM account_attachments att
+		JOIN accounts a ON att.account_id = a.id
+		WHERE att.id = ? AND a.deleted_at IS NULL
+	`, id).Scan(&storeID)
+	return storeID, err
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"account/backend/models"
+)
+
+// CreateAuditLogTables 创建审计日志表
+func CreateAuditLogTables() error {
+	createAuditLogTable := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		entity_type TEXT NOT NULL,
+		entity_id INTEGER NOT NULL DEFAULT 0,
+		store_id INTEGER NOT NULL DEFAULT 0,
+		before_data TEXT,
+		after_data TEXT,
+		ip TEXT,
+		method TEXT,
+		path TEXT,
+		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := DB.Exec(createAuditLogTable); err != nil {
+		return fmt.Errorf("创建审计日志表失败: %v", err)
+	}
+
+	indexes := []string{
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_create_time ON audit_log(create_time)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_store_id ON audit_log(store_id)`,
+	}
+	for _, index := range indexes {
+		if _, err := DB.Exec(index); err != nil {
+			return fmt.Errorf("创建审计日志表索引失败: %v", err)
+		}
+	}
+
+	log.Println("审计日志表初始化完成")
+	return nil
+}
+
+// CreateAuditLog 写入一条审计日志
+func CreateAuditLog(entry models.AuditLog) error {
+	_, err := DB.Exec(`
+		INSERT INTO audit_log (user_id, action, entity_type, entity_id, store_id, before_data, after_data, ip, method, path, create_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.UserID, entry.Action, entry.EntityType, entry.EntityID, entry.StoreID,
+		nullableJSON(entry.Before), nullableJSON(entry.After), entry.IP, entry.Method, entry.Path, time.Now())
+	if err != nil {
+		return fmt.Errorf("写入审计日志失败: %v", err)
+	}
+	return nil
+}
+
+// GetAuditLogs 按条件分页查询审计日志，按时间倒序，返回日志和总条数
+func GetAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, int, error) {
+	where := " WHERE 1=1"
+	var args []interface{}
+
+	if filter.UserID > 0 {
+		where += " AND l.user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	if filter.StoreID > 0 {
+		where += " AND l.store_id = ?"
+		args = append(args, filter.StoreID)
+	}
+	if filter.EntityType != "" {
+		where += " AND l.entity_type = ?"
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID > 0 {
+		where += " AND l.entity_id = ?"
+		args = append(args, filter.EntityID)
+	}
+	if filter.Action != "" {
+		where += " AND l.action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.StartDate != "" {
+		where += " AND l.create_time >= ?"
+		args = append(args, filter.StartDate+" 00:00:00")
+	}
+	if filter.EndDate != "" {
+		where += " AND l.create_time <= ?"
+		args = append(args, filter.EndDate+" 23:59:59")
+	}
+
+	var total int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM audit_log l"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计审计日志失败: %v", err)
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 200 {
+		filter.PageSize = 50
+	}
+
+	query := `
+		SELECT l.id, l.user_id, COALESCE(u.username, CASE WHEN l.user_id = 0 THEN '系统' ELSE '' END), l.action, l.entity_type, l.entity_id, l.store_id,
+			l.before_data, l.after_data, COALESCE(l.ip, ''), COALESCE(l.method, ''), COALESCE(l.path, ''), l.create_time
+		FROM audit_log l
+		LEFT JOIN users u ON l.user_id = u.id
+	` + where + " ORDER BY l.create_time DESC, l.id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询审计日志失败: %v", err)
+	}
+	defer rows.Close()
+
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		var entry models.AuditLog
+		var before, after sql.NullString
+		if err := rows.Scan(
+			&entry.ID, &entry.UserID, &entry.Username, &entry.Action, &entry.EntityType, &entry.EntityID, &entry.StoreID,
+			&before, &after, &entry.IP, &entry.Method, &entry.Path, &entry.CreateTime,
+		); err != nil {
+			return nil, 0, fmt.Errorf("读取审计日志失败: %v", err)
+		}
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		logs = append(logs, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// GetAuditSnapshot 读取审计对象当前的数据。keyColumn为"id"时返回单条记录，
+// 否则返回该列等于id的全部记录；记录不存在时返回nil。密码等敏感列不会返回
+func GetAuditSnapshot(table, keyColumn string, id int64) (interface{}, error) {
+	rows, err := DB.Query(fmt.Sprintf("SELECT * FROM %s WHERE %s = ?", table, keyColumn), id)
+	if err != nil {
+		return nil, fmt.Errorf("读取%s数据失败: %v", table, err)
+	}
+	defer rows.Close()
+
+	records, err := scanAuditRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyColumn != "id" {
+		return records, nil
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0], nil
+}
+
+// scanAuditRecords 将查询结果读取为审计数据，跳过敏感列
+func scanAuditRecords(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	records := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if auditSensitiveColumns[column] {
+				continue
+			}
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			record[column] = values[i]
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// auditSensitiveColumns 不写入审计日志的列
+var auditSensitiveColumns = map[string]bool{
+	"password":           true,
+	"refresh_token_hash": true,
+}
+
+// nullableJSON 空数据保存为NULL
+func nullableJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,311 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"account/backend/models"
+)
+
+// CreateBudgetTables 创建预算表
+func CreateBudgetTables() error {
+	_, err := DB.Exec(`
+	-- 店铺的月度支出预算，type_id为空时表示店铺全部支出类型的总预算
+	CREATE TABLE IF NOT EXISTS budgets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		store_id INTEGER NOT NULL,
+		type_id INTEGER,
+		period TEXT NOT NULL,  -- 格式 2006-01
+		amount INTEGER NOT NULL,
+		note TEXT,
+		created_by INTEGER NOT NULL,
+		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (store_id) REFERENCES stores(id),
+		FOREIGN KEY (type_id) REFERENCES account_types(id)
+	);
+
+	-- 同一店铺同一月份每个类型只能设置一个预算
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_unique ON budgets(store_id, IFNULL(type_id, 0), period);
+	`)
+	if err != nil {
+		return fmt.Errorf("创建预算表失败: %v", err)
+	}
+
+	log.Println("预算表初始化完成")
+	return nil
+}
+
+// budgetSpentSQL 预算所在店铺、类型和月份已发生的支出，换算为店铺的币种，b为budgets表的别名；店铺间转账不计入。
+// accountFilter为账务记录的附加条件，a为accounts表的别名
+func budgetSpentSQL(accountFilter string) string {
+	return fmt.Sprintf(`(
+	SELECT COALESCE(SUM(%s), 0) FROM accounts a
+	JOIN account_types at ON a.type_id = at.id
+	WHERE a.deleted_at IS NULL AND a.transfer_peer_id IS NULL AND at.category = %d
+	AND a.store_id = b.store_id AND (b.type_id IS NULL OR a.type_id = b.type_id)
+	AND %s = b.period%s
+)`, exchangeSQL("a.amount", accountCurrencySQL, budgetCurrencySQL, accountLocalDateSQL), models.AccountCategoryExpense, accountLocalPeriodSQL, accountFilter)
+}
+
+// budgetCurrencySQL 预算的币种，即所在店铺的币种，b为budgets表的别名
+var budgetCurrencySQL = fmt.Sprintf("COALESCE((SELECT bs.currency FROM stores bs WHERE bs.id = b.store_id), '%s')", models.DefaultCurrency)
+
+// budgetSelect 查询预算的语句及参数，以WHERE条件结尾，b为budgets表的别名。
+// 非管理员只能看到有权查看的类型的预算，全部支出的预算也只统计有权查看的类型的支出
+func budgetSelect(userID int64, isAdmin bool) (string, []interface{}) {
+	var accountFilter, budgetFilter string
+	var args []interface{}
+	if !isAdmin {
+		var accountArgs, budgetArgs []interface{}
+		accountFilter, accountArgs = accountTypeVisibilityCondition(userID, "a.type_id", "a.store_id")
+		budgetFilter, budgetArgs = accountTypeVisibilityCondition(userID, "b.type_id", "b.store_id")
+		budgetFilter = " AND (b.type_id IS NULL OR (1=1" + budgetFilter + "))"
+		args = append(accountArgs, budgetArgs...)
+	}
+
+	query := `SELECT
+	b.id, b.store_id, COALESCE(s.name, ''), COALESCE(b.type_id, 0), CASE WHEN b.type_id IS NULL THEN '全部支出' ELSE COALESCE(t.name, '') END,
+	b.period, b.amount,
+	` + budgetSpentSQL(accountFilter) + `, COALESCE(b.note, ''), b.created_by, b.create_time, b.update_time
+	FROM budgets b
+	LEFT JOIN stores s ON b.store_id = s.id
+	LEFT JOIN account_types t ON b.type_id = t.id
+	WHERE 1=1` + budgetFilter
+	return query, args
+}
+
+// scanBudget 从查询结果中读取一条预算
+func scanBudget(scanner rowScanner) (*models.Budget, error) {
+	var budget models.Budget
+	err := scanner.Scan(
+		&budget.ID, &budget.StoreID, &budget.StoreName, &budget.TypeID, &budget.TypeName, &budget.Period, &budget.Amount,
+		&budget.Spent, &budget.Note, &budget.CreatedBy, &budget.CreateTime, &budget.UpdateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// GetBudgets 获取预算列表及各预算已发生的支出。storeIDs为nil时不限店铺，为空时返回空列表；period为空时不限月份。
+// 非管理员只返回有权查看的类型的预算
+func GetBudgets(storeIDs []int64, period string, userID int64, isAdmin bool) ([]models.Budget, error) {
+	budgets := []models.Budget{}
+	if storeIDs != nil && len(storeIDs) == 0 {
+		return budgets, nil
+	}
+
+	query, args := budgetSelect(userID, isAdmin)
+	if storeIDs != nil {
+		query += " AND b.store_id IN (?" + strings.Repeat(", ?", len(storeIDs)-1) + ")"
+		for _, id := range storeIDs {
+			args = append(args, id)
+		}
+	}
+	if period != "" {
+		query += " AND b.period = ?"
+		args = append(args, period)
+	}
+	query += " ORDER BY b.period DESC, b.store_id, b.type_id IS NOT NULL, t.name"
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询预算失败: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取预算失败: %v", err)
+		}
+		budgets = append(budgets, *budget)
+	}
+	return budgets, rows.Err()
+}
+
+// GetBudget 获取预算，不存在或非管理员无权查看该类型时返回sql.ErrNoRows
+func GetBudget(id, userID int64, isAdmin bool) (*models.Budget, error) {
+	query, args := budgetSelect(userID, isAdmin)
+	return scanBudget(DB.QueryRow(query+" AND b.id = ?", append(args, id)...))
+}
+
+// BudgetExists 检查店铺在该月是否已有相同类型的预算，excludeID为修改中的预算ID
+func BudgetExists(storeID, typeID int64, period string, excludeID int64) (bool, error) {
+	var count int
+	err := DB.QueryRow(
+		"SELECT COUNT(*) FROM budgets WHERE store_id = ? AND IFNULL(type_id, 0) = ? AND period = ? AND id != ?",
+		storeID, typeID, period, excludeID,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("检查预算是否存在失败: %v", err)
+	}
+	return count > 0, nil
+}
+
+// budgetTypeID TypeID为0时保存为NULL，表示全部支出类型
+func budgetTypeID(typeID int64) interface{} {
+	if typeID <= 0 {
+		return nil
+	}
+	return typeID
+}
+
+// CreateBudget 创建预算，返回预算ID
+func CreateBudget(budget *models.Budget) (int64, error) {
+	result, err := DB.Exec(`
+		INSERT INTO budgets (store_id, type_id, period, amount, note, created_by, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, budget.StoreID, budgetTypeID(budget.TypeID), budget.Period, budget.Amount, budget.Note, budget.CreatedBy, budget.CreateTime, budget.UpdateTime)
+	if err != nil {
+		return 0, fmt.Errorf("创建预算失败: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateBudget 修改预算，不存在时返回sql.ErrNoRows
+func UpdateBudget(budget *models.Budget) error {
+	result, err := DB.Exec(`
+		UPDATE budgets SET store_id = ?, type_id = ?, period = ?, amount = ?, note = ?, update_time = ?
+		WHERE id = ?
+	`, budget.StoreID, budgetTypeID(budget.TypeID), budget.Period, budget.Amount, budget.Note, budget.UpdateTime, budget.ID)
+	if err != nil {
+		return fmt.Errorf("修改预算失败: %v", err)
+	}
+	return requireAffected(result)
+}
+
+// DeleteBudget 删除预算，不存在时返回sql.ErrNoRows
+func DeleteBudget(id int64) error {
+	result, err := DB.Exec("DELETE FROM budgets WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("删除预算失败: %v", err)
+	}
+	return requireAffected(result)
+}
+
+// GetBudgetStoreID 获取预算所属店铺，预算不存在时返回sql.ErrNoRows
+func GetBudgetStoreID(id int) (int, error) {
+	var storeID int
+	err := DB.QueryRow("SELECT store_id FROM budgets WHERE id = ?", id).Scan(&storeID)
+	return storeID, err
+}
+
+// getBudgetUsages 获取报表时间范围内各类型的预算执行情况。每个月的预算按时间范围覆盖该月的天数折算后合计，
+// 与时间范围内的实际支出对比；实际支出只统计设置了该预算的店铺。storeID为0时合计全部店铺，
+// 非管理员只统计有权查看的账务类型。预算按所在月份第一天的汇率、实际支出按交易日期的汇率换算为currency币种
+func getBudgetUsages(startDate, endDate time.Time, storeID, userID int64, isAdmin bool, currency string) ([]models.BudgetUsage, error) {
+	startPeriod := startDate.Format(models.PeriodLayout)
+	endPeriod := endDate.Format(models.PeriodLayout)
+
+	budgetFilter := " AND b.period BETWEEN ? AND ?"
+	budgetArgs := []interface{}{startPeriod, endPeriod}
+	if storeID > 0 {
+		budgetFilter += " AND b.store_id = ?"
+		budgetArgs = append(budgetArgs, storeID)
+	}
+	var accountFilter string
+	var accountArgs []interface{}
+	if !isAdmin {
+		typeFilter, typeArgs := accountTypeVisibilityCondition(userID, "b.type_id", "b.store_id")
+		budgetFilter += " AND (b.type_id IS NULL OR (1=1" + typeFilter + "))"
+		budgetArgs = append(budgetArgs, typeArgs...)
+		accountFilter, accountArgs = accountTypeVisibilityCondition(userID, "a.type_id", "a.store_id")
+	}
+
+	rows, err := DB.Query(`
+		SELECT COALESCE(b.type_id, 0), COALESCE(t.name, '全部支出'), b.period,
+			COALESCE(SUM(`+exchangeSQL("b.amount", budgetCurrencySQL, currencyLiteral(currency), "b.period || '-01'")+`), 0)
+		FROM budgets b
+		LEFT JOIN account_types t ON b.type_id = t.id
+		WHERE 1=1`+budgetFilter+`
+		GROUP BY COALESCE(b.type_id, 0), b.period
+		ORDER BY b.type_id IS NOT NULL, t.name
+	`, budgetArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("查询预算失败: %w", err)
+	}
+
+	usages := []models.BudgetUsage{}
+	index := map[int64]int{}
+	for rows.Next() {
+		var typeID int64
+		var typeName, period string
+		var amount models.Money
+		if err := rows.Scan(&typeID, &typeName, &period, &amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("读取预算失败: %w", err)
+		}
+		i, ok := index[typeID]
+		if !ok {
+			i = len(usages)
+			index[typeID] = i
+			usages = append(usages, models.BudgetUsage{TypeID: typeID, TypeName: typeName})
+		}
+		usages[i].MonthBudget += amount
+		usages[i].Budget += prorateBudget(amount, period, startDate, endDate)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	startDateStr := startDate.Format(models.DateTimeLayout)
+	endDateStr := endDate.Format(models.DateTimeLayout)
+	for i, usage := range usages {
+		query := `
+			SELECT COALESCE(SUM(` + inCurrency(expenseAmountSQL, currency) + `), 0)
+			FROM accounts a
+			LEFT JOIN account_types t ON a.type_id = t.id
+			WHERE a.deleted_at IS NULL AND ` + accountLocalTimeSQL + ` BETWEEN ? AND ?
+			AND a.store_id IN (SELECT b.store_id FROM budgets b WHERE IFNULL(b.type_id, 0) = ?` + budgetFilter + `)` + accountFilter
+		args := append([]interface{}{startDateStr, endDateStr, usage.TypeID}, budgetArgs...)
+		args = append(args, accountArgs...)
+		if usage.TypeID > 0 {
+			query += " AND a.type_id = ?"
+			args = append(args, usage.TypeID)
+		}
+
+		var actual models.Money
+		if err := DB.QueryRow(query, args...).Scan(&actual); err != nil {
+			return nil, fmt.Errorf("查询预算实际支出失败: %w", err)
+		}
+
+		result := models.NewBudgetUsage(usage.Budget, actual, 0)
+		result.TypeID, result.TypeName = usage.TypeID, usage.TypeName
+		result.MonthBudget = usage.MonthBudget
+		usages[i] = result
+	}
+
+	return usages, nil
+}
+
+// prorateBudget 按startDate到endDate覆盖period月份的天数折算该月的预算，日期按所在时区的日历日计算
+func prorateBudget(amount models.Money, period string, startDate, endDate time.Time) models.Money {
+	month, err := time.Parse(models.PeriodLayout, period)
+	if err != nil {
+		return amount
+	}
+	monthEnd := month.AddDate(0, 1, -1)
+	from := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
+	if from.Before(month) {
+		from = month
+	}
+	if to.After(monthEnd) {
+		to = monthEnd
+	}
+	if to.Before(from) {
+		return 0
+	}
+
+	days := int64(to.Sub(from).Hours()/24) + 1
+	daysInMonth := int64(monthEnd.Day())
+	if days >= daysInMonth {
+		return amount
+	}
+	return models.Money((int64(amount)*days*2 + daysInMonth) / (daysInMonth * 2))
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"account/backend/models"
+)
+
+func TestProrateBudget(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	date := func(value string) time.Time {
+		d, err := time.ParseInLocation(models.DateLayout, value, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name       string
+		amount     models.Money
+		period     string
+		start, end string
+		want       models.Money
+	}{
+		{name: "整月", amount: 310000, period: "2026-10", start: "2026-10-01", end: "2026-10-31", want: 310000},
+		{name: "单日", amount: 310000, period: "2026-10", start: "2026-10-18", end: "2026-10-18", want: 10000},
+		{name: "本月截至今天", amount: 310000, period: "2026-10", start: "2026-10-01", end: "2026-10-18", want: 180000},
+		{name: "跨月的周，前一个月", amount: 300000, period: "2026-09", start: "2026-09-28", end: "2026-10-04", want: 30000},
+		{name: "跨月的周，后一个月", amount: 310000, period: "2026-10", start: "2026-09-28", end: "2026-10-04", want: 40000},
+		{name: "不在范围内的月份", amount: 300000, period: "2026-11", start: "2026-10-01", end: "2026-10-31", want: 0},
+		{name: "覆盖整月的跨年范围", amount: 280000, period: "2026-02", start: "2025-12-15", end: "2026-03-10", want: 280000},
+		{name: "按分四舍五入", amount: 100, period: "2026-10", start: "2026-10-01", end: "2026-10-01", want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 结束时间与报表一致，为结束日期的23:59:59
+			end := date(tt.end).Add(24*time.Hour - time.Second)
+			if got := prorateBudget(tt.amount, tt.period, date(tt.start), end); got != tt.want {
+				t.Errorf("prorateBudget() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"account/backend/models"
+)
+
+// ConsolidatedReport 多店铺合并报表，包含合计、各店铺的收支和趋势，以及按净收入的排名
+type ConsolidatedReport struct {
+	TotalIncome      models.Money   `json:"totalIncome"`
+	TotalExpense     models.Money   `json:"totalExpense"`
+	NetIncome        models.Money   `json:"netIncome"`
+	UnconvertedCount int            `json:"unconvertedCount"` // 缺少汇率、未计入金额的记录数
+	Currency         string         `json:"currency"`         // 报表币种，金额均已换算为该币种
+	StartDate        string         `json:"startDate"`
+	EndDate          string         `json:"endDate"`
+	Granularity      string         `json:"granularity"`
+	Stores           []StoreReport  `json:"stores"`  // 按店铺ID排列
+	Ranking          []StoreRanking `json:"ranking"` // 按净收入从高到低排列
+}
+
+// StoreReport 单个店铺在合并报表中的数据。占比为该店铺占全部店铺合计的百分比，合计为0时为null
+type StoreReport struct {
+	StoreID          int64        `json:"storeId"`
+	StoreName        string       `json:"storeName"`
+	TotalIncome      models.Money `json:"totalIncome"`
+	TotalExpense     models.Money `json:"totalExpense"`
+	NetIncome        models.Money `json:"netIncome"`
+	TransferIn       models.Money `json:"transferIn"`
+	TransferOut      models.Money `json:"transferOut"`
+	UnconvertedCount int          `json:"unconvertedCount"`
+	IncomeShare      *float64     `json:"incomeShare"`
+	ExpenseShare     *float64     `json:"expenseShare"`
+	Trend            []TrendData  `json:"trend"`
+}
+
+// StoreRanking 店铺按净收入的排名，从1开始
+type StoreRanking struct {
+	Rank      int          `json:"rank"`
+	StoreID   int64        `json:"storeId"`
+	StoreName string       `json:"storeName"`
+	NetIncome models.Money `json:"netIncome"`
+}
+
+// GetConsolidatedReport 获取多个店铺的合并报表，storeIDs为空时统计全部店铺。
+// 店铺权限由调用方校验，非管理员只统计有权查看的账务类型；金额按交易日期的汇率换算为currency币种
+func GetConsolidatedReport(startDate, endDate time.Time, storeIDs []int64, userID int64, currency, granularity string) (ConsolidatedReport, error) {
+	report := ConsolidatedReport{
+		Currency:    currency,
+		StartDate:   startDate.Format(models.DateLayout),
+		EndDate:     endDate.Format(models.DateLayout),
+		Granularity: granularity,
+		Stores:      []StoreReport{},
+		Ranking:     []StoreRanking{},
+	}
+
+	isAdmin, err := IsUserAdmin(userID)
+	if err != nil {
+		return report, fmt.Errorf("检查用户权限失败: %w", err)
+	}
+
+	log.Printf("GetConsolidatedReport - 用户ID: %d, 店铺: %v, 日期: %s 到 %s",
+		userID, storeIDs, report.StartDate, report.EndDate)
+
+	stores, err := getReportStores(storeIDs)
+	if err != nil {
+		return report, err
+	}
+	if len(stores) == 0 {
+		return report, nil
+	}
+
+	// 按实际统计的店铺过滤，非管理员只统计有权查看的账务类型
+	storeFilter := " AND a.store_id IN (?" + strings.Repeat(", ?", len(stores)-1) + ")"
+	var args []interface{}
+	index := make(map[int64]int, len(stores))
+	for i, store := range stores {
+		args = append(args, store.StoreID)
+		index[store.StoreID] = i
+	}
+	if !isAdmin {
+		typeFilter, typeArgs := accountTypeVisibilityCondition(userID, "a.type_id", "a.store_id")
+		storeFilter += typeFilter
+		args = append(args, typeArgs...)
+	}
+
+	if err := fillStoreTotals(stores, index, startDate, endDate, storeFilter, args, currency); err != nil {
+		return report, fmt.Errorf("获取店铺总计数据失败: %w", err)
+	}
+	if err := fillStoreTrends(stores, index, startDate, endDate, storeFilter, args, currency, granularity); err != nil {
+		return report, fmt.Errorf("获取店铺趋势数据失败: %w", err)
+	}
+
+	for _, store := range stores {
+		report.TotalIncome += store.TotalIncome
+		report.TotalExpense += store.TotalExpense
+		report.NetIncome += store.NetIncome
+		report.UnconvertedCount += store.UnconvertedCount
+	}
+	for i := range stores {
+		stores[i].IncomeShare = share(stores[i].TotalIncome, report.TotalIncome)
+		stores[i].ExpenseShare = share(stores[i].TotalExpense, report.TotalExpense)
+	}
+	report.Stores = stores
+
+	// 净收入相同的店铺按店铺ID排列
+	for _, store := range stores {
+		report.Ranking = append(report.Ranking, StoreRanking{
+			StoreID:   store.StoreID,
+			StoreName: store.StoreName,
+			NetIncome: store.NetIncome,
+		})
+	}
+	sort.SliceStable(report.Ranking, func(i, j int) bool {
+		return report.Ranking[i].NetIncome > report.Ranking[j].NetIncome
+	})
+	for i := range report.Ranking {
+		report.Ranking[i].Rank = i + 1
+	}
+
+	return report, nil
+}
+
+// getReportStores 获取参与合并报表的店铺，storeIDs为空时返回全部店铺，按店铺ID排列
+func getReportStores(storeIDs []int64) ([]StoreReport, error) {
+	query := "SELECT id, name FROM stores"
+	var args []interface{}
+	if len(storeIDs) > 0 {
+		query += " WHERE id IN (?" + strings.Repeat(", ?", len(storeIDs)-1) + ")"
+		for _, id := range storeIDs {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY id"
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询店铺失败: %w", err)
+	}
+	defer rows.Close()
+
+	var stores []StoreReport
+	for rows.Next() {
+		store := StoreReport{Trend: []TrendData{}}
+		if err := rows.Scan(&store.StoreID, &store.StoreName); err != nil {
+			return nil, fmt.Errorf("读取店铺失败: %w", err)
+		}
+		stores = append(stores, store)
+	}
+	return stores, rows.Err()
+}
+
+// fillStoreTotals 按店铺统计收入、支出、净收入和店铺间转账
+func fillStoreTotals(stores []StoreReport, index map[int64]int, startDate, endDate time.Time, storeFilter string, args []interface{}, currency string) error {
+	query := `
+		SELECT
+			a.store_id,
+			COALESCE(SUM(` + inCurrency(incomeAmountSQL, currency) + `), 0) as income,
+			COALESCE(SUM(` + inCurrency(expenseAmountSQL, currency) + `), 0) as expense,
+			COALESCE(SUM(` + inCurrency(signedAmountSQL, currency) + `), 0) as net,
+			COALESCE(SUM(` + inCurrency(transferInAmountSQL, currency) + `), 0) as transfer_in,
+			COALESCE(SUM(` + inCurrency(transferOutAmountSQL, currency) + `), 0) as transfer_out,
+			` + unconvertedCountSQL(currency) + ` as unconverted
+		FROM accounts a
+		LEFT JOIN account_types t ON a.type_id = t.id
+		WHERE a.deleted_at IS NULL AND ` + accountLocalTimeSQL + ` BETWEEN ? AND ?
+	` + storeFilter + `
+		GROUP BY a.store_id`
+
+	queryArgs := append([]interface{}{startDate.Format(models.DateTimeLayout), endDate.Format(models.DateTimeLayout)}, args...)
+
+	rows, err := DB.Query(query, queryArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var storeID int64
+		var income, expense, net, transferIn, transferOut models.Money
+		var unconverted int
+		if err := rows.Scan(&storeID, &income, &expense, &net, &transferIn, &transferOut, &unconverted); err != nil {
+			return err
+		}
+		i, ok := index[storeID]
+		if !ok {
+			continue
+		}
+		stores[i].TotalIncome = income
+		stores[i].TotalExpense = expense
+		stores[i].NetIncome = net
+		stores[i].TransferIn = transferIn
+		stores[i].TransferOut = transferOut
+		stores[i].UnconvertedCount = unconverted
+	}
+	return rows.Err()
+}
+
+// fillStoreTrends 按店铺统计趋势数据，按granularity分组
+func fillStoreTrends(stores []StoreReport, index map[int64]int, startDate, endDate time.Time, storeFilter string, args []interface{}, currency, granularity string) error {
+	query := `
+		SELECT
+			a.store_id,
+			` + trendBucketSQL(granularity) + ` as date,
+			COALESCE(SUM(` + inCurrency(incomeAmountSQL, currency) + `), 0) as income,
+			-COALESCE(SUM(` + inCurrency(expenseAmountSQL, currency) + `), 0) as expense,
+			COALESCE(SUM(` + inCurrency(signedAmountSQL, currency) + `), 0) as net
+		FROM accounts a
+		LEFT JOIN account_types t ON a.type_id = t.id
+		WHERE a.deleted_at IS NULL AND a.transfer_peer_id IS NULL AND ` + accountLocalTimeSQL + ` BETWEEN ? AND ?
+	` + storeFilter + `
+		GROUP BY a.store_id, date
+		ORDER BY a.store_id, date`
+
+	queryArgs := append([]interface{}{startDate.Format(models.DateTimeLayout), endDate.Format(models.DateTimeLayout)}, args...)
+
+	rows, err := DB.Query(query, queryArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var storeID int64
+		var item TrendData
+		if err := rows.Scan(&storeID, &item.Date, &item.Income, &item.Expense, &item.Net); err != nil {
+			return err
+		}
+		if i, ok := index[storeID]; ok {
+			stores[i].Trend = append(stores[i].Trend, item)
+		}
+	}
+	return rows.Err()
+}
+
+// share 部分占合计的百分比，保留两位小数；合计为0时无法计算，返回nil
+func share(part, total models.Money) *float64 {
+	if total == 0 {
+		return nil
+	}
+	value := math.Round(float64(part)/float64(total)*10000) / 100
+	return &value
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"account/backend/models"
+)
+
+// CreateCurrencyTables 创建汇率表
+func CreateCurrencyTables() error {
+	_, err := DB.Exec(`
+	-- 汇率，1单位from_currency折合rate单位to_currency，从effective_date起生效直到下一条汇率
+	CREATE TABLE IF NOT EXISTS exchange_rates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		from_currency TEXT NOT NULL,
+		to_currency TEXT NOT NULL,
+		rate REAL NOT NULL,
+		effective_date TEXT NOT NULL,  -- 格式 2006-01-02
+		created_by INTEGER NOT NULL,
+		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (from_currency, to_currency, effective_date)
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("创建汇率表失败: %v", err)
+	}
+
+	log.Println("汇率表初始化完成")
+	return nil
+}
+
+// nullableCurrency 未指定币种时保存为NULL，表示使用店铺的币种
+func nullableCurrency(currency string) interface{} {
+	if currency == "" {
+		return nil
+	}
+	return currency
+}
+
+// accountCurrencySQL 账务记录的币种，未指定时使用店铺的币种，a为accounts表的别名
+var accountCurrencySQL = fmt.Sprintf(
+	"COALESCE(a.currency, (SELECT sc.currency FROM stores sc WHERE sc.id = a.store_id), '%s')", models.DefaultCurrency,
+)
+
+// exchangeSQL 返回按date当天有效的汇率将amount从from币种换算为to币种的SQL表达式，结果为分。
+// 参数均为SQL表达式；没有直接汇率时使用反向汇率，都没有时结果为NULL
+func exchangeSQL(amount, from, to, date string) string {
+	return fmt.Sprintf(`(CASE WHEN %[2]s = %[3]s THEN %[1]s ELSE CAST(ROUND(%[1]s * COALESCE(
+		(SELECT er.rate FROM exchange_rates er WHERE er.from_currency = %[2]s AND er.to_currency = %[3]s AND er.effective_date <= %[4]s ORDER BY er.effective_date DESC LIMIT 1),
+		(SELECT 1.0 / er.rate FROM exchange_rates er WHERE er.from_currency = %[3]s AND er.to_currency = %[2]s AND er.effective_date <= %[4]s ORDER BY er.effective_date DESC LIMIT 1)
+	)) AS INTEGER) END)`, amount, from, to, date)
+}
+
+// currencyLiteral 将已校验的币种代码转换为SQL字符串字面量
+func currencyLiteral(currency string) string {
+	return "'" + strings.ReplaceAll(currency, "'", "''") + "'"
+}
+
+// convertedAmountSQL 按交易日期的汇率换算为currency币种后的账务金额，a为accounts表的别名
+func convertedAmountSQL(currency string) string {
+	return exchangeSQL("a.amount", accountCurrencySQL, currencyLiteral(currency), accountLocalDateSQL)
+}
+
+// inCurrency 将统计用的金额SQL片段（以a.amount表示金额）改为换算成currency币种后的金额
+func inCurrency(fragment, currency string) string {
+	return strings.ReplaceAll(fragment, "a.amount", convertedAmountSQL(currency))
+}
+
+// unconvertedCountSQL 统计缺少汇率、无法换算为currency币种的记录数
+func unconvertedCountSQL(currency string) string {
+	return "COALESCE(SUM(CASE WHEN " + convertedAmountSQL(currency) + " IS NULL THEN 1 ELSE 0 END), 0)"
+}
+
+// GetStoreCurrency 获取店铺的币种，店铺不存在时返回sql.ErrNoRows
+func GetStoreCurrency(storeID int64) (string, error) {
+	var currency string
+	err := DB.QueryRow("SELECT currency FROM stores WHERE id = ?", storeID).Scan(&currency)
+	return currency, err
+}
+
+// StoresShareCurrency 检查两个店铺的币种是否相同
+func StoresShareCurrency(storeID, otherStoreID int64) (bool, error) {
+	var count int
+	err := DB.QueryRow("SELECT COUNT(DISTINCT currency) FROM stores WHERE id IN (?, ?)", storeID, otherStoreID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count <= 1, nil
+}
+
+// GetExchangeRates 获取汇率列表，currency不为空时只返回涉及该币种的汇率
+func GetExchangeRates(currency string) ([]models.ExchangeRate, error) {
+	query := `
+		SELECT id, from_currency, to_currency, rate, effective_date, created_by, create_time, update_time
+		FROM exchange_rates`
+	args := []interface{}{}
+	if currency != "" {
+		query += " WHERE from_currency = ? OR to_currency = ?"
+		args = append(args, currency, currency)
+	}
+	query += " ORDER BY from_currency, to_currency, effective_date DESC"
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询汇率失败: %v", err)
+	}
+	defer rows.Close()
+
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		rate, err := scanExchangeRate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取汇率失败: %v", err)
+		}
+		rates = append(rates, *rate)
+	}
+	return rates, rows.Err()
+}
+
+// GetExchangeRate 获取汇率，不存在时返回sql.ErrNoRows
+func GetExchangeRate(id int64) (*models.ExchangeRate, error) {
+	return scanExchangeRate(DB.QueryRow(`
+		SELECT id, from_currency, to_currency, rate, effective_date, created_by, create_time, update_time
+		FROM exchange_rates WHERE id = ?
+	`, id))
+}
+
+// scanExchangeRate 从查询结果中读取一条汇率
+func scanExchangeRate(scanner rowScanner) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	err := scanner.Scan(&rate.ID, &rate.FromCurrency, &rate.ToCurrency, &rate.Rate, &rate.EffectiveDate, &rate.CreatedBy, &rate.CreateTime, &rate.UpdateTime)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// ExchangeRateExists 检查同一币种对在同一天是否已有汇率，excludeID为修改中的汇率ID
+func ExchangeRateExists(from, to, effectiveDate string, excludeID int64) (bool, error) {
+	var count int
+	err := DB.QueryRow(
+		"SELECT COUNT(*) FROM exchange_rates WHERE from_currency = ? AND to_currency = ? AND effective_date = ? AND id != ?",
+		from, to, effectiveDate, excludeID,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("检查汇率是否存在失败: %v", err)
+	}
+	return count > 0, nil
+}
+
+// CreateExchangeRate 创建汇率，返回汇率ID
+func CreateExchangeRate(rate *models.ExchangeRate) (int64, error) {
+	result, err := DB.Exec(`
+		INSERT INTO exchange_rates (from_currency, to_currency, rate, effective_date, created_by, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.EffectiveDate, rate.CreatedBy, rate.CreateTime, rate.UpdateTime)
+	if err != nil {
+		return 0, fmt.Errorf("创建汇率失败: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateExchangeRate 修改汇率，不存在时返回sql.ErrNoRows
+func UpdateExchangeRate(rate *models.ExchangeRate) error {
+	result, err := DB.Exec(`
+		UPDATE exchange_rates SET from_currency = ?, to_currency = ?, rate = ?, effective_date = ?, update_time = ?
+		WHERE id = ?
+	`, rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.EffectiveDate, rate.UpdateTime, rate.ID)
+	if err != nil {
+		return fmt.Errorf("修改汇率失败: %v", err)
+	}
+	return requireAffected(result)
+}
+
+// DeleteExchangeRate 删除汇率，不存在时返回sql.ErrNoRows
+func DeleteExchangeRate(id int64) error {
+	result, err := DB.Exec("DELETE FROM exchange_rates WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("删除汇率失败: %v", err)
+	}
+	return requireAffected(result)
+}
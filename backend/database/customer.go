@@ -6,7 +6,9 @@ import (
 	"log"
 	"math"
 	"os"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -678,6 +680,25 @@ func GetCustomerRecords(customerID int, page int, pageSize int) (map[string]inte
 	return result, nil
 }
 
+// reportTimeLayout 客户报告文件名中的生成时间格式
+const reportTimeLayout = "20060102_150405"
+
+// reportFilePattern 匹配ExportCustomerReport生成的报告文件名 customer_<客户ID>_<生成时间>.pdf
+var reportFilePattern = regexp.MustCompile(`^customer_([1-9][0-9]*)_[0-9]{8}_[0-9]{6}\.pdf$`)
+
+// ReportCustomerID 从报告文件名解析所属客户ID，文件名不是客户报告时返回false
+func ReportCustomerID(filename string) (int, bool) {
+	match := reportFilePattern.FindStringSubmatch(filename)
+	if match == nil {
+		return 0, false
+	}
+	customerID, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return customerID, true
+}
+
 // ExportCustomerReport 导出客户报表，返回报表URL
 func ExportCustomerReport(customerID int) (string, error) {
 	// 检查客户是否存在
@@ -716,7 +737,7 @@ func ExportCustomerReport(customerID int) (string, error) {
 	}
 
 	// 生成报告文件名
-	filename := fmt.Sprintf("customer_%d_%s.pdf", customerID, time.Now().Format("20060102_150405"))
+	filename := fmt.Sprintf("customer_%d_%s.pdf", customerID, time.Now().Format(reportTimeLayout))
 	filePath := fmt.Sprintf("./data/reports/%s", filename)
 
 	// 确保报告目录存在
@@ -0,0 +1,25 @@
+package database
+
+import "testing"
+
+func TestReportCustomerID(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     int
+		ok       bool
+	}{
+		{"customer_12_20240102_150405.pdf", 12, true},
+		{"customer_0_20240102_150405.pdf", 0, false},
+		{"customer_12_20240102.pdf", 0, false},
+		{"customer_12_20240102_150405.pdf.bak", 0, false},
+		{"../customer_12_20240102_150405.pdf", 0, false},
+		{"customer_1_20240102_150405.PDF", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ReportCustomerID(tt.filename)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ReportCustomerID(%q) = %d, %v, want %d, %v", tt.filename, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"account/backend/models"
+)
+
+// CreateIdentityTables 创建第三方身份绑定表
+func CreateIdentityTables() error {
+	createIdentityTable := `
+	CREATE TABLE IF NOT EXISTS user_identities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		provider TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		union_id TEXT,
+		user_id INTEGER,
+		requested_username TEXT,
+		remark TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		review_time TIMESTAMP,
+		reviewed_by INTEGER,
+		UNIQUE (provider, subject),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`
+
+	if _, err := DB.Exec(createIdentityTable); err != nil {
+		return fmt.Errorf("创建身份绑定表失败: %v", err)
+	}
+
+	if _, err := DB.Exec(`CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id)`); err != nil {
+		return fmt.Errorf("创建身份绑定表索引失败: %v", err)
+	}
+
+	log.Println("身份绑定表初始化完成")
+	return nil
+}
+
+const identityColumns = `
+	i.id, i.provider, i.subject, i.union_id, i.user_id, COALESCE(u.username, ''),
+	i.requested_username, i.remark, i.status, i.create_time, i.review_time, i.reviewed_by
+`
+
+// GetIdentity 根据第三方标识查找绑定记录，不存在时返回nil
+func GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	row := DB.QueryRow(`
+		SELECT `+identityColumns+`
+		FROM user_identities i
+		LEFT JOIN users u ON i.user_id = u.id
+		WHERE i.provider = ? AND i.subject = ?
+	`, provider, subject)
+
+	identity, err := scanIdentity(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询身份绑定失败: %v", err)
+	}
+	return identity, nil
+}
+
+// GetIdentityByID 根据ID查找绑定记录，不存在时返回sql.ErrNoRows
+func GetIdentityByID(id int64) (*models.UserIdentity, error) {
+	row := DB.QueryRow(`
+		SELECT `+identityColumns+`
+		FROM user_identities i
+		LEFT JOIN users u ON i.user_id = u.id
+		WHERE i.id = ?
+	`, id)
+	return scanIdentity(row)
+}
+
+// GetIdentities 获取绑定记录列表，status为空时返回全部
+func GetIdentities(provider, status string) ([]models.UserIdentity, error) {
+	query := `
+		SELECT ` + identityColumns + `
+		FROM user_identities i
+		LEFT JOIN users u ON i.user_id = u.id
+		WHERE i.provider = ?
+	`
+	args := []interface{}{provider}
+	if status != "" {
+		query += " AND i.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY i.create_time DESC"
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询身份绑定列表失败: %v", err)
+	}
+	defer rows.Close()
+
+	identities := []models.UserIdentity{}
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取身份绑定数据失败: %v", err)
+		}
+		identities = append(identities, *identity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+// CreatePendingIdentity 登记待审核的绑定申请，重复申请时只更新填写了的申请信息
+func CreatePendingIdentity(identity models.UserIdentity) error {
+	_, err := DB.Exec(`
+		INSERT INTO user_identities (provider, subject, union_id, requested_username, remark, status, create_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(provider, subject) DO UPDATE SET
+			union_id = excluded.union_id,
+			requested_username = COALESCE(NULLIF(excluded.requested_username, ''), user_identities.requested_username),
+			remark = COALESCE(NULLIF(excluded.remark, ''), user_identities.remark)
+		WHERE user_identities.status = ?
+	`, identity.Provider, identity.Subject, identity.UnionID, identity.RequestedUsername, identity.Remark,
+		models.IdentityStatusPending, time.Now(), models.IdentityStatusPending)
+	if err != nil {
+		return fmt.Errorf("登记绑定申请失败: %v", err)
+	}
+	return nil
+}
+
+// BindIdentity 将第三方身份直接绑定到用户，替换该身份原有的绑定
+func BindIdentity(identity models.UserIdentity, userID int64) error {
+	_, err := DB.Exec(`
+		INSERT INTO user_identities (provider, subject, union_id, user_id, status, create_time, review_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(provider, subject) DO UPDATE SET
+			union_id = excluded.union_id,
+			user_id = excluded.user_id,
+			status = excluded.status,
+			review_time = excluded.review_time
+	`, identity.Provider, identity.Subject, identity.UnionID, userID, models.IdentityStatusActive, time.Now(), time.Now())
+	if err != nil {
+		return fmt.Errorf("绑定身份失败: %v", err)
+	}
+	return nil
+}
+
+// ReviewIdentity 管理员审核绑定申请，通过时需要指定绑定的用户，记录不存在或已审核时返回false
+func ReviewIdentity(id int64, status string, userID *int64, reviewerID int64) (bool, error) {
+	result, err := DB.Exec(`
+		UPDATE user_identities SET status = ?, user_id = ?, review_time = ?, reviewed_by = ?
+		WHERE id = ? AND status = ?
+	`, status, userID, time.Now(), reviewerID, id, models.IdentityStatusPending)
+	if err != nil {
+		return false, fmt.Errorf("审核绑定申请失败: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteIdentity 解除绑定，记录不存在时返回false
+func DeleteIdentity(id int64) (bool, error) {
+	result, err := DB.Exec("DELETE FROM user_identities WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("解除绑定失败: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// scanIdentity 从查询结果中读取一条身份绑定记录
+func scanIdentity(scanner rowScanner) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	var unionID, requestedUsername, remark sql.NullString
+	var userID, reviewedBy sql.NullInt64
+	var reviewTime sql.NullTime
+
+	err := scanner.Scan(
+		&identity.ID, &identity.Provider, &identity.Subject, &unionID, &userID, &identity.Username,
+		&requestedUsername, &remark, &identity.Status, &identity.CreateTime, &reviewTime, &reviewedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	identity.UnionID = unionID.String
+	identity.RequestedUsername = requestedUsername.String
+	identity.Remark = remark.String
+	if userID.Valid {
+		identity.UserID = &userID.Int64
+	}
+	if reviewTime.Valid {
+		identity.ReviewTime = &reviewTime.Time
+	}
+	if reviewedBy.Valid {
+		identity.ReviewedBy = &reviewedBy.Int64
+	}
+
+	return &identity, nil
+}
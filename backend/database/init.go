@@ -69,6 +69,7 @@ func ResetDatabase() error {
 		role INTEGER DEFAULT 0, -- 0普通用户，1管理员
 		avatar TEXT,
 		phone TEXT,
+		must_change_password INTEGER NOT NULL DEFAULT 0,
 		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
@@ -79,6 +80,8 @@ func ResetDatabase() error {
 		name TEXT NOT NULL,
 		address TEXT,
 		phone TEXT,
+		currency TEXT NOT NULL DEFAULT 'CNY',
+		timezone TEXT,
 		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
@@ -101,6 +104,7 @@ func ResetDatabase() error {
 		icon TEXT,
 		sort_order INTEGER DEFAULT 0,
 		is_expense BOOLEAN,
+		system_code TEXT,
 		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
@@ -111,11 +115,18 @@ func ResetDatabase() error {
 		store_id INTEGER,
 		user_id INTEGER,
 		type_id INTEGER,
-		amount REAL NOT NULL,
+		amount INTEGER NOT NULL, -- 金额，单位为分
+		currency TEXT,
 		remark TEXT,
 		transaction_time TIMESTAMP,
+		utc_offset INTEGER NOT NULL DEFAULT 0,
 		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		deleted_at TIMESTAMP,
+		deleted_by INTEGER,
+		transfer_peer_id INTEGER,
+		recurring_id INTEGER,
+		recurring_date TEXT,
 		FOREIGN KEY (store_id) REFERENCES stores(id),
 		FOREIGN KEY (user_id) REFERENCES users(id),
 		FOREIGN KEY (type_id) REFERENCES account_types(id)
@@ -170,6 +181,7 @@ func CreateTables() error {
 		role INTEGER NOT NULL DEFAULT 2,
 		phone TEXT,
 		avatar TEXT,
+		must_change_password INTEGER NOT NULL DEFAULT 0,
 		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		last_login TIMESTAMP
@@ -187,6 +199,8 @@ func CreateTables() error {
 		name TEXT NOT NULL,
 		address TEXT,
 		phone TEXT,
+		currency TEXT NOT NULL DEFAULT 'CNY',  -- 店铺记账使用的币种
+		timezone TEXT,  -- IANA时区名称，为空时使用业务时区
 		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	)
@@ -205,6 +219,7 @@ func CreateTables() error {
 		icon TEXT,
 		sort_order INTEGER DEFAULT 0,
 		is_expense BOOLEAN NOT NULL DEFAULT 0,
+		system_code TEXT,  -- 系统内置类型的编码，普通类型为空
 		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	)
@@ -221,11 +236,18 @@ func CreateTables() error {
 		store_id INTEGER NOT NULL,
 		user_id INTEGER DEFAULT 0,  -- 记录人ID，默认为0表示未知用户
 		type_id INTEGER NOT NULL,
-		amount REAL NOT NULL,
+		amount INTEGER NOT NULL, -- 金额，单位为分
+		currency TEXT,  -- 币种，为空时使用店铺的币种
 		remark TEXT,
-		transaction_time TIMESTAMP,
+		transaction_time TIMESTAMP,  -- UTC时间
+		utc_offset INTEGER NOT NULL DEFAULT 0,  -- 交易时刻店铺时区相对UTC的分钟数
 		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		deleted_at TIMESTAMP,  -- 删除时间，为空表示未删除
+		deleted_by INTEGER,
+		transfer_peer_id INTEGER,  -- 店铺间转账时对方店铺的记录ID
+		recurring_id INTEGER,  -- 由周期账务模板生成时的模板ID
+		recurring_date TEXT,  -- 对应模板的生成日期，与recurring_id一起保证不重复生成
 		FOREIGN KEY (store_id) REFERENCES stores(id),
 		FOREIGN KEY (type_id) REFERENCES account_types(id)
 	)
@@ -457,12 +479,80 @@ func CreateTables() error {
 		log.Println("已成功添加user_id列到accounts表")
 	}
 
+	// 早期版本的users表缺少email列，登录和令牌认证需要读取该列
+	if err := addColumnIfNotExists("users", "email", "TEXT"); err != nil {
+		return err
+	}
+
+	// 新建用户和重置密码后需要用户先修改密码
+	if err := addColumnIfNotExists("users", "must_change_password", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// 添加该列前创建的默认账号仍在使用公开的默认密码，同样需要先修改密码
+	if err := requireDefaultPasswordChange(); err != nil {
+		return err
+	}
+
+	// 金额改为以分为单位的整数保存
+	if err := migrateAmountToCents("accounts"); err != nil {
+		return err
+	}
+
+	// 收支方向由账务类型决定，金额统一保存为正数
+	if err := normalizeAccountDirections(); err != nil {
+		return err
+	}
+
+	// 账务记录改为软删除，删除的记录进入回收站
+	if err := addColumnIfNotExists("accounts", "deleted_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists("accounts", "deleted_by", "INTEGER"); err != nil {
+		return err
+	}
+
+	// 店铺间转账由两条互相关联的记录组成，使用系统内置的转入、转出类型
+	if err := addColumnIfNotExists("accounts", "transfer_peer_id", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists("account_types", "system_code", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureTransferAccountTypes(); err != nil {
+		return err
+	}
+
+	// 周期账务模板生成的记录，记录来源模板和生成日期
+	if err := addColumnIfNotExists("accounts", "recurring_id", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists("accounts", "recurring_date", "TEXT"); err != nil {
+		return err
+	}
+
+	// 店铺可以使用不同币种记账，单条记录也可以单独指定币种
+	if err := addColumnIfNotExists("stores", "currency", "TEXT NOT NULL DEFAULT 'CNY'"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists("accounts", "currency", "TEXT"); err != nil {
+		return err
+	}
+
+	// 店铺可以单独设置时区，交易时间统一以UTC保存
+	if err := addColumnIfNotExists("stores", "timezone", "TEXT"); err != nil {
+		return err
+	}
+	if err := migrateTimesToUTC("accounts", "create_time", "update_time", "deleted_at"); err != nil {
+		return err
+	}
+
 	// 创建用户店铺权限表
 	_, err = DB.Exec(`
 	CREATE TABLE IF NOT EXISTS user_store_permissions (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		user_id INTEGER NOT NULL,
 		store_id INTEGER NOT NULL,
+		role TEXT NOT NULL DEFAULT 'manager',
 		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (user_id) REFERENCES users(id),
@@ -475,6 +565,11 @@ func CreateTables() error {
 		return fmt.Errorf("创建用户店铺权限表失败: %w", err)
 	}
 
+	// 店铺权限增加店内角色，已有的分配默认为店长，保持原有权限不变
+	if err := addColumnIfNotExists("user_store_permissions", "role", "TEXT NOT NULL DEFAULT 'manager'"); err != nil {
+		return err
+	}
+
 	// 创建用户默认设置表
 	_, err = DB.Exec(`
 	CREATE TABLE IF NOT EXISTS user_default_settings (
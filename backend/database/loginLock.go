@@ -0,0 +1,167 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"account/backend/models"
+)
+
+// CreateLoginLockTables 创建登录失败记录表
+func CreateLoginLockTables() error {
+	createLoginLockTable := `
+	CREATE TABLE IF NOT EXISTS login_locks (
+		scope TEXT NOT NULL,
+		lock_key TEXT NOT NULL,
+		failed_count INTEGER NOT NULL DEFAULT 0,
+		last_failed_at TIMESTAMP NOT NULL,
+		locked_until TIMESTAMP,
+		PRIMARY KEY (scope, lock_key)
+	);`
+
+	if _, err := DB.Exec(createLoginLockTable); err != nil {
+		return fmt.Errorf("创建登录失败记录表失败: %v", err)
+	}
+
+	log.Println("登录失败记录表初始化完成")
+	return nil
+}
+
+// GetLoginLock 获取用户名或IP的登录失败记录，没有记录时返回nil
+func GetLoginLock(scope, key string) (*models.LoginLock, error) {
+	row := DB.QueryRow(`
+		SELECT scope, lock_key, failed_count, last_failed_at, locked_until
+		FROM login_locks
+		WHERE scope = ? AND lock_key = ?
+	`, scope, key)
+
+	lock, err := scanLoginLock(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询登录失败记录失败: %v", err)
+	}
+	return lock, nil
+}
+
+// UpdateLoginLocks 在同一事务中读取keys对应的登录失败记录（没有记录时为nil），保存update返回的记录。
+// 事务开始时即获取写锁，并发的登录请求依次读写，失败次数不会互相覆盖
+func UpdateLoginLocks(keys []models.LoginLockKey, update func(locks []*models.LoginLock) []models.LoginLock) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	locks := make([]*models.LoginLock, len(keys))
+	for i, key := range keys {
+		lock, err := scanLoginLock(tx.QueryRow(`
+			SELECT scope, lock_key, failed_count, last_failed_at, locked_until
+			FROM login_locks
+			WHERE scope = ? AND lock_key = ?
+		`, key.Scope, key.Key))
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("查询登录失败记录失败: %v", err)
+		}
+		locks[i] = lock
+	}
+
+	for _, lock := range update(locks) {
+		if err := saveLoginLock(tx, lock); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ReleaseLoginAttempt 撤销预先计入的一次失败，撤销后未达到maxAttempts时同时解除该次计数造成的锁定
+func ReleaseLoginAttempt(key models.LoginLockKey, maxAttempts int) error {
+	_, err := DB.Exec(`
+		UPDATE login_locks SET
+			failed_count = MAX(failed_count - 1, 0),
+			locked_until = CASE WHEN failed_count - 1 < ? THEN NULL ELSE locked_until END
+		WHERE scope = ? AND lock_key = ?
+	`, maxAttempts, key.Scope, key.Key)
+	if err != nil {
+		return fmt.Errorf("撤销登录失败次数失败: %v", err)
+	}
+	return nil
+}
+
+// saveLoginLock 保存登录失败次数和锁定截止时间
+func saveLoginLock(tx *sql.Tx, lock models.LoginLock) error {
+	_, err := tx.Exec(`
+		INSERT INTO login_locks (scope, lock_key, failed_count, last_failed_at, locked_until)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(scope, lock_key) DO UPDATE SET
+			failed_count = excluded.failed_count,
+			last_failed_at = excluded.last_failed_at,
+			locked_until = excluded.locked_until
+	`, lock.Scope, lock.Key, lock.FailedCount, lock.LastFailedAt, lock.LockedUntil)
+	if err != nil {
+		return fmt.Errorf("保存登录失败记录失败: %v", err)
+	}
+	return nil
+}
+
+// ClearLoginLock 清除登录失败记录并解除锁定，记录不存在时返回false
+func ClearLoginLock(scope, key string) (bool, error) {
+	result, err := DB.Exec("DELETE FROM login_locks WHERE scope = ? AND lock_key = ?", scope, key)
+	if err != nil {
+		return false, fmt.Errorf("清除登录失败记录失败: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// GetLoginLocks 获取所有登录失败记录，锁定中的排在前面
+func GetLoginLocks() ([]models.LoginLock, error) {
+	rows, err := DB.Query(`
+		SELECT scope, lock_key, failed_count, last_failed_at, locked_until
+		FROM login_locks
+		ORDER BY locked_until IS NULL, locked_until DESC, last_failed_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询登录失败记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	locks := []models.LoginLock{}
+	for rows.Next() {
+		lock, err := scanLoginLock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取登录失败记录失败: %v", err)
+		}
+		locks = append(locks, *lock)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locks, nil
+}
+
+// scanLoginLock 从查询结果中读取一条登录失败记录，并根据当前时间计算锁定状态
+func scanLoginLock(scanner rowScanner) (*models.LoginLock, error) {
+	var lock models.LoginLock
+	var lockedUntil sql.NullTime
+
+	if err := scanner.Scan(&lock.Scope, &lock.Key, &lock.FailedCount, &lock.LastFailedAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+
+	if lockedUntil.Valid {
+		lock.LockedUntil = &lockedUntil.Time
+		lock.Locked = lockedUntil.Time.After(time.Now())
+	}
+
+	return &lock, nil
+}
@@ -1,8 +1,13 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
+	"strings"
+
+	"account/backend/models"
+	"account/backend/utils"
 )
 
 // CheckAndMigrateTables 检查并迁移缺少的表和列
@@ -273,3 +278,166 @@ func CheckAndMigrateTables() error {
 	log.Println("数据库迁移完成")
 	return nil
 }
+
+// addColumnIfNotExists 当表中缺少指定列时执行ALTER TABLE添加该列
+func addColumnIfNotExists(table, column, definition string) error {
+	var exists bool
+	err := DB.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info(?)
+		WHERE name = ?
+	`, table, column).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("检查%s表的%s列失败: %w", table, column, err)
+	}
+
+	if exists {
+		return nil
+	}
+
+	log.Printf("向%s表添加%s列...", table, column)
+	if _, err := DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("添加%s列失败: %w", column, err)
+	}
+	log.Printf("%s列添加成功", column)
+	return nil
+}
+
+// defaultAccountPasswords 初始化和测试数据创建的默认账号及其密码
+var defaultAccountPasswords = []struct {
+	username string
+	password string
+}{
+	{"admin", "admin123"},
+	{"clerk", "clerk123"},
+	{"staff1", "staff123"},
+	{"staff2", "staff123"},
+}
+
+// requireDefaultPasswordChange 默认账号的密码仍是默认密码时要求登录后先修改密码，
+// 已经要求修改或已改过密码的账号不受影响
+func requireDefaultPasswordChange() error {
+	for _, account := range defaultAccountPasswords {
+		var id int64
+		var hashedPassword string
+		err := DB.QueryRow(
+			"SELECT id, password FROM users WHERE username = ? AND must_change_password = 0", account.username,
+		).Scan(&id, &hashedPassword)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("检查默认账号%s失败: %w", account.username, err)
+		}
+		if utils.CompareHashAndPassword([]byte(hashedPassword), []byte(account.password)) != nil {
+			continue
+		}
+		if _, err := DB.Exec("UPDATE users SET must_change_password = 1 WHERE id = ?", id); err != nil {
+			return fmt.Errorf("设置默认账号%s修改密码失败: %w", account.username, err)
+		}
+		log.Printf("默认账号%s仍在使用默认密码，已要求登录后修改密码", account.username)
+	}
+	return nil
+}
+
+// migrateAmountToCents 将早期版本以REAL保存的amount列改为以分为单位的INTEGER，
+// 按四舍五入换算已有数据，已经是INTEGER时不做处理。乘以100后先保留6位小数消除浮点误差再取整，
+// 否则1.015*100为101.49999999999999，会被舍为101
+func migrateAmountToCents(table string) error {
+	var columnType string
+	err := DB.QueryRow(`SELECT type FROM pragma_table_info(?) WHERE name = 'amount'`, table).Scan(&columnType)
+	if err != nil {
+		return fmt.Errorf("检查%s表的amount列失败: %w", table, err)
+	}
+	if strings.EqualFold(columnType, "INTEGER") {
+		return nil
+	}
+
+	log.Printf("将%s表的金额转换为以分为单位的整数...", table)
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME COLUMN amount TO amount_real", table),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN amount INTEGER NOT NULL DEFAULT 0", table),
+		fmt.Sprintf("UPDATE %s SET amount = CAST(ROUND(ROUND(amount_real * 100, 6)) AS INTEGER)", table),
+		fmt.Sprintf("ALTER TABLE %s DROP COLUMN amount_real", table),
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("转换%s表金额失败: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("%s表金额转换完成", table)
+	return nil
+}
+
+// normalizeAccountDirections 收支方向改由账务类型决定：同步账务类型的is_expense与category，
+// 并将早期以负数表示支出的金额改为正数保存。收入类型或没有类型的负数记录可能是退款或冲销，
+// 转为正数会改变历史合计，因此保持不变并在日志中列出
+func normalizeAccountDirections() error {
+	if _, err := DB.Exec(`
+		UPDATE account_types SET category = CASE WHEN is_expense THEN ? ELSE ? END
+		WHERE category NOT IN (?, ?) OR category IS NULL
+	`, models.AccountCategoryExpense, models.AccountCategoryIncome, models.AccountCategoryIncome, models.AccountCategoryExpense); err != nil {
+		return fmt.Errorf("修正账务类型收支方向失败: %w", err)
+	}
+	if _, err := DB.Exec(`
+		UPDATE account_types SET is_expense = (category = ?)
+		WHERE is_expense IS NULL OR is_expense != (category = ?)
+	`, models.AccountCategoryExpense, models.AccountCategoryExpense); err != nil {
+		return fmt.Errorf("同步账务类型is_expense失败: %w", err)
+	}
+
+	result, err := DB.Exec(`
+		UPDATE accounts SET amount = -amount
+		WHERE amount < 0 AND type_id IN (SELECT id FROM account_types WHERE category = ?)
+	`, models.AccountCategoryExpense)
+	if err != nil {
+		return fmt.Errorf("转换账务记录金额失败: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		log.Printf("已将%d条支出类型账务记录的金额转为正数", affected)
+	}
+
+	mismatched, err := negativeIncomeAccountIDs()
+	if err != nil {
+		return fmt.Errorf("检查账务记录收支方向失败: %w", err)
+	}
+	if len(mismatched) > 0 {
+		log.Printf("警告: %d条收入类型或没有类型的账务记录金额为负数，未做修改，将按负数收入统计，请人工核对: %v",
+			len(mismatched), mismatched)
+	}
+	return nil
+}
+
+// negativeIncomeAccountIDs 获取金额为负数的收入类型或没有类型的账务记录ID
+func negativeIncomeAccountIDs() ([]int64, error) {
+	rows, err := DB.Query(`
+		SELECT a.id FROM accounts a
+		LEFT JOIN account_types t ON a.type_id = t.id
+		WHERE a.amount < 0 AND COALESCE(t.category, 0) != ?
+		ORDER BY a.id
+	`, models.AccountCategoryExpense)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"account/backend/utils"
+)
+
+// useTestDB 使用内存数据库替换全局DB，测试结束后恢复
+func useTestDB(t *testing.T) {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接各自独立，只保留一个连接
+	db.SetMaxOpenConns(1)
+
+	previous := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = previous
+		db.Close()
+	})
+}
+
+func TestMigrateAmountToCents(t *testing.T) {
+	useTestDB(t)
+
+	if _, err := DB.Exec(`CREATE TABLE accounts (id INTEGER PRIMARY KEY, store_id INTEGER, amount REAL NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	rows := []struct {
+		storeID int
+		amount  float64
+		want    int64
+	}{
+		{1, 0.1, 10},
+		{1, 0.2, 20},
+		{1, 0.3, 30},
+		{1, 19.99, 1999}, // 19.99*100为1998.9999999999998
+		{1, 1.015, 102},  // 1.015*100为101.49999999999999，仍应进位
+		{1, 0.285, 29},   // 0.285*100为28.499999999999996
+		{1, 2.004, 200},  // 超过两位小数时四舍五入
+		{2, -12.35, -1235},
+		{2, 1234567.89, 123456789},
+		{2, -0.005, -1}, // 负数的一半向远离0的方向舍入
+		{2, 0, 0},
+	}
+	for _, row := range rows {
+		if _, err := DB.Exec("INSERT INTO accounts (store_id, amount) VALUES (?, ?)", row.storeID, row.amount); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrateAmountToCents("accounts"); err != nil {
+		t.Fatalf("migrateAmountToCents() error = %v", err)
+	}
+
+	var columnType string
+	if err := DB.QueryRow(`SELECT type FROM pragma_table_info('accounts') WHERE name = 'amount'`).Scan(&columnType); err != nil {
+		t.Fatal(err)
+	}
+	if columnType != "INTEGER" {
+		t.Errorf("amount列类型 = %s, want INTEGER", columnType)
+	}
+	var oldColumns int
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('accounts') WHERE name = 'amount_real'`).Scan(&oldColumns); err != nil {
+		t.Fatal(err)
+	}
+	if oldColumns != 0 {
+		t.Error("amount_real列未删除")
+	}
+
+	wantSums := map[int]int64{}
+	for i, row := range rows {
+		var got int64
+		if err := DB.QueryRow("SELECT amount FROM accounts WHERE id = ?", i+1).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != row.want {
+			t.Errorf("第%d条金额 %v 转换为 %d, want %d", i+1, row.amount, got, row.want)
+		}
+		wantSums[row.storeID] += row.want
+	}
+
+	// 按店铺合计与逐条换算后的合计一致，不受浮点误差影响
+	result, err := DB.Query("SELECT store_id, SUM(amount) FROM accounts GROUP BY store_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Close()
+	for result.Next() {
+		var storeID int
+		var sum int64
+		if err := result.Scan(&storeID, &sum); err != nil {
+			t.Fatal(err)
+		}
+		if sum != wantSums[storeID] {
+			t.Errorf("店铺%d合计 = %d, want %d", storeID, sum, wantSums[storeID])
+		}
+	}
+
+	// 已经是INTEGER时再次执行不做处理
+	if err := migrateAmountToCents("accounts"); err != nil {
+		t.Fatalf("再次执行migrateAmountToCents() error = %v", err)
+	}
+	var total int64
+	if err := DB.QueryRow("SELECT SUM(amount) FROM accounts").Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+	if total != wantSums[1]+wantSums[2] {
+		t.Errorf("再次执行后合计 = %d, want %d", total, wantSums[1]+wantSums[2])
+	}
+}
+
+func TestRequireDefaultPasswordChange(t *testing.T) {
+	useTestDB(t)
+
+	if _, err := DB.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT, password TEXT, must_change_password INTEGER NOT NULL DEFAULT 0)`); err != nil {
+		t.Fatal(err)
+	}
+	users := []struct {
+		username string
+		password string
+		want     int
+	}{
+		{"admin", "admin123", 1},     // 仍是默认密码
+		{"clerk", "Changed#2024", 0}, // 已修改密码
+		{"staff1", "staff123", 1},
+		{"alice", "admin123", 0}, // 不是默认账号
+	}
+	for _, user := range users {
+		hashedPassword, err := utils.HashPassword(user.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DB.Exec("INSERT INTO users (username, password) VALUES (?, ?)", user.username, hashedPassword); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := requireDefaultPasswordChange(); err != nil {
+		t.Fatalf("requireDefaultPasswordChange() error = %v", err)
+	}
+
+	for _, user := range users {
+		var got int
+		if err := DB.QueryRow("SELECT must_change_password FROM users WHERE username = ?", user.username).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != user.want {
+			t.Errorf("%s must_change_password = %d, want %d", user.username, got, user.want)
+		}
+	}
+}
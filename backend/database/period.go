@@ -0,0 +1,228 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"account/backend/models"
+)
+
+// CreatePeriodTables 创建结账相关的表
+func CreatePeriodTables() error {
+	_, err := DB.Exec(`
+	-- 店铺已结账的月份，结账后不能新增、修改或删除该月的账务记录
+	CREATE TABLE IF NOT EXISTS closed_periods (
+		store_id INTEGER NOT NULL,
+		period TEXT NOT NULL,  -- 格式 2006-01
+		closed_by INTEGER NOT NULL,
+		closed_at TIMESTAMP NOT NULL,
+		note TEXT,
+		PRIMARY KEY (store_id, period),
+		FOREIGN KEY (store_id) REFERENCES stores(id)
+	);
+
+	-- 结账和重新开账的操作记录
+	CREATE TABLE IF NOT EXISTS period_close_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		store_id INTEGER NOT NULL,
+		period TEXT NOT NULL,
+		action TEXT NOT NULL,
+		user_id INTEGER NOT NULL,
+		note TEXT,
+		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (store_id) REFERENCES stores(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_period_close_log_store ON period_close_log(store_id, period);
+	`)
+	if err != nil {
+		return fmt.Errorf("创建结账表失败: %v", err)
+	}
+
+	log.Println("结账表初始化完成")
+	return nil
+}
+
+// queryer 兼容*sql.DB和*sql.Tx的单行查询接口
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// checkPeriodOpen 检查店铺在交易时间所在的月份是否已结账，已结账时返回*models.PeriodClosedError
+func checkPeriodOpen(q queryer, storeID int64, transactionTime string) error {
+	period := transactionTime
+	if len(period) > len(models.PeriodLayout) {
+		period = period[:len(models.PeriodLayout)]
+	}
+
+	var count int
+	err := q.QueryRow("SELECT COUNT(*) FROM closed_periods WHERE store_id = ? AND period = ?", storeID, period).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("检查结账状态失败: %v", err)
+	}
+	if count > 0 {
+		return &models.PeriodClosedError{StoreID: storeID, Period: period}
+	}
+	return nil
+}
+
+// checkAccountPeriodOpen 检查账务记录及其转账对方记录所在的月份是否已结账，包括回收站中的记录
+func checkAccountPeriodOpen(q queryer, accountID int64) error {
+	var storeID int64
+	var period string
+	err := q.QueryRow(`
+		SELECT a.store_id, cp.period FROM accounts a
+		JOIN closed_periods cp ON cp.store_id = a.store_id AND cp.period = `+accountLocalPeriodSQL+`
+		WHERE a.id = ? OR a.transfer_peer_id = ?
+		LIMIT 1
+	`, accountID, accountID).Scan(&storeID, &period)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("检查结账状态失败: %v", err)
+	}
+	return &models.PeriodClosedError{StoreID: storeID, Period: period}
+}
+
+// CheckPeriodOpen 检查店铺在交易时间（格式2006-01-02 15:04:05）所在的月份是否已结账，已结账时返回*models.PeriodClosedError
+func CheckPeriodOpen(storeID int64, transactionTime string) error {
+	return checkPeriodOpen(DB, storeID, transactionTime)
+}
+
+// GetClosedPeriods 获取已结账的月份，storeID为0时返回全部店铺；userID不为0时只返回该用户有权限店铺的月份
+func GetClosedPeriods(storeID, userID int64) ([]models.ClosedPeriod, error) {
+	query := `
+		SELECT cp.store_id, COALESCE(s.name, ''), cp.period, cp.closed_by, COALESCE(u.username, ''), cp.closed_at, COALESCE(cp.note, '')
+		FROM closed_periods cp
+		LEFT JOIN stores s ON cp.store_id = s.id
+		LEFT JOIN users u ON cp.closed_by = u.id
+		WHERE 1=1`
+	args := []interface{}{}
+	if storeID > 0 {
+		query += " AND cp.store_id = ?"
+		args = append(args, storeID)
+	}
+	if userID > 0 {
+		query += " AND cp.store_id IN (SELECT store_id FROM user_store_permissions WHERE user_id = ?)"
+		args = append(args, userID)
+	}
+	query += " ORDER BY cp.store_id, cp.period DESC"
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询已结账月份失败: %v", err)
+	}
+	defer rows.Close()
+
+	periods := []models.ClosedPeriod{}
+	for rows.Next() {
+		var p models.ClosedPeriod
+		if err := rows.Scan(&p.StoreID, &p.StoreName, &p.Period, &p.ClosedBy, &p.ClosedByName, &p.ClosedAt, &p.Note); err != nil {
+			return nil, fmt.Errorf("读取已结账月份失败: %v", err)
+		}
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
+
+// ClosePeriod 结账并记录操作，返回操作记录ID；该月已结账时changed为false
+func ClosePeriod(storeID int64, period string, userID int64, note string) (logID int64, changed bool, err error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(
+		"INSERT OR IGNORE INTO closed_periods (store_id, period, closed_by, closed_at, note) VALUES (?, ?, ?, ?, ?)",
+		storeID, period, userID, now, note,
+	)
+	if err != nil {
+		return 0, false, fmt.Errorf("结账失败: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return 0, false, err
+	}
+
+	logID, err = insertPeriodCloseLog(tx, storeID, period, models.PeriodActionClose, userID, note, now)
+	if err != nil {
+		return 0, false, err
+	}
+	return logID, true, tx.Commit()
+}
+
+// ReopenPeriod 重新开账并记录操作，返回操作记录ID；该月未结账时changed为false
+func ReopenPeriod(storeID int64, period string, userID int64, note string) (logID int64, changed bool, err error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM closed_periods WHERE store_id = ? AND period = ?", storeID, period)
+	if err != nil {
+		return 0, false, fmt.Errorf("重新开账失败: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return 0, false, err
+	}
+
+	logID, err = insertPeriodCloseLog(tx, storeID, period, models.PeriodActionReopen, userID, note, time.Now())
+	if err != nil {
+		return 0, false, err
+	}
+	return logID, true, tx.Commit()
+}
+
+// insertPeriodCloseLog 写入结账操作记录
+func insertPeriodCloseLog(tx *sql.Tx, storeID int64, period, action string, userID int64, note string, at time.Time) (int64, error) {
+	result, err := tx.Exec(
+		"INSERT INTO period_close_log (store_id, period, action, user_id, note, create_time) VALUES (?, ?, ?, ?, ?, ?)",
+		storeID, period, action, userID, note, at,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("记录结账操作失败: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+// GetPeriodCloseLogs 分页获取结账和重新开账的操作记录，storeID为0时不限店铺
+func GetPeriodCloseLogs(storeID int64, page, limit int) ([]models.PeriodCloseLog, int, error) {
+	where := ""
+	args := []interface{}{}
+	if storeID > 0 {
+		where = " WHERE l.store_id = ?"
+		args = append(args, storeID)
+	}
+
+	var total int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM period_close_log l"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("查询结账记录失败: %v", err)
+	}
+
+	rows, err := DB.Query(`
+		SELECT l.id, l.store_id, COALESCE(s.name, ''), l.period, l.action, l.user_id, COALESCE(u.username, ''), COALESCE(l.note, ''), l.create_time
+		FROM period_close_log l
+		LEFT JOIN stores s ON l.store_id = s.id
+		LEFT JOIN users u ON l.user_id = u.id`+where+`
+		ORDER BY l.id DESC LIMIT ? OFFSET ?
+	`, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询结账记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	logs := []models.PeriodCloseLog{}
+	for rows.Next() {
+		var l models.PeriodCloseLog
+		if err := rows.Scan(&l.ID, &l.StoreID, &l.StoreName, &l.Period, &l.Action, &l.UserID, &l.Username, &l.Note, &l.CreateTime); err != nil {
+			return nil, 0, fmt.Errorf("读取结账记录失败: %v", err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, total, rows.Err()
+}
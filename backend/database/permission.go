@@ -1,20 +1,27 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
+	"strings"
+
+	"account/backend/models"
 )
 
-// UserHasAllStoresAccess 检查用户是否有访问所有店铺的权限
-func UserHasAllStoresAccess(userID int) (bool, error) {
-	// 检查用户角色
+// IsUserAdmin 检查用户是否为管理员
+func IsUserAdmin(userID int64) (bool, error) {
 	var role int
 	err := DB.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
 	if err != nil {
 		return false, fmt.Errorf("查询用户角色失败: %v", err)
 	}
+	return role == RoleAdmin, nil
+}
 
-	// 角色为1表示管理员，有权访问所有店铺
-	return role == 1, nil
+// UserHasAllStoresAccess 检查用户是否有访问所有店铺的权限
+func UserHasAllStoresAccess(userID int) (bool, error) {
+	// 管理员有权访问所有店铺
+	return IsUserAdmin(int64(userID))
 }
 
 // GetStoreIDsForUser 获取用户有权限访问的店铺ID列表
@@ -56,9 +63,13 @@ func UserHasStorePermission(userID, storeID int) (bool, error) {
 		return true, nil
 	}
 
-	// 检查用户是否有访问该店铺的权限
+	return IsStoreAssignedToUser(userID, storeID)
+}
+
+// IsStoreAssignedToUser 检查店铺是否分配给了该用户，不考虑管理员角色
+func IsStoreAssignedToUser(userID, storeID int) (bool, error) {
 	var count int
-	err = DB.QueryRow(`
+	err := DB.QueryRow(`
 		SELECT COUNT(*) FROM user_store_permissions 
 		WHERE user_id = ? AND store_id = ?
 	`, userID, storeID).Scan(&count)
@@ -68,3 +79,110 @@ func UserHasStorePermission(userID, storeID int) (bool, error) {
 
 	return count > 0, nil
 }
+
+// GetUserStoreRole 获取用户在店铺内的角色，未分配该店铺时第二个返回值为false
+func GetUserStoreRole(userID, storeID int) (models.StoreRole, bool, error) {
+	var role models.StoreRole
+	err := DB.QueryRow(`
+		SELECT role FROM user_store_permissions
+		WHERE user_id = ? AND store_id = ?
+	`, userID, storeID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("查询用户店铺角色失败: %v", err)
+	}
+	return role, true, nil
+}
+
+// GetStoreIDsWithCapability 获取用户拥有指定能力的店铺ID列表
+func GetStoreIDsWithCapability(userID int, capability models.Capability) ([]int, error) {
+	rows, err := DB.Query(`
+		SELECT store_id, role FROM user_store_permissions
+		WHERE user_id = ?
+		ORDER BY store_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户店铺权限失败: %v", err)
+	}
+	defer rows.Close()
+
+	var storeIDs []int
+	for rows.Next() {
+		var storeID int
+		var role models.StoreRole
+		if err := rows.Scan(&storeID, &role); err != nil {
+			return nil, err
+		}
+		if role.Can(capability) {
+			storeIDs = append(storeIDs, storeID)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return storeIDs, nil
+}
+
+// GetCustomerStoreID 获取客户所属店铺，客户不存在时返回sql.ErrNoRows
+func GetCustomerStoreID(customerID int) (int, error) {
+	var storeID int
+	err := DB.QueryRow("SELECT store_id FROM customers WHERE id = ?", customerID).Scan(&storeID)
+	return storeID, err
+}
+
+// GetAccountStoreID 获取账目所属店铺，账目不存在或已删除时返回sql.ErrNoRows
+func GetAccountStoreID(accountID int) (int, error) {
+	var storeID int
+	err := DB.QueryRow("SELECT store_id FROM accounts WHERE id = ? AND deleted_at IS NULL", accountID).Scan(&storeID)
+	return storeID, err
+}
+
+// GetProductStoreID 获取产品所属店铺，产品不存在时返回sql.ErrNoRows
+func GetProductStoreID(productID int) (int, error) {
+	var storeID int
+	err := DB.QueryRow("SELECT store_id FROM products WHERE id = ?", productID).Scan(&storeID)
+	return storeID, err
+}
+
+// GetWeightRecordStoreID 获取体重记录对应客户所属的店铺
+func GetWeightRecordStoreID(recordID int) (int, error) {
+	var storeID int
+	err := DB.QueryRow(`
+		SELECT c.store_id FROM weight_records wr
+		INNER JOIN customers c ON wr.customer_id = c.id
+		WHERE wr.id = ?
+	`, recordID).Scan(&storeID)
+	return storeID, err
+}
+
+// GetProductUsageStoreID 获取产品使用记录对应客户所属的店铺
+func GetProductUsageStoreID(usageID int) (int, error) {
+	var storeID int
+	err := DB.QueryRow(`
+		SELECT c.store_id FROM product_usages pu
+		INNER JOIN customers c ON pu.customer_id = c.id
+		WHERE pu.id = ?
+	`, usageID).Scan(&storeID)
+	return storeID, err
+}
+
+// storeRoleCondition 生成只匹配拥有capability的店铺角色的SQL条件，roleColumn为角色列；
+// capability为空时不限制角色，返回空条件
+func storeRoleCondition(roleColumn string, capability models.Capability) (string, []interface{}) {
+	if capability == "" {
+		return "", nil
+	}
+	roles := models.RolesWith(capability)
+	if len(roles) == 0 {
+		return " AND 0", nil
+	}
+	args := make([]interface{}, len(roles))
+	for i, role := range roles {
+		args[i] = role
+	}
+	return " AND " + roleColumn + " IN (?" + strings.Repeat(", ?", len(roles)-1) + ")", args
+}
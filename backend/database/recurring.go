@@ -0,0 +1,258 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"account/backend/models"
+)
+
+// CreateRecurringTables 创建周期账务模板表
+func CreateRecurringTables() error {
+	createRecurringTable := `
+	CREATE TABLE IF NOT EXISTS recurring_entries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		store_id INTEGER NOT NULL,
+		type_id INTEGER NOT NULL,
+		amount INTEGER NOT NULL,
+		remark TEXT,
+		frequency TEXT NOT NULL,
+		day_of_month INTEGER NOT NULL DEFAULT 0,
+		weekday INTEGER NOT NULL DEFAULT 0,
+		month INTEGER NOT NULL DEFAULT 0,
+		start_date TEXT NOT NULL,
+		end_date TEXT,
+		next_date TEXT,
+		paused INTEGER NOT NULL DEFAULT 0,
+		created_by INTEGER NOT NULL,
+		create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		update_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (store_id) REFERENCES stores(id),
+		FOREIGN KEY (type_id) REFERENCES account_types(id)
+	);`
+
+	if _, err := DB.Exec(createRecurringTable); err != nil {
+		return fmt.Errorf("创建周期账务表失败: %v", err)
+	}
+
+	// 同一模板同一天只生成一条账务记录，服务重启或重复执行时不会重复生成
+	if _, err := DB.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_recurring
+		ON accounts(recurring_id, recurring_date) WHERE recurring_id IS NOT NULL
+	`); err != nil {
+		return fmt.Errorf("创建周期账务索引失败: %v", err)
+	}
+
+	log.Println("周期账务表初始化完成")
+	return nil
+}
+
+// recurringEntryColumns 查询周期账务模板的列，r为recurring_entries表的别名
+const recurringEntryColumns = `
+	r.id, r.store_id, COALESCE(s.name, ''), r.type_id, COALESCE(t.name, ''), r.amount, COALESCE(r.remark, ''),
+	r.frequency, r.day_of_month, r.weekday, r.month, r.start_date, COALESCE(r.end_date, ''), COALESCE(r.next_date, ''),
+	r.paused, r.created_by, r.create_time, r.update_time
+	FROM recurring_entries r
+	LEFT JOIN stores s ON r.store_id = s.id
+	LEFT JOIN account_types t ON r.type_id = t.id`
+
+// scanRecurringEntry 从查询结果中读取一条周期账务模板
+func scanRecurringEntry(scanner rowScanner) (*models.RecurringEntry, error) {
+	var entry models.RecurringEntry
+	err := scanner.Scan(
+		&entry.ID, &entry.StoreID, &entry.StoreName, &entry.TypeID, &entry.TypeName, &entry.Amount, &entry.Remark,
+		&entry.Frequency, &entry.DayOfMonth, &entry.Weekday, &entry.Month, &entry.StartDate, &entry.EndDate, &entry.NextDate,
+		&entry.Paused, &entry.CreatedBy, &entry.CreateTime, &entry.UpdateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetRecurringEntries 获取周期账务模板列表，storeID为0时不限店铺；userID不为0时只返回该用户有权限店铺的模板
+func GetRecurringEntries(storeID, userID int64) ([]models.RecurringEntry, error) {
+	query := "SELECT " + recurringEntryColumns + " WHERE 1=1"
+	args := []interface{}{}
+	if storeID > 0 {
+		query += " AND r.store_id = ?"
+		args = append(args, storeID)
+	}
+	if userID > 0 {
+		query += " AND r.store_id IN (SELECT store_id FROM user_store_permissions WHERE user_id = ?)"
+		args = append(args, userID)
+	}
+	query += " ORDER BY r.store_id, r.id"
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询周期账务失败: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.RecurringEntry{}
+	for rows.Next() {
+		entry, err := scanRecurringEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取周期账务失败: %v", err)
+		}
+		entries = append(entries, *entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetRecurringEntry 获取周期账务模板，不存在时返回sql.ErrNoRows
+func GetRecurringEntry(id int64) (*models.RecurringEntry, error) {
+	return scanRecurringEntry(DB.QueryRow("SELECT "+recurringEntryColumns+" WHERE r.id = ?", id))
+}
+
+// GetDueRecurringEntries 获取未暂停且下一次生成日期不晚于date的模板
+func GetDueRecurringEntries(date string) ([]models.RecurringEntry, error) {
+	rows, err := DB.Query(
+		"SELECT "+recurringEntryColumns+" WHERE r.paused = 0 AND r.next_date IS NOT NULL AND r.next_date != '' AND r.next_date <= ? ORDER BY r.id",
+		date,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询到期的周期账务失败: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.RecurringEntry{}
+	for rows.Next() {
+		entry, err := scanRecurringEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取周期账务失败: %v", err)
+		}
+		entries = append(entries, *entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// CreateRecurringEntry 创建周期账务模板，返回模板ID
+func CreateRecurringEntry(entry *models.RecurringEntry) (int64, error) {
+	result, err := DB.Exec(`
+		INSERT INTO recurring_entries (store_id, type_id, amount, remark, frequency, day_of_month, weekday, month,
+			start_date, end_date, next_date, paused, created_by, create_time, update_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.StoreID, entry.TypeID, entry.Amount, entry.Remark, entry.Frequency, entry.DayOfMonth, entry.Weekday, entry.Month,
+		entry.StartDate, entry.EndDate, entry.NextDate, entry.Paused, entry.CreatedBy, entry.CreateTime, entry.UpdateTime)
+	if err != nil {
+		return 0, fmt.Errorf("创建周期账务失败: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateRecurringEntry 修改周期账务模板的内容、重复规则、下一次生成日期和暂停状态，不存在时返回sql.ErrNoRows
+func UpdateRecurringEntry(entry *models.RecurringEntry) error {
+	result, err := DB.Exec(`
+		UPDATE recurring_entries SET store_id = ?, type_id = ?, amount = ?, remark = ?, frequency = ?, day_of_month = ?,
+			weekday = ?, month = ?, start_date = ?, end_date = ?, next_date = ?, paused = ?, update_time = ?
+		WHERE id = ?
+	`, entry.StoreID, entry.TypeID, entry.Amount, entry.Remark, entry.Frequency, entry.DayOfMonth,
+		entry.Weekday, entry.Month, entry.StartDate, entry.EndDate, entry.NextDate, entry.Paused, entry.UpdateTime, entry.ID)
+	if err != nil {
+		return fmt.Errorf("修改周期账务失败: %v", err)
+	}
+	return requireAffected(result)
+}
+
+// DeleteRecurringEntry 删除周期账务模板，已生成的账务记录保留；不存在时返回sql.ErrNoRows
+func DeleteRecurringEntry(id int64) error {
+	result, err := DB.Exec("DELETE FROM recurring_entries WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("删除周期账务失败: %v", err)
+	}
+	return requireAffected(result)
+}
+
+// MaterializeRecurringEntry 按模板生成date当天的账务记录，并把下一次生成日期改为nextDate，两者在同一事务中完成。
+// 返回生成的账务记录ID，当天的记录已生成过或所在月份已结账时不生成，返回0；
+// 因所在月份已结账跳过生成时skipped为true，由调用方记录
+func MaterializeRecurringEntry(entry *models.RecurringEntry, date, nextDate string) (accountID int64, skipped bool, err error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	transactionTime, err := resolveAccountTime(tx, entry.StoreID, date+" 00:00:00")
+	if err != nil {
+		return 0, false, err
+	}
+	err = checkPeriodOpen(tx, entry.StoreID, transactionTime.local)
+	if errors.Is(err, models.ErrPeriodClosed) {
+		log.Printf("周期账务 %d 在 %s 的记录所在月份已结账，跳过生成", entry.ID, date)
+		skipped = true
+	} else if err != nil {
+		return 0, false, err
+	} else {
+		now := dbTime(time.Now())
+		result, err := tx.Exec(`
+			INSERT OR IGNORE INTO accounts (store_id, user_id, type_id, amount, remark, transaction_time, utc_offset, create_time, update_time, recurring_id, recurring_date)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, entry.StoreID, entry.CreatedBy, entry.TypeID, entry.Amount, entry.Remark, transactionTime.utc, transactionTime.offset, now, now, entry.ID, date)
+		if err != nil {
+			return 0, false, fmt.Errorf("生成周期账务记录失败: %v", err)
+		}
+		created, err := result.RowsAffected()
+		if err != nil {
+			return 0, false, err
+		}
+		if created > 0 {
+			if accountID, err = result.LastInsertId(); err != nil {
+				return 0, false, err
+			}
+		}
+	}
+
+	// 只有下一次生成日期仍是本次处理的日期时才推进，避免与模板修改冲突
+	result, err := tx.Exec(
+		"UPDATE recurring_entries SET next_date = ? WHERE id = ? AND next_date = ?",
+		nextDate, entry.ID, entry.NextDate,
+	)
+	if err != nil {
+		return 0, false, fmt.Errorf("更新周期账务下一次生成日期失败: %v", err)
+	}
+	// 其他任务已处理过该日期时不重复报告跳过
+	if skipped {
+		advanced, err := result.RowsAffected()
+		if err != nil {
+			return 0, false, err
+		}
+		skipped = advanced > 0
+	}
+
+	return accountID, skipped, tx.Commit()
+}
+
+// GetRecurringEntryStoreID 获取周期账务模板所属店铺，模板不存在时返回sql.ErrNoRows
+func GetRecurringEntryStoreID(id int) (int, error) {
+	var storeID int
+	err := DB.QueryRow("SELECT store_id FROM recurring_entries WHERE id = ?", id).Scan(&storeID)
+	return storeID, err
+}
+
+// requireAffected 没有记录被修改时返回sql.ErrNoRows
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -25,8 +25,8 @@ func CreateTestData() error {
 		}
 
 		_, err = DB.Exec(`
-			INSERT INTO users (username, nickname, password, role, avatar)
-			VALUES (?, ?, ?, ?, ?)
+			INSERT INTO users (username, nickname, password, role, avatar, must_change_password)
+			VALUES (?, ?, ?, ?, ?, 1)
 		`, "admin", "系统管理员", string(hashedPassword), 1, "")
 
 		if err != nil {
@@ -49,8 +49,8 @@ func CreateTestData() error {
 		}
 
 		_, err = DB.Exec(`
-			INSERT INTO users (username, nickname, password, role, avatar)
-			VALUES (?, ?, ?, ?, ?)
+			INSERT INTO users (username, nickname, password, role, avatar, must_change_password)
+			VALUES (?, ?, ?, ?, ?, 1)
 		`, "clerk", "测试店员", string(hashedPassword), 0, "")
 
 		if err != nil {
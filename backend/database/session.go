@@ -0,0 +1,205 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"account/backend/models"
+)
+
+// CreateSessionTables 创建登录会话表
+func CreateSessionTables() error {
+	createSessionTable := `
+	CREATE TABLE IF NOT EXISTS user_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		refresh_token_hash TEXT NOT NULL UNIQUE,
+		device TEXT,
+		user_agent TEXT,
+		ip TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`
+
+	if _, err := DB.Exec(createSessionTable); err != nil {
+		return fmt.Errorf("创建会话表失败: %v", err)
+	}
+
+	if _, err := DB.Exec(`CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id)`); err != nil {
+		return fmt.Errorf("创建会话表索引失败: %v", err)
+	}
+
+	log.Println("会话表初始化完成")
+	return nil
+}
+
+// CreateSession 为用户创建新的登录会话，返回会话ID
+func CreateSession(userID int64, refreshTokenHash, device, userAgent, ip string, expiresAt time.Time) (int64, error) {
+	now := time.Now()
+	result, err := DB.Exec(`
+		INSERT INTO user_sessions (user_id, refresh_token_hash, device, user_agent, ip, created_at, last_used_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, userID, refreshTokenHash, device, userAgent, ip, now, now, expiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("创建会话失败: %v", err)
+	}
+	return result.LastInsertId()
+}
+
+// GetSessionByRefreshTokenHash 根据刷新令牌哈希查找会话，不存在时返回nil
+func GetSessionByRefreshTokenHash(refreshTokenHash string) (*models.Session, error) {
+	row := DB.QueryRow(`
+		SELECT id, user_id, refresh_token_hash, device, user_agent, ip, created_at, last_used_at, expires_at, revoked_at
+		FROM user_sessions
+		WHERE refresh_token_hash = ?
+	`, refreshTokenHash)
+
+	session, err := scanSession(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询会话失败: %v", err)
+	}
+	return session, nil
+}
+
+// RotateSessionRefreshToken 刷新会话时把旧的刷新令牌替换为新令牌并延长有效期。
+// 只有会话仍使用旧令牌且有效时才更新，并发请求使用同一个旧令牌时只有一个成功，其余返回models.ErrRefreshTokenUsed
+func RotateSessionRefreshToken(sessionID int64, oldRefreshTokenHash, refreshTokenHash string, expiresAt time.Time) error {
+	now := time.Now()
+	result, err := DB.Exec(`
+		UPDATE user_sessions
+		SET refresh_token_hash = ?, expires_at = ?, last_used_at = ?
+		WHERE id = ? AND refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > ?
+	`, refreshTokenHash, expiresAt, now, sessionID, oldRefreshTokenHash, now)
+	if err != nil {
+		return fmt.Errorf("更新会话刷新令牌失败: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("更新会话刷新令牌失败: %v", err)
+	}
+	if affected == 0 {
+		return models.ErrRefreshTokenUsed
+	}
+	return nil
+}
+
+// IsSessionActive 检查会话是否存在、未被注销且未过期
+func IsSessionActive(sessionID int64) (bool, error) {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM user_sessions
+		WHERE id = ? AND revoked_at IS NULL AND expires_at > ?
+	`, sessionID, time.Now()).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("查询会话状态失败: %v", err)
+	}
+	return count > 0, nil
+}
+
+// GetActiveSessions 获取用户所有有效的会话，按最近使用时间倒序
+func GetActiveSessions(userID int64) ([]models.Session, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, refresh_token_hash, device, user_agent, ip, created_at, last_used_at, expires_at, revoked_at
+		FROM user_sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_used_at DESC
+	`, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("查询用户会话失败: %v", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取会话数据失败: %v", err)
+		}
+		sessions = append(sessions, *session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession 注销用户的指定会话，会话不属于该用户或已注销时返回false
+func RevokeSession(userID, sessionID int64) (bool, error) {
+	result, err := DB.Exec(`
+		UPDATE user_sessions SET revoked_at = ?
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, time.Now(), sessionID, userID)
+	if err != nil {
+		return false, fmt.Errorf("注销会话失败: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// RevokeUserSessions 注销用户的全部会话，用于强制下线
+func RevokeUserSessions(userID int64) error {
+	_, err := DB.Exec(`
+		UPDATE user_sessions SET revoked_at = ?
+		WHERE user_id = ? AND revoked_at IS NULL
+	`, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("注销用户会话失败: %v", err)
+	}
+	return nil
+}
+
+// RevokeOtherSessions 注销用户除当前会话以外的全部会话，用于修改密码后让其他设备下线
+func RevokeOtherSessions(userID, currentSessionID int64) error {
+	_, err := DB.Exec(`
+		UPDATE user_sessions SET revoked_at = ?
+		WHERE user_id = ? AND id != ? AND revoked_at IS NULL
+	`, time.Now(), userID, currentSessionID)
+	if err != nil {
+		return fmt.Errorf("注销用户其他会话失败: %v", err)
+	}
+	return nil
+}
+
+// rowScanner 兼容sql.Row和sql.Rows的扫描接口
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSession 从查询结果中读取一条会话记录
+func scanSession(scanner rowScanner) (*models.Session, error) {
+	var session models.Session
+	var device, userAgent, ip sql.NullString
+	var revokedAt sql.NullTime
+
+	err := scanner.Scan(
+		&session.ID, &session.UserID, &session.RefreshTokenHash,
+		&device, &userAgent, &ip,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session.Device = device.String
+	session.UserAgent = userAgent.String
+	session.IP = ip.String
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+
+	return &session, nil
+}
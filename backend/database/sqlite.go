@@ -4,6 +4,7 @@ import (
 	"log"
 	"time"
 
+	"account/backend/models"
 	"account/backend/utils"
 )
 
@@ -24,11 +25,16 @@ func InsertDefaultAdmin() {
 		return
 	}
 
-	// 创建默认管理员
+	// 创建默认管理员，默认密码是公开的，首次登录后必须修改
+	hashedPassword, err := utils.HashPassword("123456")
+	if err != nil {
+		log.Fatalf("生成密码哈希失败: %v", err)
+	}
+
 	_, err = DB.Exec(`
-		INSERT INTO users (username, password, nickname, role)
-		VALUES (?, ?, ?, ?)
-	`, "admin", "123456", "系统管理员", 1)
+		INSERT INTO users (username, password, nickname, role, must_change_password)
+		VALUES (?, ?, ?, ?, 1)
+	`, "admin", hashedPassword, "系统管理员", 1)
 
 	if err != nil {
 		log.Fatalf("创建默认管理员失败: %v", err)
@@ -87,10 +93,16 @@ func InsertTestDataLegacy() {
 			continue
 		}
 
-		_, err := DB.Exec(`
-			INSERT INTO users (username, password, nickname, role, phone)
-			VALUES (?, ?, ?, ?, ?)
-		`, staff.username, staff.password, staff.nickname, RoleStaff, staff.phone)
+		hashedPassword, err := utils.HashPassword(staff.password)
+		if err != nil {
+			log.Printf("生成密码哈希失败: %v", err)
+			continue
+		}
+
+		_, err = DB.Exec(`
+			INSERT INTO users (username, password, nickname, role, phone, must_change_password)
+			VALUES (?, ?, ?, ?, ?, 1)
+		`, staff.username, hashedPassword, staff.nickname, RoleStaff, staff.phone)
 
 		if err != nil {
 			log.Printf("插入店员数据失败: %v", err)
@@ -143,9 +155,9 @@ func InsertTestDataLegacy() {
 					staff1ID, storeID).Scan(&count)
 				if count == 0 {
 					_, err = DB.Exec(`
-						INSERT INTO user_store_permissions (user_id, store_id)
-						VALUES (?, ?)
-					`, staff1ID, storeID)
+						INSERT INTO user_store_permissions (user_id, store_id, role)
+						VALUES (?, ?, ?)
+					`, staff1ID, storeID, models.StoreRoleManager)
 					if err != nil {
 						log.Printf("设置店员权限失败: %v", err)
 					}
@@ -172,9 +184,9 @@ func InsertTestDataLegacy() {
 				staff2ID, store3ID).Scan(&count)
 			if count == 0 {
 				_, err = DB.Exec(`
-					INSERT INTO user_store_permissions (user_id, store_id)
-					VALUES (?, ?)
-				`, staff2ID, store3ID)
+					INSERT INTO user_store_permissions (user_id, store_id, role)
+					VALUES (?, ?, ?)
+				`, staff2ID, store3ID, models.StoreRoleManager)
 				if err != nil {
 					log.Printf("设置店员权限失败: %v", err)
 				}
@@ -220,22 +232,28 @@ func InsertTestDataLegacy() {
 		storeID         int64
 		userID          int64
 		typeID          int64
-		amount          float64
+		amount          models.Money
 		remark          string
 		transactionTime time.Time
 	}{
-		{store1ID, adminID, salesIncomeID, 1200, "日常销售", currentTime},
-		{store2ID, staff1ID, utilityExpenseID, -300, "水电费支出", currentTime},
-		{store3ID, staff2ID, salaryExpenseID, -5000, "员工工资", yesterday},
-		{store1ID, adminID, salesIncomeID, 2000, "周末促销", yesterday},
-		{store2ID, staff1ID, salesIncomeID, 1500, "节日活动", twoDaysAgo},
+		{store1ID, adminID, salesIncomeID, 120000, "日常销售", currentTime},
+		{store2ID, staff1ID, utilityExpenseID, 30000, "水电费支出", currentTime},
+		{store3ID, staff2ID, salaryExpenseID, 500000, "员工工资", yesterday},
+		{store1ID, adminID, salesIncomeID, 200000, "周末促销", yesterday},
+		{store2ID, staff1ID, salesIncomeID, 150000, "节日活动", twoDaysAgo},
 	}
 
 	for _, account := range accounts {
-		_, err := DB.Exec(`
-			INSERT INTO accounts (store_id, user_id, type_id, amount, remark, transaction_time)
-			VALUES (?, ?, ?, ?, ?, ?)
-		`, account.storeID, account.userID, account.typeID, account.amount, account.remark, account.transactionTime)
+		loc, err := storeLocation(DB, account.storeID)
+		if err != nil {
+			log.Printf("插入账务记录失败: %v", err)
+			continue
+		}
+		transactionTime := newAccountTime(account.transactionTime, loc)
+		_, err = DB.Exec(`
+			INSERT INTO accounts (store_id, user_id, type_id, amount, remark, transaction_time, utc_offset)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, account.storeID, account.userID, account.typeID, account.amount, account.remark, transactionTime.utc, transactionTime.offset)
 
 		if err != nil {
 			log.Printf("插入账务记录失败: %v", err)
@@ -376,8 +394,8 @@ func InitUsersLegacy() {
 		}
 		
 		_, err = DB.Exec(`
-			INSERT INTO users (username, password, nickname, role, create_time, update_time) 
-			VALUES (?, ?, ?, ?, ?, ?)
+			INSERT INTO users (username, password, nickname, role, must_change_password, create_time, update_time) 
+			VALUES (?, ?, ?, ?, 1, ?, ?)
 		`, "admin", hashedPassword, "系统管理员", 1, time.Now(), time.Now())
 		
 		if err != nil {
@@ -405,8 +423,8 @@ func InitUsersLegacy() {
 		}
 		
 		_, err = DB.Exec(`
-			INSERT INTO users (username, password, nickname, role, create_time, update_time) 
-			VALUES (?, ?, ?, ?, ?, ?)
+			INSERT INTO users (username, password, nickname, role, must_change_password, create_time, update_time) 
+			VALUES (?, ?, ?, ?, 1, ?, ?)
 		`, "staff1", hashedPassword, "默认店员", 2, time.Now(), time.Now())
 		
 		if err != nil {
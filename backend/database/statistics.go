@@ -4,50 +4,99 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"math"
 	"time"
+
+	"account/backend/models"
 )
 
 // 报表数据结构
 type ReportData struct {
-	TotalIncome       float64        `json:"totalIncome"`
-	TotalExpense      float64        `json:"totalExpense"`
-	NetIncome         float64        `json:"netIncome"`
-	Trend             []TrendData    `json:"trend"`
-	Compare           []CompareData  `json:"compare"`
-	IncomeCategories  []CategoryData `json:"incomeCategories"`
-	ExpenseCategories []CategoryData `json:"expenseCategories"`
+	TotalIncome       models.Money         `json:"totalIncome"`
+	TotalExpense      models.Money         `json:"totalExpense"`
+	NetIncome         models.Money         `json:"netIncome"`
+	TransferIn        models.Money         `json:"transferIn"`  // 从其他店铺转入，不计入收入
+	TransferOut       models.Money         `json:"transferOut"` // 转出到其他店铺，不计入支出
+	Trend             []TrendData          `json:"trend"`
+	Compare           []CompareData        `json:"compare"`
+	IncomeCategories  []CategoryData       `json:"incomeCategories"`
+	ExpenseCategories []CategoryData       `json:"expenseCategories"`
+	Budgets           []models.BudgetUsage `json:"budgets"`          // 预算与实际支出对比
+	Currency          string               `json:"currency"`         // 报表币种，金额均已换算为该币种
+	UnconvertedCount  int                  `json:"unconvertedCount"` // 缺少汇率、未计入金额的记录数
+	StartDate         string               `json:"startDate"`        // 统计的开始日期
+	EndDate           string               `json:"endDate"`          // 统计的结束日期
+	Granularity       string               `json:"granularity"`      // 趋势数据的时间粒度
+	Comparison        *ReportComparison    `json:"comparison"`       // 对比期间的数据，未指定对比时为null
+}
+
+// 趋势数据的时间粒度
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// ReportComparison 对比期间的收支合计，以及本期相对对比期的变化百分比；对比期为0时变化百分比为null
+type ReportComparison struct {
+	CompareTo        string       `json:"compareTo"`
+	StartDate        string       `json:"startDate"`
+	EndDate          string       `json:"endDate"`
+	TotalIncome      models.Money `json:"totalIncome"`
+	TotalExpense     models.Money `json:"totalExpense"`
+	NetIncome        models.Money `json:"netIncome"`
+	UnconvertedCount int          `json:"unconvertedCount"`
+	IncomeChange     *float64     `json:"incomeChange"`
+	ExpenseChange    *float64     `json:"expenseChange"`
+	NetChange        *float64     `json:"netChange"`
 }
 
 // 趋势数据结构
 type TrendData struct {
-	Date    string  `json:"date"`
-	Income  float64 `json:"income"`
-	Expense float64 `json:"expense"`
-	Net     float64 `json:"net"`
+	Date    string       `json:"date"`
+	Income  models.Money `json:"income"`
+	Expense models.Money `json:"expense"`
+	Net     models.Money `json:"net"`
 }
 
 // 对比数据结构
 type CompareData struct {
-	Category string  `json:"category"`
-	Income   float64 `json:"income"`
-	Expense  float64 `json:"expense"`
-	Net      float64 `json:"net"`
+	Category string       `json:"category"`
+	Income   models.Money `json:"income"`
+	Expense  models.Money `json:"expense"`
+	Net      models.Money `json:"net"`
 }
 
 // 分类数据结构
 type CategoryData struct {
-	ID     int64   `json:"id"`
-	Name   string  `json:"name"`
-	Amount float64 `json:"amount"`
+	ID     int64        `json:"id"`
+	Name   string       `json:"name"`
+	Amount models.Money `json:"amount"`
 }
 
-// 获取报表数据
-func GetReportData(startDate, endDate time.Time, storeId int64, userID int64) (ReportData, error) {
-	var reportData ReportData
+// 收支方向由账务类型决定，金额均为正数。以下SQL片段中a为accounts表、t为account_types表的别名，
+// 没有类型的记录按收入统计；店铺间转账不计入收入和支出，单独统计转入和转出
+var (
+	incomeAmountSQL      = fmt.Sprintf("CASE WHEN a.transfer_peer_id IS NOT NULL OR t.category = %d THEN 0 ELSE a.amount END", models.AccountCategoryExpense)
+	expenseAmountSQL     = fmt.Sprintf("CASE WHEN a.transfer_peer_id IS NULL AND t.category = %d THEN a.amount ELSE 0 END", models.AccountCategoryExpense)
+	signedAmountSQL      = fmt.Sprintf("CASE WHEN a.transfer_peer_id IS NOT NULL THEN 0 WHEN t.category = %d THEN -a.amount ELSE a.amount END", models.AccountCategoryExpense)
+	transferInAmountSQL  = fmt.Sprintf("CASE WHEN a.transfer_peer_id IS NOT NULL AND t.category != %d THEN a.amount ELSE 0 END", models.AccountCategoryExpense)
+	transferOutAmountSQL = fmt.Sprintf("CASE WHEN a.transfer_peer_id IS NOT NULL AND t.category = %d THEN a.amount ELSE 0 END", models.AccountCategoryExpense)
+	isExpenseSQL         = fmt.Sprintf("COALESCE(t.category, 0) = %d", models.AccountCategoryExpense)
+)
+
+// 获取报表数据，金额按各记录交易日期的汇率换算为currency币种，趋势数据按granularity分组。
+// compareStart不为零值时同时统计compareStart到compareEnd的对比数据
+func GetReportData(startDate, endDate time.Time, storeId int64, userID int64, currency, granularity string, compareStart, compareEnd time.Time) (ReportData, error) {
+	reportData := ReportData{
+		Currency:    currency,
+		StartDate:   startDate.Format(models.DateLayout),
+		EndDate:     endDate.Format(models.DateLayout),
+		Granularity: granularity,
+	}
 
 	// 检查用户是否是管理员
-	var isAdmin bool
-	err := DB.QueryRow("SELECT role = 1 FROM users WHERE id = ?", userID).Scan(&isAdmin)
+	isAdmin, err := IsUserAdmin(userID)
 	if err != nil {
 		return reportData, fmt.Errorf("检查用户权限失败: %w", err)
 	}
@@ -69,7 +118,7 @@ func GetReportData(startDate, endDate time.Time, storeId int64, userID int64) (R
 			if err == sql.ErrNoRows {
 				// 没有权限的店铺，返回空数据
 				log.Printf("用户 %d 没有任何店铺权限", userID)
-				return ReportData{}, nil
+				return reportData, nil
 			}
 			return reportData, fmt.Errorf("获取用户店铺权限失败: %w", err)
 		}
@@ -77,14 +126,7 @@ func GetReportData(startDate, endDate time.Time, storeId int64, userID int64) (R
 		log.Printf("非管理员用户 %d 未指定店铺，自动选择第一个有权限的店铺ID: %d", userID, storeId)
 	} else if !isAdmin && storeId > 0 {
 		// 非管理员指定了店铺，检查是否有权限
-		var hasPermission bool
-		err := DB.QueryRow(`
-			SELECT EXISTS (
-				SELECT 1 FROM user_store_permissions
-				WHERE user_id = ? AND store_id = ?
-			)
-		`, userID, storeId).Scan(&hasPermission)
-
+		hasPermission, err := IsStoreAssignedToUser(int(userID), int(storeId))
 		if err != nil {
 			return reportData, fmt.Errorf("检查店铺权限失败: %w", err)
 		}
@@ -98,7 +140,7 @@ func GetReportData(startDate, endDate time.Time, storeId int64, userID int64) (R
 	// 如果此时仍然没有有效的店铺ID，返回空数据
 	if storeId <= 0 && !isAdmin {
 		log.Printf("用户 %d 没有指定有效的店铺ID", userID)
-		return ReportData{}, nil
+		return reportData, nil
 	}
 
 	// 构建查询参数
@@ -110,41 +152,76 @@ func GetReportData(startDate, endDate time.Time, storeId int64, userID int64) (R
 		args = append(args, storeId)
 	}
 
+	// 非管理员只统计有权查看的账务类型
+	if !isAdmin {
+		typeFilter, typeArgs := accountTypeVisibilityCondition(userID, "a.type_id", "a.store_id")
+		storeFilter += typeFilter
+		args = append(args, typeArgs...)
+	}
+
 	// 获取总计数据
-	reportData.TotalIncome, reportData.TotalExpense, reportData.NetIncome, err = getTotalAmounts(startDate, endDate, storeId, storeFilter, args)
+	reportData.TotalIncome, reportData.TotalExpense, reportData.NetIncome, reportData.UnconvertedCount, err = getTotalAmounts(startDate, endDate, storeId, storeFilter, args, currency)
 	if err != nil {
 		return reportData, fmt.Errorf("获取总计数据失败: %w", err)
 	}
 
+	// 获取店铺间转账数据，单独展示
+	reportData.TransferIn, reportData.TransferOut, err = getTransferAmounts(startDate, endDate, storeFilter, args, currency)
+	if err != nil {
+		return reportData, fmt.Errorf("获取转账数据失败: %w", err)
+	}
+
+	// 获取对比期间的总计数据
+	if !compareStart.IsZero() {
+		comparison := &ReportComparison{
+			StartDate: compareStart.Format(models.DateLayout),
+			EndDate:   compareEnd.Format(models.DateLayout),
+		}
+		comparison.TotalIncome, comparison.TotalExpense, comparison.NetIncome, comparison.UnconvertedCount, err = getTotalAmounts(compareStart, compareEnd, storeId, storeFilter, args, currency)
+		if err != nil {
+			return reportData, fmt.Errorf("获取对比数据失败: %w", err)
+		}
+		comparison.IncomeChange = percentChange(reportData.TotalIncome, comparison.TotalIncome)
+		comparison.ExpenseChange = percentChange(reportData.TotalExpense, comparison.TotalExpense)
+		comparison.NetChange = percentChange(reportData.NetIncome, comparison.NetIncome)
+		reportData.Comparison = comparison
+	}
+
 	// 获取趋势数据
-	reportData.Trend, err = getTrendData(startDate, endDate, storeId, storeFilter, args)
+	reportData.Trend, err = getTrendData(startDate, endDate, storeId, storeFilter, args, currency, granularity)
 	if err != nil {
 		return reportData, fmt.Errorf("获取趋势数据失败: %w", err)
 	}
 
 	// 获取分类对比数据
-	reportData.Compare, err = getCompareData(startDate, endDate, storeId)
+	reportData.Compare, err = getCompareData(startDate, endDate, storeId, storeFilter, args, currency)
 	if err != nil {
 		return reportData, fmt.Errorf("获取分类对比数据失败: %w", err)
 	}
 
 	// 获取收入分类数据
-	reportData.IncomeCategories, err = getCategoryData(startDate, endDate, storeId, true)
+	reportData.IncomeCategories, err = getCategoryData(startDate, endDate, storeId, storeFilter, args, true, currency)
 	if err != nil {
 		return reportData, fmt.Errorf("获取收入分类数据失败: %w", err)
 	}
 
 	// 获取支出分类数据
-	reportData.ExpenseCategories, err = getCategoryData(startDate, endDate, storeId, false)
+	reportData.ExpenseCategories, err = getCategoryData(startDate, endDate, storeId, storeFilter, args, false, currency)
 	if err != nil {
 		return reportData, fmt.Errorf("获取支出分类数据失败: %w", err)
 	}
 
+	// 获取预算执行情况
+	reportData.Budgets, err = getBudgetUsages(startDate, endDate, storeId, userID, isAdmin, currency)
+	if err != nil {
+		return reportData, fmt.Errorf("获取预算数据失败: %w", err)
+	}
+
 	return reportData, nil
 }
 
-// 获取总收入和总支出
-func getTotalAmounts(startDate, endDate time.Time, storeId int64, storeFilter string, args []interface{}) (float64, float64, float64, error) {
+// 获取总收入和总支出，以及缺少汇率无法换算的记录数
+func getTotalAmounts(startDate, endDate time.Time, storeId int64, storeFilter string, args []interface{}, currency string) (models.Money, models.Money, models.Money, int, error) {
 	// 复制args以避免修改原始切片
 	queryArgs := make([]interface{}, len(args))
 	copy(queryArgs, args)
@@ -152,23 +229,66 @@ func getTotalAmounts(startDate, endDate time.Time, storeId int64, storeFilter st
 	// 添加日期参数
 	query := `
 		SELECT 
-			COALESCE(SUM(CASE WHEN a.amount > 0 THEN a.amount ELSE 0 END), 0) as income,
-			COALESCE(SUM(CASE WHEN a.amount < 0 THEN ABS(a.amount) ELSE 0 END), 0) as expense,
-			COALESCE(SUM(a.amount), 0) as net
+			COALESCE(SUM(` + inCurrency(incomeAmountSQL, currency) + `), 0) as income,
+			COALESCE(SUM(` + inCurrency(expenseAmountSQL, currency) + `), 0) as expense,
+			COALESCE(SUM(` + inCurrency(signedAmountSQL, currency) + `), 0) as net,
+			` + unconvertedCountSQL(currency) + ` as unconverted
+		FROM accounts a
+		LEFT JOIN account_types t ON a.type_id = t.id
+		WHERE a.deleted_at IS NULL AND ` + accountLocalTimeSQL + ` BETWEEN ? AND ?
+	` + storeFilter
+
+	queryArgs = append([]interface{}{startDate.Format(models.DateTimeLayout), endDate.Format(models.DateTimeLayout)}, queryArgs...)
+
+	var income, expense, net models.Money
+	var unconverted int
+	err := DB.QueryRow(query, queryArgs...).Scan(&income, &expense, &net, &unconverted)
+
+	return income, expense, net, unconverted, err
+}
+
+// 获取店铺间转入和转出的总额
+func getTransferAmounts(startDate, endDate time.Time, storeFilter string, args []interface{}, currency string) (models.Money, models.Money, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(` + inCurrency(transferInAmountSQL, currency) + `), 0) as transfer_in,
+			COALESCE(SUM(` + inCurrency(transferOutAmountSQL, currency) + `), 0) as transfer_out
 		FROM accounts a
-		WHERE a.transaction_time BETWEEN ? AND ?
+		LEFT JOIN account_types t ON a.type_id = t.id
+		WHERE a.deleted_at IS NULL AND a.transfer_peer_id IS NOT NULL AND ` + accountLocalTimeSQL + ` BETWEEN ? AND ?
 	` + storeFilter
 
-	queryArgs = append([]interface{}{startDate, endDate}, queryArgs...)
+	queryArgs := append([]interface{}{startDate.Format(models.DateTimeLayout), endDate.Format(models.DateTimeLayout)}, args...)
+
+	var transferIn, transferOut models.Money
+	err := DB.QueryRow(query, queryArgs...).Scan(&transferIn, &transferOut)
 
-	var income, expense, net float64
-	err := DB.QueryRow(query, queryArgs...).Scan(&income, &expense, &net)
+	return transferIn, transferOut, err
+}
+
+// percentChange 本期相对对比期的变化百分比，保留两位小数；对比期为0时无法计算，返回nil
+func percentChange(current, previous models.Money) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round(float64(current-previous)/math.Abs(float64(previous))*10000) / 100
+	return &change
+}
 
-	return income, expense, net, err
+// trendBucketSQL 趋势数据分组的日期，为每组第一天的日期：按周分组时为周一，按月分组时为1日
+func trendBucketSQL(granularity string) string {
+	switch granularity {
+	case GranularityWeek:
+		return "date(" + accountLocalTimeSQL + ", 'weekday 0', '-6 days')"
+	case GranularityMonth:
+		return "date(" + accountLocalTimeSQL + ", 'start of month')"
+	default:
+		return accountLocalDateSQL
+	}
 }
 
-// 获取趋势数据
-func getTrendData(startDate, endDate time.Time, storeId int64, storeFilter string, args []interface{}) ([]TrendData, error) {
+// 获取趋势数据，按granularity分组
+func getTrendData(startDate, endDate time.Time, storeId int64, storeFilter string, args []interface{}, currency, granularity string) ([]TrendData, error) {
 	var trendData []TrendData
 
 	// 复制args以避免修改原始切片
@@ -176,18 +296,19 @@ func getTrendData(startDate, endDate time.Time, storeId int64, storeFilter strin
 	copy(queryArgs, args)
 
 	// 将时间戳参数转换为字符串格式
-	startDateStr := startDate.Format("2006-01-02 15:04:05")
-	endDateStr := endDate.Format("2006-01-02 15:04:05")
+	startDateStr := startDate.Format(models.DateTimeLayout)
+	endDateStr := endDate.Format(models.DateTimeLayout)
 
 	// 修改SQL查询，先添加日期条件，然后再添加其他条件
 	query := `
 		SELECT 
-			SUBSTR(a.transaction_time, 1, 10) as date,
-			SUM(CASE WHEN a.amount >= 0 THEN a.amount ELSE 0 END) as income,
-			SUM(CASE WHEN a.amount < 0 THEN a.amount ELSE 0 END) as expense,
-			SUM(a.amount) as net
+			` + trendBucketSQL(granularity) + ` as date,
+			COALESCE(SUM(` + inCurrency(incomeAmountSQL, currency) + `), 0) as income,
+			-COALESCE(SUM(` + inCurrency(expenseAmountSQL, currency) + `), 0) as expense,
+			COALESCE(SUM(` + inCurrency(signedAmountSQL, currency) + `), 0) as net
 		FROM accounts a
-		WHERE a.transaction_time BETWEEN ? AND ?
+		LEFT JOIN account_types t ON a.type_id = t.id
+		WHERE a.deleted_at IS NULL AND a.transfer_peer_id IS NULL AND ` + accountLocalTimeSQL + ` BETWEEN ? AND ?
 	`
 
 	// 先准备日期参数
@@ -199,7 +320,7 @@ func getTrendData(startDate, endDate time.Time, storeId int64, storeFilter strin
 	}
 
 	// 按日期分组
-	query += " GROUP BY SUBSTR(a.transaction_time, 1, 10) ORDER BY date"
+	query += " GROUP BY date ORDER BY date"
 
 	// 执行查询
 	rows, err := DB.Query(query, queryArgs...)
@@ -226,38 +347,35 @@ func getTrendData(startDate, endDate time.Time, storeId int64, storeFilter strin
 }
 
 // 获取分类对比数据
-func getCompareData(startDate, endDate time.Time, storeId int64) ([]CompareData, error) {
+func getCompareData(startDate, endDate time.Time, storeId int64, storeFilter string, args []interface{}, currency string) ([]CompareData, error) {
 	var compareData []CompareData
 
 	// 将时间戳参数转换为字符串格式
-	startDateStr := startDate.Format("2006-01-02 15:04:05")
-	endDateStr := endDate.Format("2006-01-02 15:04:05")
+	startDateStr := startDate.Format(models.DateTimeLayout)
+	endDateStr := endDate.Format(models.DateTimeLayout)
 
 	// 构建查询字符串
 	query := `
 		SELECT 
 			COALESCE(t.name, '未分类') as category,
-			SUM(CASE WHEN a.amount >= 0 THEN a.amount ELSE 0 END) as income,
-			SUM(CASE WHEN a.amount < 0 THEN a.amount ELSE 0 END) as expense,
-			SUM(a.amount) as net
+			COALESCE(SUM(` + inCurrency(incomeAmountSQL, currency) + `), 0) as income,
+			-COALESCE(SUM(` + inCurrency(expenseAmountSQL, currency) + `), 0) as expense,
+			COALESCE(SUM(` + inCurrency(signedAmountSQL, currency) + `), 0) as net
 		FROM accounts a
 		LEFT JOIN account_types t ON a.type_id = t.id
-		WHERE a.transaction_time BETWEEN ? AND ?
+		WHERE a.deleted_at IS NULL AND a.transfer_peer_id IS NULL AND ` + accountLocalTimeSQL + ` BETWEEN ? AND ?
 	`
 
-	// 准备参数
-	args := []interface{}{startDateStr, endDateStr}
+	// 准备参数，先日期后过滤条件
+	queryArgs := append([]interface{}{startDateStr, endDateStr}, args...)
 
-	// 添加店铺过滤条件
-	if storeId > 0 {
-		query += " AND a.store_id = ?"
-		args = append(args, storeId)
-	}
+	// 添加过滤条件
+	query += storeFilter
 
 	// 按类别分组
 	query += " GROUP BY t.name"
 
-	rows, err := DB.Query(query, args...)
+	rows, err := DB.Query(query, queryArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("查询分类对比数据失败: %w", err)
 	}
@@ -276,37 +394,33 @@ func getCompareData(startDate, endDate time.Time, storeId int64) ([]CompareData,
 }
 
 // 获取分类数据
-func getCategoryData(startDate, endDate time.Time, storeId int64, isIncome bool) ([]CategoryData, error) {
+func getCategoryData(startDate, endDate time.Time, storeId int64, storeFilter string, args []interface{}, isIncome bool, currency string) ([]CategoryData, error) {
 	var categoryData []CategoryData
 
 	// SQL查询条件
-	amountCondition := "a.amount < 0"
+	amountCondition := isExpenseSQL
 	if isIncome {
-		amountCondition = "a.amount >= 0"
+		amountCondition = "NOT " + isExpenseSQL
 	}
 
-	storeCondition := ""
-	args := []interface{}{startDate, endDate}
-
-	if storeId > 0 {
-		storeCondition = " AND a.store_id = ?"
-		args = append(args, storeId)
-	}
+	queryArgs := append([]interface{}{startDate.Format(models.DateTimeLayout), endDate.Format(models.DateTimeLayout)}, args...)
 
 	// 查询分类数据
 	query := `
 		SELECT 
 			COALESCE(t.id, 0) as id,
 			COALESCE(t.name, '未分类') as name,
-			SUM(a.amount) as amount
+			COALESCE(SUM(` + inCurrency(signedAmountSQL, currency) + `), 0) as amount
 		FROM accounts a
 		LEFT JOIN account_types t ON a.type_id = t.id
 		WHERE ` + amountCondition + `
-		AND a.transaction_time BETWEEN ? AND ?` + storeCondition + `
+		AND a.deleted_at IS NULL
+		AND a.transfer_peer_id IS NULL
+		AND ` + accountLocalTimeSQL + ` BETWEEN ? AND ?` + storeFilter + `
 		GROUP BY t.id
 		ORDER BY ABS(amount) DESC`
 
-	rows, err := DB.Query(query, args...)
+	rows, err := DB.Query(query, queryArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("查询分类数据失败: %w", err)
 	}
@@ -8,8 +8,7 @@ import (
 // GetUserStores 获取用户可访问的店铺列表
 func GetUserStores(userID int64) ([]map[string]interface{}, error) {
 	// 检查用户是否是管理员
-	var isAdmin bool
-	err := DB.QueryRow("SELECT role = 1 FROM users WHERE id = ?", userID).Scan(&isAdmin)
+	isAdmin, err := IsUserAdmin(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -20,14 +19,14 @@ func GetUserStores(userID int64) ([]map[string]interface{}, error) {
 	if isAdmin {
 		// 管理员可以看到所有店铺
 		query = `
-			SELECT id, name, address, phone
+			SELECT id, name, address, phone, currency, COALESCE(timezone, '')
 			FROM stores
 			ORDER BY name
 		`
 	} else {
 		// 普通店员只能看到有权限的店铺
 		query = `
-			SELECT s.id, s.name, s.address, s.phone
+			SELECT s.id, s.name, s.address, s.phone, s.currency, COALESCE(s.timezone, '')
 			FROM stores s
 			INNER JOIN user_store_permissions usp ON s.id = usp.store_id
 			WHERE usp.user_id = ?
@@ -45,17 +44,19 @@ func GetUserStores(userID int64) ([]map[string]interface{}, error) {
 	var stores []map[string]interface{}
 	for rows.Next() {
 		var id int64
-		var name, address, phone string
-		err := rows.Scan(&id, &name, &address, &phone)
+		var name, address, phone, currency, timezone string
+		err := rows.Scan(&id, &name, &address, &phone, &currency, &timezone)
 		if err != nil {
 			return nil, err
 		}
 
 		store := map[string]interface{}{
-			"id":      id,
-			"name":    name,
-			"address": address,
-			"phone":   phone,
+			"id":       id,
+			"name":     name,
+			"address":  address,
+			"phone":    phone,
+			"currency": currency,
+			"timezone": timezone,
 		}
 		stores = append(stores, store)
 	}
@@ -63,21 +64,14 @@ func GetUserStores(userID int64) ([]map[string]interface{}, error) {
 	return stores, nil
 }
 
-// IsUserAdmin 检查用户是否为管理员
-func IsUserAdmin(userID int64) (bool, error) {
-	var role int
-	err := DB.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
-	if err != nil {
-		return false, err
-	}
-	return role == 1, nil
-}
-
-// CreateStore 创建新店铺
+// CreateStore 创建新店铺，未指定币种时使用默认币种
 func CreateStore(store models.Store) (int64, error) {
+	if store.Currency == "" {
+		store.Currency = models.DefaultCurrency
+	}
 	result, err := DB.Exec(
-		"INSERT INTO stores (name, address, phone) VALUES (?, ?, ?)",
-		store.Name, store.Address, store.Phone,
+		"INSERT INTO stores (name, address, phone, currency, timezone) VALUES (?, ?, ?, ?, NULLIF(?, ''))",
+		store.Name, store.Address, store.Phone, store.Currency, store.Timezone,
 	)
 	if err != nil {
 		return 0, err
@@ -85,13 +79,50 @@ func CreateStore(store models.Store) (int64, error) {
 	return result.LastInsertId()
 }
 
-// UpdateStore 更新店铺信息
+// UpdateStore 更新店铺信息，未指定币种或时区时保持不变。
+// 修改时区时按新时区重新计算已有记录的所属日期，店铺已有结账的月份时返回models.ErrTimezoneLocked
 func UpdateStore(store models.Store) error {
-	_, err := DB.Exec(
-		"UPDATE stores SET name = ?, address = ?, phone = ? WHERE id = ?",
-		store.Name, store.Address, store.Phone, store.ID,
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var timezone string
+	if err := tx.QueryRow("SELECT COALESCE(timezone, '') FROM stores WHERE id = ?", store.ID).Scan(&timezone); err != nil {
+		return err
+	}
+	timezoneChanged := store.Timezone != "" && store.Timezone != timezone
+
+	if timezoneChanged {
+		var closed int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM closed_periods WHERE store_id = ?", store.ID).Scan(&closed); err != nil {
+			return err
+		}
+		if closed > 0 {
+			return models.ErrTimezoneLocked
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE stores SET name = ?, address = ?, phone = ?, currency = COALESCE(NULLIF(?, ''), currency), timezone = COALESCE(NULLIF(?, ''), timezone) WHERE id = ?",
+		store.Name, store.Address, store.Phone, store.Currency, store.Timezone, store.ID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if timezoneChanged {
+		loc, err := storeLocation(tx, store.ID)
+		if err != nil {
+			return err
+		}
+		if err := updateStoreOffsets(tx, store.ID, loc); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // CheckStoreExists 检查店铺是否存在
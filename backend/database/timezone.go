@@ -0,0 +1,289 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"account/backend/models"
+	"account/backend/utils"
+)
+
+// 账务记录的交易时间以UTC保存，utc_offset列保存该时刻店铺时区相对UTC的分钟数，
+// 按日期筛选、分组和判断所属月份时使用换算回店铺时区的本地时间。
+// 创建、修改和删除时间同样以UTC保存，与SQLite的CURRENT_TIMESTAMP格式一致
+
+// localTimeSQL 店铺时区的本地交易时间，alias为accounts或account_history表的别名
+func localTimeSQL(alias string) string {
+	return fmt.Sprintf("datetime(%[1]s.transaction_time, %[1]s.utc_offset || ' minutes')", alias)
+}
+
+var (
+	// accountLocalTimeSQL 本地交易时间，a为accounts表的别名
+	accountLocalTimeSQL = localTimeSQL("a")
+	// accountLocalDateSQL 本地交易日期
+	accountLocalDateSQL = "SUBSTR(" + accountLocalTimeSQL + ", 1, 10)"
+	// accountLocalPeriodSQL 本地交易时间所在的月份
+	accountLocalPeriodSQL = "SUBSTR(" + accountLocalTimeSQL + ", 1, 7)"
+)
+
+// dbTime 将时间转换为保存到数据库的UTC格式
+func dbTime(t time.Time) string {
+	return t.UTC().Format(models.DateTimeLayout)
+}
+
+// StoreLocation 获取店铺的时区，店铺未设置时区或店铺不存在时返回业务时区
+func StoreLocation(storeID int64) (*time.Location, error) {
+	return storeLocation(DB, storeID)
+}
+
+// storeLocation 在事务或连接中获取店铺的时区
+func storeLocation(q queryer, storeID int64) (*time.Location, error) {
+	var name string
+	err := q.QueryRow("SELECT COALESCE(timezone, '') FROM stores WHERE id = ?", storeID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return utils.BusinessLocation(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询店铺时区失败: %v", err)
+	}
+
+	loc, err := utils.LoadLocation(name)
+	if err != nil {
+		log.Printf("店铺%d的时区%s无效，使用业务时区: %v", storeID, name, err)
+		return utils.BusinessLocation(), nil
+	}
+	return loc, nil
+}
+
+// storeLocations 按店铺缓存时区，用于逐行显示多个店铺的记录
+type storeLocations map[int64]*time.Location
+
+// get 获取店铺的时区，查询失败时使用业务时区
+func (l storeLocations) get(storeID int64) *time.Location {
+	if loc, ok := l[storeID]; ok {
+		return loc
+	}
+	loc, err := StoreLocation(storeID)
+	if err != nil {
+		log.Printf("获取店铺%d的时区失败，使用业务时区: %v", storeID, err)
+		loc = utils.BusinessLocation()
+	}
+	l[storeID] = loc
+	return loc
+}
+
+// accountTime 保存账务记录时使用的交易时间
+type accountTime struct {
+	utc    string // 保存到transaction_time列的UTC时间
+	offset int    // 保存到utc_offset列的分钟数
+	local  string // 店铺时区的本地时间
+}
+
+// newAccountTime 按店铺时区生成保存用的交易时间
+func newAccountTime(t time.Time, loc *time.Location) accountTime {
+	t = t.In(loc)
+	_, offset := t.Zone()
+	return accountTime{utc: dbTime(t), offset: offset / 60, local: t.Format(models.DateTimeLayout)}
+}
+
+// resolveAccountTime 将店铺时区的本地交易时间（格式2006-01-02 15:04:05）换算为保存用的交易时间
+func resolveAccountTime(q queryer, storeID int64, local string) (accountTime, error) {
+	loc, err := storeLocation(q, storeID)
+	if err != nil {
+		return accountTime{}, err
+	}
+	t, err := time.ParseInLocation(models.DateTimeLayout, local, loc)
+	if err != nil {
+		return accountTime{}, fmt.Errorf("无效的交易时间: %s", local)
+	}
+	return newAccountTime(t, loc), nil
+}
+
+// accountTimeInStore 将同一时刻换算为另一店铺时区的交易时间，用于转账对方店铺的记录
+func accountTimeInStore(q queryer, storeID int64, at accountTime) (accountTime, error) {
+	loc, err := storeLocation(q, storeID)
+	if err != nil {
+		return accountTime{}, err
+	}
+	t, err := time.Parse(models.DateTimeLayout, at.utc)
+	if err != nil {
+		return accountTime{}, fmt.Errorf("无效的交易时间: %s", at.utc)
+	}
+	return newAccountTime(t, loc), nil
+}
+
+// storedTimeLayouts 早期版本保存的时间格式：带时区的为Go默认格式和驱动格式，不带时区的按调用方指定的时区解析
+var storedTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+}
+
+var naiveTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseStoredTime 解析早期版本保存的时间，不带时区的时间按loc解析
+func parseStoredTime(value string, loc *time.Location) (time.Time, bool) {
+	// Go默认格式中的单调时钟读数
+	if i := strings.Index(value, " m="); i >= 0 {
+		value = value[:i]
+	}
+	value = strings.TrimSpace(value)
+	for _, layout := range storedTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	for _, layout := range naiveTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// migrateTimesToUTC 早期版本的交易时间是店铺本地时间，创建和修改时间的格式也不统一。
+// 添加utc_offset列时将table中已有记录的交易时间按店铺时区换算为UTC，timeColumns中的时间统一为UTC格式，
+// 不带时区的创建和修改时间来自CURRENT_TIMESTAMP，按UTC处理；utc_offset列已存在时不做处理
+func migrateTimesToUTC(table string, timeColumns ...string) error {
+	var exists bool
+	err := DB.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = 'utc_offset'`, table).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("检查%s表的utc_offset列失败: %w", table, err)
+	}
+	if exists {
+		return nil
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	log.Printf("向%s表添加utc_offset列并将时间换算为UTC...", table)
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN utc_offset INTEGER NOT NULL DEFAULT 0", table)); err != nil {
+		return fmt.Errorf("添加utc_offset列失败: %w", err)
+	}
+
+	// 转换为TEXT读取原始内容，避免驱动按列类型解析时间
+	columns := []string{"id", "COALESCE(store_id, 0)", "CAST(transaction_time AS TEXT)"}
+	for _, column := range timeColumns {
+		columns = append(columns, fmt.Sprintf("CAST(%s AS TEXT)", column))
+	}
+	rows, err := tx.Query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table))
+	if err != nil {
+		return fmt.Errorf("读取%s表的时间失败: %w", table, err)
+	}
+
+	type rowTimes struct {
+		id      int64
+		storeID int64
+		values  []sql.NullString
+	}
+	var pending []rowTimes
+	for rows.Next() {
+		row := rowTimes{values: make([]sql.NullString, len(columns)-2)}
+		dest := []interface{}{&row.id, &row.storeID}
+		for i := range row.values {
+			dest = append(dest, &row.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取%s表的时间失败: %w", table, err)
+		}
+		pending = append(pending, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	assignments := []string{"transaction_time = ?", "utc_offset = ?"}
+	for _, column := range timeColumns {
+		assignments = append(assignments, column+" = ?")
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", table, strings.Join(assignments, ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	locations := map[int64]*time.Location{}
+	for _, row := range pending {
+		loc, ok := locations[row.storeID]
+		if !ok {
+			if loc, err = storeLocation(tx, row.storeID); err != nil {
+				return err
+			}
+			locations[row.storeID] = loc
+		}
+
+		args := make([]interface{}, 0, len(row.values)+2)
+		if t, ok := parseStoredTime(row.values[0].String, loc); row.values[0].Valid && ok {
+			at := newAccountTime(t, loc)
+			args = append(args, at.utc, at.offset)
+		} else {
+			if row.values[0].Valid {
+				log.Printf("警告: %s表记录%d的交易时间 '%s' 无法解析，保持不变", table, row.id, row.values[0].String)
+			}
+			args = append(args, row.values[0], 0)
+		}
+		for _, value := range row.values[1:] {
+			if t, ok := parseStoredTime(value.String, time.UTC); value.Valid && ok {
+				args = append(args, dbTime(t))
+			} else {
+				args = append(args, value)
+			}
+		}
+		if _, err := stmt.Exec(append(args, row.id)...); err != nil {
+			return fmt.Errorf("换算%s表记录%d的时间失败: %w", table, row.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("%s表的%d条记录已换算为UTC时间", table, len(pending))
+	return nil
+}
+
+// updateStoreOffsets 店铺时区修改后，按新时区重新计算店铺记录的utc_offset，交易时刻保持不变
+func updateStoreOffsets(tx *sql.Tx, storeID int64, loc *time.Location) error {
+	for _, table := range []string{"accounts", "account_history"} {
+		rows, err := tx.Query(fmt.Sprintf("SELECT id, CAST(transaction_time AS TEXT) FROM %s WHERE store_id = ?", table), storeID)
+		if err != nil {
+			return fmt.Errorf("读取%s表的交易时间失败: %w", table, err)
+		}
+		offsets := map[int64]int{}
+		for rows.Next() {
+			var id int64
+			var value sql.NullString
+			if err := rows.Scan(&id, &value); err != nil {
+				rows.Close()
+				return err
+			}
+			if t, err := time.Parse(models.DateTimeLayout, value.String); err == nil {
+				offsets[id] = newAccountTime(t, loc).offset
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, offset := range offsets {
+			if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET utc_offset = ? WHERE id = ?", table), offset, id); err != nil {
+				return fmt.Errorf("更新%s表记录%d的时区偏移失败: %w", table, id, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"account/backend/models"
+)
+
+// transferAccountTypes 店铺间转账使用的系统内置账务类型
+var transferAccountTypes = []struct {
+	code     string
+	name     string
+	category int
+}{
+	{models.AccountTypeCodeTransferOut, "转出到其他店铺", models.AccountCategoryExpense},
+	{models.AccountTypeCodeTransferIn, "从其他店铺转入", models.AccountCategoryIncome},
+}
+
+// ensureTransferAccountTypes 创建店铺间转账使用的系统内置账务类型
+func ensureTransferAccountTypes() error {
+	for _, accountType := range transferAccountTypes {
+		var count int
+		if err := DB.QueryRow("SELECT COUNT(*) FROM account_types WHERE system_code = ?", accountType.code).Scan(&count); err != nil {
+			return fmt.Errorf("检查转账账务类型失败: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		_, err := DB.Exec(
+			"INSERT INTO account_types (name, category, is_expense, system_code) VALUES (?, ?, ?, ?)",
+			accountType.name, accountType.category, accountType.category == models.AccountCategoryExpense, accountType.code,
+		)
+		if err != nil {
+			return fmt.Errorf("创建转账账务类型失败: %w", err)
+		}
+		log.Printf("已创建系统账务类型: %s", accountType.name)
+	}
+	return nil
+}
+
+// getSystemAccountTypeID 获取系统内置账务类型的ID
+func getSystemAccountTypeID(tx *sql.Tx, code string) (int64, error) {
+	var id int64
+	err := tx.QueryRow("SELECT id FROM account_types WHERE system_code = ?", code).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("查询系统账务类型%s失败: %v", code, err)
+	}
+	return id, nil
+}
+
+// CreateTransfer 在同一事务中创建转出店铺的支出记录和转入店铺的收入记录，两条记录互相关联。
+// 调用前需设置Out和In的店铺、记录人、金额、备注和交易时间，交易时间为转出店铺时区的本地时间；
+// 成功后填充ID和账务类型，In的交易时间换算为转入店铺时区的本地时间
+func CreateTransfer(transfer *models.Transfer) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if transfer.Out.TypeID, err = getSystemAccountTypeID(tx, models.AccountTypeCodeTransferOut); err != nil {
+		return err
+	}
+	if transfer.In.TypeID, err = getSystemAccountTypeID(tx, models.AccountTypeCodeTransferIn); err != nil {
+		return err
+	}
+	transfer.Out.IsExpense = true
+	transfer.In.IsExpense = false
+
+	// 两条记录是同一时刻，各自按所在店铺的时区保存
+	outTime, err := resolveAccountTime(tx, transfer.Out.StoreID, transfer.Out.TransactionTime)
+	if err != nil {
+		return err
+	}
+	inTime, err := accountTimeInStore(tx, transfer.In.StoreID, outTime)
+	if err != nil {
+		return err
+	}
+	transfer.In.TransactionTime = inTime.local
+
+	for _, item := range []struct {
+		account         *models.Account
+		transactionTime accountTime
+	}{{&transfer.Out, outTime}, {&transfer.In, inTime}} {
+		account, transactionTime := item.account, item.transactionTime
+		if err := checkPeriodOpen(tx, account.StoreID, transactionTime.local); err != nil {
+			return err
+		}
+		result, err := tx.Exec(`
+			INSERT INTO accounts (store_id, user_id, type_id, amount, remark, transaction_time, utc_offset, create_time, update_time)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, account.StoreID, account.UserID, account.TypeID, account.Amount, account.Remark,
+			transactionTime.utc, transactionTime.offset, dbTime(account.CreateTime), dbTime(account.UpdateTime))
+		if err != nil {
+			return fmt.Errorf("创建转账记录失败: %v", err)
+		}
+		if account.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+	}
+
+	// 关联两条记录
+	transfer.Out.TransferPeerID = transfer.In.ID
+	transfer.In.TransferPeerID = transfer.Out.ID
+	for _, account := range []*models.Account{&transfer.Out, &transfer.In} {
+		if _, err := tx.Exec("UPDATE accounts SET transfer_peer_id = ? WHERE id = ?", account.TransferPeerID, account.ID); err != nil {
+			return fmt.Errorf("关联转账记录失败: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateTransfer 修改转账记录，金额、备注和交易时间同步到对方店铺的记录，两条记录的修改前版本都保存到历史表。
+// 店铺和账务类型保持不变；记录不存在时返回sql.ErrNoRows
+func UpdateTransfer(account *models.Account, changedBy int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	peer := *account
+	peer.ID = account.TransferPeerID
+	peer.TransferPeerID = account.ID
+	err = tx.QueryRow(
+		"SELECT store_id, type_id FROM accounts WHERE id = ? AND deleted_at IS NULL", peer.ID,
+	).Scan(&peer.StoreID, &peer.TypeID)
+	if err != nil {
+		return err
+	}
+
+	// 对方店铺的记录使用同一时刻在其时区的本地时间
+	ownTime, err := resolveAccountTime(tx, account.StoreID, account.TransactionTime)
+	if err != nil {
+		return err
+	}
+	peerTime, err := accountTimeInStore(tx, peer.StoreID, ownTime)
+	if err != nil {
+		return err
+	}
+	peer.TransactionTime = peerTime.local
+
+	for _, item := range []*models.Account{account, &peer} {
+		if err := updateAccountTx(tx, item, changedBy); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AccountStoreType 账目所属店铺和账务类型，没有类型的早期记录TypeID为0
+type AccountStoreType struct {
+	StoreID int64
+	TypeID  int64
+}
+
+// GetAccountStoreTypes 获取账目所属店铺和账务类型，转账记录同时返回对方记录的店铺和类型；
+// 账目不存在或已删除时返回sql.ErrNoRows
+func GetAccountStoreTypes(accountID int) ([]AccountStoreType, error) {
+	rows, err := DB.Query(`
+		SELECT store_id, COALESCE(type_id, 0) FROM accounts
+		WHERE (id = ? OR transfer_peer_id = ?) AND deleted_at IS NULL
+	`, accountID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []AccountStoreType
+	for rows.Next() {
+		var item AccountStoreType
+		if err := rows.Scan(&item.StoreID, &item.TypeID); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return items, nil
+}
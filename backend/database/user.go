@@ -2,9 +2,10 @@ package database
 
 import (
 	"account/backend/models"
+	"account/backend/utils"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
 	"log"
+	"time"
 )
 
 // GetAllUsers 获取所有用户
@@ -38,17 +39,17 @@ func CheckUserExists(username string) (bool, error) {
 	return count > 0, nil
 }
 
-// CreateUser 创建新用户
+// CreateUser 创建新用户，新用户首次登录后必须修改密码
 func CreateUser(user models.User) (int64, error) {
 	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := utils.HashPassword(user.Password)
 	if err != nil {
 		return 0, err
 	}
 
 	result, err := DB.Exec(
-		"INSERT INTO users (username, password, nickname, role) VALUES (?, ?, ?, ?)",
-		user.Username, string(hashedPassword), user.Nickname, user.Role,
+		"INSERT INTO users (username, password, nickname, role, must_change_password) VALUES (?, ?, ?, ?, 1)",
+		user.Username, hashedPassword, user.Nickname, user.Role,
 	)
 	if err != nil {
 		return 0, err
@@ -65,6 +66,16 @@ func UpdateUser(user models.User) error {
 	return err
 }
 
+// UpdateUserProfile 更新用户自己维护的个人资料（昵称、手机、邮箱、头像），为nil的字段保持不变
+func UpdateUserProfile(userID int64, nickname, phone, email, avatar *string) error {
+	_, err := DB.Exec(
+		`UPDATE users SET nickname = COALESCE(?, nickname), phone = COALESCE(?, phone), email = COALESCE(?, email),
+			avatar = COALESCE(?, avatar), update_time = ? WHERE id = ?`,
+		nickname, phone, email, avatar, dbTime(time.Now()), userID,
+	)
+	return err
+}
+
 // DeleteUser 删除用户
 func DeleteUser(userID int64) error {
 	// 先删除用户的店铺权限
@@ -73,23 +84,55 @@ func DeleteUser(userID int64) error {
 		return err
 	}
 
+	// 删除用户的登录会话，使其所有设备立即下线
+	_, err = DB.Exec("DELETE FROM user_sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+
+	// 解除用户绑定的微信等第三方身份
+	_, err = DB.Exec("DELETE FROM user_identities WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+
 	// 删除用户
 	_, err = DB.Exec("DELETE FROM users WHERE id = ?", userID)
 	return err
 }
 
-// ResetUserPassword 重置用户密码
+// ResetUserPassword 管理员重置用户密码，用户下次登录后必须修改密码
 func ResetUserPassword(userID int64, newPassword string) error {
+	return setUserPassword(userID, newPassword, true)
+}
+
+// ChangeUserPassword 用户修改自己的密码，同时解除必须修改密码的限制
+func ChangeUserPassword(userID int64, newPassword string) error {
+	return setUserPassword(userID, newPassword, false)
+}
+
+// setUserPassword 加密并保存用户密码
+func setUserPassword(userID int64, newPassword string, mustChange bool) error {
 	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPassword, err := utils.HashPassword(newPassword)
 	if err != nil {
 		return err
 	}
 
-	_, err = DB.Exec("UPDATE users SET password = ? WHERE id = ?", string(hashedPassword), userID)
+	_, err = DB.Exec(
+		"UPDATE users SET password = ?, must_change_password = ?, update_time = ? WHERE id = ?",
+		hashedPassword, mustChange, dbTime(time.Now()), userID,
+	)
 	return err
 }
 
+// GetUserPasswordHash 获取用户的密码哈希，用于校验旧密码
+func GetUserPasswordHash(userID int64) (string, error) {
+	var hashedPassword string
+	err := DB.QueryRow("SELECT password FROM users WHERE id = ?", userID).Scan(&hashedPassword)
+	return hashedPassword, err
+}
+
 // GetUserStorePermissions 获取用户的店铺权限
 func GetUserStorePermissions(userID int64) ([]models.StorePermission, error) {
 	log.Printf("开始查询用户%d的权限", userID)
@@ -113,6 +156,7 @@ func GetUserStorePermissions(userID int64) ([]models.StorePermission, error) {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
 			store_id INTEGER NOT NULL,
+			role TEXT NOT NULL DEFAULT 'manager',
 			create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
 			FOREIGN KEY (store_id) REFERENCES stores(id) ON DELETE CASCADE,
@@ -154,7 +198,8 @@ func GetUserStorePermissions(userID int64) ([]models.StorePermission, error) {
 	// 获取所有店铺
 	query := `
 		SELECT s.id, s.name,
-		CASE WHEN usp.id IS NULL THEN 0 ELSE 1 END as has_permission
+		CASE WHEN usp.id IS NULL THEN 0 ELSE 1 END as has_permission,
+		COALESCE(usp.role, '') as role
 		FROM stores s
 		LEFT JOIN user_store_permissions usp ON s.id = usp.store_id AND usp.user_id = ?
 		ORDER BY s.name
@@ -171,11 +216,12 @@ func GetUserStorePermissions(userID int64) ([]models.StorePermission, error) {
 	var permissions []models.StorePermission
 	for rows.Next() {
 		var perm models.StorePermission
-		err := rows.Scan(&perm.StoreID, &perm.StoreName, &perm.HasPermission)
+		err := rows.Scan(&perm.StoreID, &perm.StoreName, &perm.HasPermission, &perm.Role)
 		if err != nil {
 			log.Printf("扫描权限数据失败: %v", err)
 			continue
 		}
+		perm.Capabilities = perm.Role.Capabilities()
 		permissions = append(permissions, perm)
 	}
 	
@@ -188,8 +234,9 @@ func GetUserStorePermissions(userID int64) ([]models.StorePermission, error) {
 	return permissions, nil
 }
 
-// UpdateUserStorePermissions 更新用户的店铺权限
-func UpdateUserStorePermissions(userID int64, storeIDs []int64) error {
+// UpdateUserStorePermissions 更新用户的店铺权限及店内角色，
+// 未指定角色的店铺沿用原有角色，新分配的店铺使用默认角色
+func UpdateUserStorePermissions(userID int64, permissions []models.StorePermission) error {
 	// 首先检查表是否存在，不存在则创建
 	var tableExists int
 	err := DB.QueryRow(`
@@ -209,6 +256,7 @@ func UpdateUserStorePermissions(userID int64, storeIDs []int64) error {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
 			store_id INTEGER NOT NULL,
+			role TEXT NOT NULL DEFAULT 'manager',
 			create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
 			FOREIGN KEY (store_id) REFERENCES stores(id) ON DELETE CASCADE,
@@ -227,6 +275,23 @@ func UpdateUserStorePermissions(userID int64, storeIDs []int64) error {
 		return err
 	}
 	defer tx.Rollback()
+
+	// 记录原有的店内角色
+	existingRoles := map[int64]models.StoreRole{}
+	rows, err := tx.Query("SELECT store_id, role FROM user_store_permissions WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var storeID int64
+		var role models.StoreRole
+		if err := rows.Scan(&storeID, &role); err != nil {
+			rows.Close()
+			return err
+		}
+		existingRoles[storeID] = role
+	}
+	rows.Close()
 	
 	// 删除该用户的所有权限
 	_, err = tx.Exec("DELETE FROM user_store_permissions WHERE user_id = ?", userID)
@@ -235,14 +300,22 @@ func UpdateUserStorePermissions(userID int64, storeIDs []int64) error {
 	}
 	
 	// 添加新的权限
-	stmt, err := tx.Prepare("INSERT INTO user_store_permissions (user_id, store_id) VALUES (?, ?)")
+	stmt, err := tx.Prepare("INSERT INTO user_store_permissions (user_id, store_id, role) VALUES (?, ?, ?)")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 	
-	for _, storeID := range storeIDs {
-		_, err = stmt.Exec(userID, storeID)
+	for _, perm := range permissions {
+		// 未指定角色时保留原有角色，新分配的店铺使用默认角色
+		role := perm.Role
+		if role == "" {
+			role = existingRoles[perm.StoreID]
+		}
+		if role == "" {
+			role = models.DefaultStoreRole
+		}
+		_, err = stmt.Exec(userID, perm.StoreID, role)
 		if err != nil {
 			return err
 		}
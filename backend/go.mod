@@ -4,12 +4,13 @@ go 1.23.0
 
 toolchain go1.23.6
 
+require modernc.org/sqlite v1.29.2
+
 require (
-	modernc.org/sqlite v1.29.2
+	github.com/gorilla/mux v1.8.1
+	golang.org/x/crypto v0.35.0
 )
 
-require golang.org/x/crypto v0.35.0
-
 require (
 	github.com/bytedance/sonic v1.11.6 // indirect
 	github.com/bytedance/sonic/loader v0.1.1 // indirect
@@ -24,7 +25,6 @@ require (
 	github.com/go-playground/validator/v10 v10.20.0 // indirect
 	github.com/goccy/go-json v0.10.2 // indirect
 	github.com/google/uuid v1.3.0 // indirect
-	github.com/gorilla/mux v1.8.1 // indirect
 	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.7 // indirect
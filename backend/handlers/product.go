@@ -8,36 +8,19 @@ import (
 	"strings"
 
 	"account/backend/database"
+	"account/backend/middleware"
 	"account/backend/utils"
 )
 
 // 获取产品列表
 func GetProductList(w http.ResponseWriter, r *http.Request) {
-	// 解析请求参数
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Missing user_id parameter")
-		return
-	}
-
-	// 获取用户ID
-	userIDInt, err := strconv.Atoi(userID)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user_id parameter")
-		return
-	}
-
-	// 检查用户是否是管理员
-	isAdmin, err := database.IsUserAdmin(int64(userIDInt))
-	if err != nil {
-		log.Printf("Error checking user admin status: %v\n", err)
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check user permissions")
-		return
-	}
+	// 获取当前登录用户ID
+	userIDInt := int(middleware.CurrentUserID(r))
 
 	var products []map[string]interface{}
+	var err error
 
-	if isAdmin {
+	if middleware.IsAdmin(r) {
 		// 管理员可以看到所有产品
 		products, err = database.GetAllProducts()
 		if err != nil {
@@ -83,12 +66,11 @@ func GetProductList(w http.ResponseWriter, r *http.Request) {
 func AddProduct(w http.ResponseWriter, r *http.Request) {
 	// 解析请求体
 	var productReq struct {
-		UserID      interface{} `json:"user_id"`
-		Name        string      `json:"name"`
-		Description string      `json:"description"`
-		Price       float64     `json:"price"`
-		Stock       int         `json:"stock"`
-		StoreID     int         `json:"store_id"`
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		Price       float64 `json:"price"`
+		Stock       int     `json:"stock"`
+		StoreID     int     `json:"store_id"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&productReq)
@@ -99,45 +81,11 @@ func AddProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 验证必填字段
-	if productReq.UserID == nil || productReq.Name == "" || productReq.Price <= 0 {
+	if productReq.Name == "" || productReq.Price <= 0 {
 		utils.RespondWithError(w, http.StatusBadRequest, "缺少必要的字段")
 		return
 	}
 
-	// 获取用户ID
-	var userID int
-	switch v := productReq.UserID.(type) {
-	case float64:
-		userID = int(v)
-	case int:
-		userID = v
-	case string:
-		var err error
-		userID, err = strconv.Atoi(v)
-		if err != nil {
-			utils.RespondWithError(w, http.StatusBadRequest, "无效的用户ID")
-			return
-		}
-	default:
-		utils.RespondWithError(w, http.StatusBadRequest, "无效的用户ID类型")
-		return
-	}
-
-	// 检查用户权限
-	if productReq.StoreID > 0 {
-		hasPermission, err := database.UserHasStorePermission(userID, productReq.StoreID)
-		if err != nil {
-			log.Printf("Error checking user permission: %v\n", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check user permission")
-			return
-		}
-
-		if !hasPermission {
-			utils.RespondWithError(w, http.StatusForbidden, "No permission to add product to this store")
-			return
-		}
-	}
-
 	// 创建产品对象
 	product := struct {
 		Name        string
@@ -171,8 +119,7 @@ func AddProduct(w http.ResponseWriter, r *http.Request) {
 func DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	// 解析请求体
 	var deleteReq struct {
-		UserID    string `json:"user_id"`
-		ProductID int    `json:"product_id"`
+		ProductID int `json:"product_id"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&deleteReq)
@@ -182,7 +129,7 @@ func DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 验证必填字段
-	if deleteReq.UserID == "" || deleteReq.ProductID <= 0 {
+	if deleteReq.ProductID <= 0 {
 		utils.RespondWithError(w, http.StatusBadRequest, "Missing required fields")
 		return
 	}
@@ -227,19 +174,8 @@ func DeleteProduct(w http.ResponseWriter, r *http.Request) {
 
 // 获取客户的产品列表
 func GetCustomerProducts(w http.ResponseWriter, r *http.Request) {
-	// 解析请求参数
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Missing user_id parameter")
-		return
-	}
-
-	// 获取用户ID
-	userIDInt, err := strconv.Atoi(userID)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user_id parameter")
-		return
-	}
+	// 获取当前登录用户ID
+	userIDInt := int(middleware.CurrentUserID(r))
 
 	// 获取用户有权限的店铺ID列表
 	storeIDs, err := database.GetStoreIDsForUser(userIDInt)
@@ -6,11 +6,14 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"account/backend/api"
 	"account/backend/database"
+	"account/backend/models"
+	"account/backend/services"
 )
 
 func init() {
@@ -54,6 +57,83 @@ func main() {
 		log.Println("客户管理数据库表结构初始化成功")
 	}
 
+	// 创建登录会话表
+	if err := database.CreateSessionTables(); err != nil {
+		log.Printf("会话数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("会话数据库表结构初始化成功")
+	}
+
+	// 创建登录失败记录表
+	if err := database.CreateLoginLockTables(); err != nil {
+		log.Printf("登录锁定数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("登录锁定数据库表结构初始化成功")
+	}
+
+	// 创建微信等第三方身份绑定表
+	if err := database.CreateIdentityTables(); err != nil {
+		log.Printf("身份绑定数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("身份绑定数据库表结构初始化成功")
+	}
+
+	// 创建账务修改历史表
+	if err := database.CreateAccountHistoryTables(); err != nil {
+		log.Printf("账务修改历史数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("账务修改历史数据库表结构初始化成功")
+	}
+
+	// 创建周期账务表
+	if err := database.CreateRecurringTables(); err != nil {
+		log.Printf("周期账务数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("周期账务数据库表结构初始化成功")
+	}
+
+	// 创建结账表
+	if err := database.CreatePeriodTables(); err != nil {
+		log.Printf("结账数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("结账数据库表结构初始化成功")
+	}
+
+	// 创建预算表
+	if err := database.CreateBudgetTables(); err != nil {
+		log.Printf("预算数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("预算数据库表结构初始化成功")
+	}
+
+	// 创建汇率表
+	if err := database.CreateCurrencyTables(); err != nil {
+		log.Printf("汇率数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("汇率数据库表结构初始化成功")
+	}
+
+	// 创建账务附件表
+	if err := database.CreateAttachmentTables(); err != nil {
+		log.Printf("账务附件数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("账务附件数据库表结构初始化成功")
+	}
+
+	// 创建审计日志表
+	if err := database.CreateAuditLogTables(); err != nil {
+		log.Printf("审计日志数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("审计日志数据库表结构初始化成功")
+	}
+
+	// 创建账务类型权限表
+	if err := database.CreateAccountTypePermissionTables(); err != nil {
+		log.Printf("账务类型权限数据库表结构初始化失败: %v", err)
+	} else {
+		log.Println("账务类型权限数据库表结构初始化成功")
+	}
+
 	// 数据库表结构检查已经在InitDB中完成，这里不再重复执行
 	// if err := database.EnsureDatabaseTables(); err != nil {
 	// 	log.Printf("数据库表结构初始化失败: %v", err)
@@ -91,18 +171,40 @@ func main() {
 		log.Println("跳过测试数据初始化，保留现有数据")
 	}
 
+	// 定时清理回收站中超过保留天数的账务记录
+	services.StartRecycleBinPurger(time.Hour)
+	services.StartRecurringScheduler(time.Hour)
+
 	// 实例化处理器
 	userHandler := &api.UserHandler{}
 	accountHandler := &api.AccountHandler{}
 	storeHandler := &api.StoreHandler{}
 	accountTypeHandler := &api.AccountTypeHandler{}
+	recurringEntryHandler := &api.RecurringEntryHandler{}
+	periodHandler := &api.PeriodHandler{}
+	budgetHandler := &api.BudgetHandler{}
+	exchangeRateHandler := &api.ExchangeRateHandler{}
 	settingsHandler := &api.SettingsHandler{}
+	sessionHandler := &api.SessionHandler{}
+	auditHandler := &api.AuditHandler{}
+	wechatHandler := &api.WeChatHandler{Provider: services.NewWeChatProviderFromEnv()}
 
-	// 注册路由 - 使用CORS中间件
+	// 注册路由 - 使用CORS中间件，除登录和刷新令牌接口外均通过Protect认证，并声明各自的访问要求
 	router.HandleFunc("/api/login", api.CORSMiddleware(userHandler.Login)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/login/wechat", api.CORSMiddleware(wechatHandler.Login)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/token/refresh", api.CORSMiddleware(sessionHandler.Refresh)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/logout", api.CORSMiddleware(middleware.ProtectPasswordChange(middleware.Audited(sessionHandler.Logout, models.AuditActionRevoke, middleware.AuditSession, "session_id"), middleware.Authenticated))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/users/change-password", api.CORSMiddleware(middleware.ProtectPasswordChange(middleware.Audited(userHandler.ChangePassword, models.AuditActionUpdate, middleware.AuditUser, ""), middleware.Authenticated))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/users/profile", api.CORSMiddleware(middleware.Protect(userHandler.GetProfile, middleware.Authenticated))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/users/profile", api.CORSMiddleware(middleware.Protect(middleware.Audited(userHandler.UpdateProfile, models.AuditActionUpdate, middleware.AuditUser, ""), middleware.Authenticated))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/users/password-policy", api.CORSMiddleware(middleware.ProtectPasswordChange(userHandler.GetPasswordPolicy, middleware.Authenticated))).Methods("GET", "OPTIONS")
+
+	// 登录设备管理API
+	router.HandleFunc("/api/sessions", api.CORSMiddleware(middleware.Protect(sessionHandler.GetSessions, middleware.Authenticated))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/sessions/revoke", api.CORSMiddleware(middleware.Protect(middleware.Audited(sessionHandler.RevokeSession, models.AuditActionRevoke, middleware.AuditSession, "session_id"), middleware.Authenticated))).Methods("POST", "OPTIONS")
 
 	// 添加查询用户的调试接口
-	router.HandleFunc("/api/debug/users", api.CORSMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	router.HandleFunc("/api/debug/users", api.CORSMiddleware(middleware.Protect(func(w http.ResponseWriter, r *http.Request) {
 		// 简单的调试API，列出所有用户
 		users, err := database.GetAllUsersDebug()
 		if err != nil {
@@ -110,90 +212,134 @@ func main() {
 			return
 		}
 		api.SendResponse(w, http.StatusOK, 200, "成功", users)
-	}))
+	}, middleware.AdminOnly)))
 
 	router.HandleFunc("/test", accountHandler.Test)
 	// 账务相关API
-	router.HandleFunc("/api/accounts", api.CORSMiddleware(accountHandler.List))
-	router.HandleFunc("/api/accounts/create", api.CORSMiddleware(accountHandler.Create))
-	router.HandleFunc("/api/accounts/statistics", api.CORSMiddleware(accountHandler.Statistics))
+	router.HandleFunc("/api/accounts", api.CORSMiddleware(middleware.Protect(accountHandler.List, middleware.StoreAccess("store_id"))))
+	router.HandleFunc("/api/accounts/create", api.CORSMiddleware(middleware.Protect(middleware.Audited(accountHandler.Create, models.AuditActionCreate, middleware.AuditAccount, "id"), middleware.StoreAccess("store_id", models.CapAccountsCreate), middleware.AccountTypeVisible("type_id", "store_id"))))
+	router.HandleFunc("/api/accounts/export", api.CORSMiddleware(middleware.Protect(accountHandler.Export, middleware.StoreAccess("store_id", models.CapReportsView)))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/accounts/import/preview", api.CORSMiddleware(api.LimitUploadSize(middleware.Protect(accountHandler.ImportPreview, middleware.Authenticated), services.ImportMaxSize()))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/accounts/import", api.CORSMiddleware(api.LimitUploadSize(middleware.Protect(accountHandler.Import, middleware.Authenticated), services.ImportMaxSize()))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/accounts/transfer", api.CORSMiddleware(middleware.Protect(middleware.Audited(accountHandler.Transfer, models.AuditActionCreate, middleware.AuditAccount, "id"), middleware.StoreAccess("from_store_id", models.CapAccountsCreate), middleware.StoreAccess("to_store_id", models.CapAccountsCreate)))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/accounts/update", api.CORSMiddleware(middleware.Protect(middleware.Audited(accountHandler.Update, models.AuditActionUpdate, middleware.AuditAccount, "id"), middleware.AccountAccess("id", models.CapAccountsCreate), middleware.StoreAccess("store_id", models.CapAccountsCreate), middleware.AccountTypeVisible("type_id", "store_id")))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/accounts/history", api.CORSMiddleware(middleware.Protect(accountHandler.History, middleware.AccountAccess("id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/accounts/recycle-bin", api.CORSMiddleware(middleware.Protect(accountHandler.RecycleBin, middleware.AdminOnly))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/accounts/restore", api.CORSMiddleware(middleware.Protect(middleware.Audited(accountHandler.Restore, models.AuditActionRestore, middleware.AuditAccount, "id"), middleware.AdminOnly))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/accounts/statistics", api.CORSMiddleware(middleware.Protect(accountHandler.Statistics, middleware.StoreAccess("store_id", models.CapReportsView))))
+	router.HandleFunc("/api/accounts/attachments", api.CORSMiddleware(middleware.Protect(accountHandler.Attachments, middleware.AccountAccess("account_id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/accounts/attachments/upload", api.CORSMiddleware(api.LimitUploadSize(middleware.Protect(middleware.Audited(accountHandler.UploadAttachment, models.AuditActionCreate, middleware.AuditAttachment, "id"), middleware.AccountAccess("account_id", models.CapAccountsCreate)), services.AttachmentMaxSize()))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/accounts/attachments/download", api.CORSMiddleware(middleware.Protect(accountHandler.DownloadAttachment, middleware.AttachmentAccess("id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/accounts/attachments/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(accountHandler.DeleteAttachment, models.AuditActionDelete, middleware.AuditAttachment, "id"), middleware.AttachmentAccess("id", models.CapAccountsCreate)))).Methods("DELETE", "OPTIONS")
+
+	// 周期账务相关API
+	router.HandleFunc("/api/recurring-entries", api.CORSMiddleware(middleware.Protect(recurringEntryHandler.List, middleware.StoreAccess("store_id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/recurring-entries/create", api.CORSMiddleware(middleware.Protect(middleware.Audited(recurringEntryHandler.Create, models.AuditActionCreate, middleware.AuditRecurringEntry, "id"), middleware.StoreAccess("store_id", models.CapAccountsCreate), middleware.AccountTypeVisible("type_id", "store_id")))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/recurring-entries/update", api.CORSMiddleware(middleware.Protect(middleware.Audited(recurringEntryHandler.Update, models.AuditActionUpdate, middleware.AuditRecurringEntry, "id"), middleware.RecurringEntryAccess("id", models.CapAccountsCreate), middleware.StoreAccess("store_id", models.CapAccountsCreate), middleware.AccountTypeVisible("type_id", "store_id")))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/recurring-entries/pause", api.CORSMiddleware(middleware.Protect(middleware.Audited(recurringEntryHandler.Pause, models.AuditActionUpdate, middleware.AuditRecurringEntry, "id"), middleware.RecurringEntryAccess("id", models.CapAccountsCreate)))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/recurring-entries/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(recurringEntryHandler.Delete, models.AuditActionDelete, middleware.AuditRecurringEntry, "id"), middleware.RecurringEntryAccess("id", models.CapAccountsCreate)))).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/recurring-entries/preview", api.CORSMiddleware(middleware.Protect(recurringEntryHandler.Preview, middleware.RecurringEntryAccess("id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/periods/closed", api.CORSMiddleware(middleware.Protect(periodHandler.List, middleware.StoreAccess("store_id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/periods/close", api.CORSMiddleware(middleware.Protect(middleware.Audited(periodHandler.Close, models.AuditActionClose, middleware.AuditPeriodClose, "id"), middleware.AdminOnly))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/periods/reopen", api.CORSMiddleware(middleware.Protect(middleware.Audited(periodHandler.Reopen, models.AuditActionReopen, middleware.AuditPeriodClose, "id"), middleware.AdminOnly))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/periods/logs", api.CORSMiddleware(middleware.Protect(periodHandler.Logs, middleware.AdminOnly))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/budgets", api.CORSMiddleware(middleware.Protect(budgetHandler.List, middleware.StoreAccess("store_id", models.CapReportsView)))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/budgets/create", api.CORSMiddleware(middleware.Protect(middleware.Audited(budgetHandler.Create, models.AuditActionCreate, middleware.AuditBudget, "id"), middleware.StoreAccess("store_id", models.CapBudgetsManage), middleware.AccountTypeVisible("type_id", "store_id")))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/budgets/update", api.CORSMiddleware(middleware.Protect(middleware.Audited(budgetHandler.Update, models.AuditActionUpdate, middleware.AuditBudget, "id"), middleware.BudgetAccess("id", models.CapBudgetsManage), middleware.StoreAccess("store_id", models.CapBudgetsManage), middleware.AccountTypeVisible("type_id", "store_id")))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/budgets/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(budgetHandler.Delete, models.AuditActionDelete, middleware.AuditBudget, "id"), middleware.BudgetAccess("id", models.CapBudgetsManage)))).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/budgets/alerts", api.CORSMiddleware(middleware.Protect(budgetHandler.Alerts, middleware.StoreAccess("store_id", models.CapReportsView)))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/exchange-rates", api.CORSMiddleware(middleware.Protect(exchangeRateHandler.List, middleware.Authenticated))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/exchange-rates/create", api.CORSMiddleware(middleware.Protect(middleware.Audited(exchangeRateHandler.Create, models.AuditActionCreate, middleware.AuditExchangeRate, "id"), middleware.AdminOnly))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/exchange-rates/update", api.CORSMiddleware(middleware.Protect(middleware.Audited(exchangeRateHandler.Update, models.AuditActionUpdate, middleware.AuditExchangeRate, "id"), middleware.AdminOnly))).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/exchange-rates/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(exchangeRateHandler.Delete, models.AuditActionDelete, middleware.AuditExchangeRate, "id"), middleware.AdminOnly))).Methods("DELETE", "OPTIONS")
 
 	// 店铺相关API
-	router.HandleFunc("/api/stores", api.CORSMiddleware(storeHandler.GetUserStores))
-	router.HandleFunc("/api/stores/create", api.CORSMiddleware(storeHandler.CreateStore))
-	router.HandleFunc("/api/stores/update", api.CORSMiddleware(storeHandler.UpdateStore))
-	router.HandleFunc("/api/stores/delete", api.CORSMiddleware(storeHandler.DeleteStore)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/stores", api.CORSMiddleware(middleware.Protect(storeHandler.GetUserStores, middleware.Authenticated)))
+	router.HandleFunc("/api/stores/create", api.CORSMiddleware(middleware.Protect(middleware.Audited(storeHandler.CreateStore, models.AuditActionCreate, middleware.AuditStore, "id"), middleware.AdminOnly)))
+	router.HandleFunc("/api/stores/update", api.CORSMiddleware(middleware.Protect(middleware.Audited(storeHandler.UpdateStore, models.AuditActionUpdate, middleware.AuditStore, "id"), middleware.AdminOnly)))
+	router.HandleFunc("/api/stores/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(storeHandler.DeleteStore, models.AuditActionDelete, middleware.AuditStore, "store_id"), middleware.AdminOnly))).Methods("POST", "OPTIONS")
 
 	// 账务类型相关API
-	router.HandleFunc("/api/account-types", api.CORSMiddleware(accountTypeHandler.GetAll))
-	router.HandleFunc("/api/account-types/create", api.CORSMiddleware(accountTypeHandler.CreateAccountType))
-	router.HandleFunc("/api/account-types/update", api.CORSMiddleware(accountTypeHandler.UpdateAccountType))
-	router.HandleFunc("/api/account-types/delete", api.CORSMiddleware(accountTypeHandler.DeleteAccountType))
+	router.HandleFunc("/api/account-types", api.CORSMiddleware(middleware.Protect(accountTypeHandler.GetAll, middleware.Authenticated)))
+	router.HandleFunc("/api/account-types/create", api.CORSMiddleware(middleware.Protect(middleware.Audited(accountTypeHandler.CreateAccountType, models.AuditActionCreate, middleware.AuditAccountType, "id"), middleware.AdminOnly)))
+	router.HandleFunc("/api/account-types/update", api.CORSMiddleware(middleware.Protect(middleware.Audited(accountTypeHandler.UpdateAccountType, models.AuditActionUpdate, middleware.AuditAccountType, "id"), middleware.AdminOnly)))
+	router.HandleFunc("/api/account-types/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(accountTypeHandler.DeleteAccountType, models.AuditActionDelete, middleware.AuditAccountType, "id"), middleware.AdminOnly)))
+	router.HandleFunc("/api/account-types/permissions", api.CORSMiddleware(middleware.Protect(accountTypeHandler.GetPermissions, middleware.AdminOnly))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/account-types/permissions", api.CORSMiddleware(middleware.Protect(middleware.Audited(accountTypeHandler.UpdatePermissions, models.AuditActionUpdate, middleware.AuditAccountTypePermission, "account_type_id"), middleware.AdminOnly))).Methods("POST", "OPTIONS")
 
 	// 用户管理相关API
-	router.HandleFunc("/api/users", api.CORSMiddleware(userHandler.GetAllUsers))
-	router.HandleFunc("/api/users/create", api.CORSMiddleware(userHandler.CreateUser))
-	router.HandleFunc("/api/users/create-alt", api.CORSMiddleware(userHandler.CreateUserAlt))
-	router.HandleFunc("/api/users/update", api.CORSMiddleware(userHandler.UpdateUser))
-	router.HandleFunc("/api/users/delete", api.CORSMiddleware(userHandler.DeleteUser)).Methods("DELETE", "OPTIONS")
-	router.HandleFunc("/api/users/reset-password", api.CORSMiddleware(userHandler.ResetPassword))
+	router.HandleFunc("/api/users", api.CORSMiddleware(middleware.Protect(userHandler.GetAllUsers, middleware.AdminOnly)))
+	router.HandleFunc("/api/users/create", api.CORSMiddleware(middleware.Protect(middleware.Audited(userHandler.CreateUser, models.AuditActionCreate, middleware.AuditUser, "id"), middleware.AdminOnly)))
+	router.HandleFunc("/api/users/create-alt", api.CORSMiddleware(middleware.Protect(middleware.Audited(userHandler.CreateUserAlt, models.AuditActionCreate, middleware.AuditUser, "id"), middleware.AdminOnly)))
+	router.HandleFunc("/api/users/update", api.CORSMiddleware(middleware.Protect(middleware.Audited(userHandler.UpdateUser, models.AuditActionUpdate, middleware.AuditUser, "id"), middleware.AdminOnly)))
+	router.HandleFunc("/api/users/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(userHandler.DeleteUser, models.AuditActionDelete, middleware.AuditUser, "id"), middleware.AdminOnly))).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/users/reset-password", api.CORSMiddleware(middleware.Protect(middleware.Audited(userHandler.ResetPassword, models.AuditActionUpdate, middleware.AuditUser, "user_id"), middleware.AdminOnly)))
 	// 用户权限API - 使用Methods指定允许的HTTP方法
-	router.HandleFunc("/api/users/permissions", api.CORSMiddleware(userHandler.GetUserStorePermissions)).Methods("GET")
-	router.HandleFunc("/api/users/permissions", api.CORSMiddleware(userHandler.UpdateUserStorePermissions)).Methods("POST")
+	router.HandleFunc("/api/users/permissions", api.CORSMiddleware(middleware.Protect(userHandler.GetUserStorePermissions, middleware.SelfOrAdmin("user_id")))).Methods("GET")
+	router.HandleFunc("/api/users/permissions", api.CORSMiddleware(middleware.Protect(middleware.Audited(userHandler.UpdateUserStorePermissions, models.AuditActionUpdate, middleware.AuditUserStorePermission, "user_id"), middleware.AdminOnly))).Methods("POST")
+	router.HandleFunc("/api/users/login-locks", api.CORSMiddleware(middleware.Protect(userHandler.GetLoginLocks, middleware.AdminOnly))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/users/login-locks/clear", api.CORSMiddleware(middleware.Protect(middleware.Audited(userHandler.ClearLoginLock, models.AuditActionUnlock, middleware.AuditLoginLock, "id"), middleware.AdminOnly))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/wechat/bindings", api.CORSMiddleware(middleware.Protect(wechatHandler.GetBindings, middleware.AdminOnly))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/wechat/bindings/approve", api.CORSMiddleware(middleware.Protect(middleware.Audited(wechatHandler.ApproveBinding, models.AuditActionUpdate, middleware.AuditWeChatBinding, "id"), middleware.AdminOnly))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/wechat/bindings/reject", api.CORSMiddleware(middleware.Protect(middleware.Audited(wechatHandler.RejectBinding, models.AuditActionUpdate, middleware.AuditWeChatBinding, "id"), middleware.AdminOnly))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/wechat/bindings/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(wechatHandler.DeleteBinding, models.AuditActionDelete, middleware.AuditWeChatBinding, "id"), middleware.AdminOnly))).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/audit-logs", api.CORSMiddleware(middleware.Protect(auditHandler.GetLogs, middleware.AdminOnly))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/users/store-roles", api.CORSMiddleware(middleware.Protect(userHandler.GetStoreRoles, middleware.Authenticated))).Methods("GET", "OPTIONS")
 
 	// 默认设置相关路由
-	router.HandleFunc("/api/settings/default", api.CORSMiddleware(settingsHandler.SaveDefaultSettings)).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/settings/default", api.CORSMiddleware(settingsHandler.GetDefaultSettings)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/settings/default", api.CORSMiddleware(middleware.Protect(middleware.Audited(settingsHandler.SaveDefaultSettings, models.AuditActionUpdate, middleware.AuditDefaultSettings, ""), middleware.StoreAccess("store_id")))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/settings/default", api.CORSMiddleware(middleware.Protect(settingsHandler.GetDefaultSettings, middleware.Authenticated))).Methods("GET", "OPTIONS")
 
 	// 删除账目接口 - RESTful风格
-	router.HandleFunc("/api/accounts/{id}", api.CORSMiddleware(api.DeleteAccount)).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/accounts/{id}", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.DeleteAccount, models.AuditActionDelete, middleware.AuditAccount, "id"), middleware.AccountAccess("id", models.CapAccountsDelete)))).Methods("DELETE", "OPTIONS")
 
 	// 也可以添加查询参数风格的接口做兼容
-	router.HandleFunc("/api/account", api.CORSMiddleware(api.DeleteAccountByQuery)).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/account", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.DeleteAccountByQuery, models.AuditActionDelete, middleware.AuditAccount, "id"), middleware.AccountAccess("id", models.CapAccountsDelete)))).Methods("DELETE", "OPTIONS")
 
 	// 在路由部分添加统计报表接口
 	// 统计相关接口
-	router.HandleFunc("/api/statistics/report", api.CORSMiddleware(api.GetReport)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/statistics/report", api.CORSMiddleware(middleware.Protect(api.GetReport, middleware.StoreAccess("storeId", models.CapReportsView)))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/statistics/consolidated", api.CORSMiddleware(middleware.Protect(api.GetConsolidatedReport, middleware.StoreAccess("storeIds", models.CapReportsView)))).Methods("GET", "OPTIONS")
 
 	// 客户管理相关API
-	router.HandleFunc("/api/customers", api.CORSMiddleware(api.GetCustomers)).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/customers/detail", api.CORSMiddleware(api.GetCustomerDetail)).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/customers/create", api.CORSMiddleware(api.CreateCustomer)).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/customers/update", api.CORSMiddleware(api.UpdateCustomer)).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/customers/delete", api.CORSMiddleware(api.DeleteCustomer)).Methods("GET", "DELETE", "OPTIONS")
-	router.HandleFunc("/api/customers/weight-records", api.CORSMiddleware(api.GetWeightRecords)).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/customers/weight-records/add", api.CORSMiddleware(api.AddWeightRecord)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customers", api.CORSMiddleware(middleware.Protect(api.GetCustomers, middleware.StoreAccess("store_id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/customers/detail", api.CORSMiddleware(middleware.Protect(api.GetCustomerDetail, middleware.CustomerAccess("customer_id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/customers/create", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.CreateCustomer, models.AuditActionCreate, middleware.AuditCustomer, "customer_id"), middleware.StoreAccess("store_id", models.CapCustomersEdit)))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customers/update", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.UpdateCustomer, models.AuditActionUpdate, middleware.AuditCustomer, "customer_id"), middleware.CustomerAccess("customer_id", models.CapCustomersEdit), middleware.StoreAccess("store_id", models.CapCustomersEdit)))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customers/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.DeleteCustomer, models.AuditActionDelete, middleware.AuditCustomer, "customer_id"), middleware.CustomerAccess("customer_id", models.CapCustomersEdit)))).Methods("GET", "DELETE", "OPTIONS")
+	router.HandleFunc("/api/customers/weight-records", api.CORSMiddleware(middleware.Protect(api.GetWeightRecords, middleware.CustomerAccess("customer_id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/customers/weight-records/add", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.AddWeightRecord, models.AuditActionCreate, middleware.AuditWeightRecord, "record_id"), middleware.CustomerAccess("customer_id", models.CapCustomersEdit)))).Methods("POST", "OPTIONS")
 	// 添加删除体重记录接口路由
-	router.HandleFunc("/api/customers/delete-weight-record", api.CORSMiddleware(api.DeleteWeightRecord)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customers/delete-weight-record", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.DeleteWeightRecord, models.AuditActionDelete, middleware.AuditWeightRecord, "record_id"), middleware.WeightRecordAccess("record_id", models.CapCustomersEdit)))).Methods("POST", "OPTIONS")
 	// 添加新的体重记录接口路由
-	router.HandleFunc("/api/customers/add-weight-record", api.CORSMiddleware(api.AddWeightRecord)).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/customers/product-usage", api.CORSMiddleware(api.GetProductUsage)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/customers/add-weight-record", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.AddWeightRecord, models.AuditActionCreate, middleware.AuditWeightRecord, "record_id"), middleware.CustomerAccess("customer_id", models.CapCustomersEdit)))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customers/product-usage", api.CORSMiddleware(middleware.Protect(api.GetProductUsage, middleware.CustomerAccess("customer_id")))).Methods("GET", "OPTIONS")
 	// 添加新的产品使用记录接口路由
-	router.HandleFunc("/api/customers/add-product-usage", api.CORSMiddleware(api.AddProductUsage)).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/customers/product-usage/add", api.CORSMiddleware(api.AddProductUsage)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customers/add-product-usage", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.AddProductUsage, models.AuditActionCreate, middleware.AuditProductUsage, "usage_id"), middleware.CustomerAccess("customer_id", models.CapCustomersEdit)))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customers/product-usage/add", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.AddProductUsage, models.AuditActionCreate, middleware.AuditProductUsage, "usage_id"), middleware.CustomerAccess("customer_id", models.CapCustomersEdit)))).Methods("POST", "OPTIONS")
 	// 添加更新产品使用记录接口路由
-	router.HandleFunc("/api/customers/update-product-usage", api.CORSMiddleware(api.UpdateProductUsage)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customers/update-product-usage", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.UpdateProductUsage, models.AuditActionUpdate, middleware.AuditProductUsage, "usage_id"), middleware.CustomerAccess("customer_id", models.CapCustomersEdit), middleware.ProductUsageAccess("usage_id", models.CapCustomersEdit)))).Methods("POST", "OPTIONS")
 	// 添加删除产品使用记录接口路由
-	router.HandleFunc("/api/customers/delete-product-usage", api.CORSMiddleware(api.DeleteProductUsage)).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/customers/products", api.CORSMiddleware(api.GetProducts)).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/customers/records", api.CORSMiddleware(api.GetCustomerRecords)).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/customers/export-report", api.CORSMiddleware(api.ExportCustomerReport)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/customers/delete-product-usage", api.CORSMiddleware(middleware.Protect(middleware.Audited(api.DeleteProductUsage, models.AuditActionDelete, middleware.AuditProductUsage, "usage_id"), middleware.ProductUsageAccess("usage_id", models.CapCustomersEdit)))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customers/products", api.CORSMiddleware(middleware.Protect(api.GetProducts, middleware.StoreAccess("store_id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/customers/records", api.CORSMiddleware(middleware.Protect(api.GetCustomerRecords, middleware.CustomerAccess("customer_id")))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/customers/export-report", api.CORSMiddleware(middleware.Protect(api.ExportCustomerReport, middleware.CustomerAccess("customer_id")))).Methods("GET", "OPTIONS")
 
 	// 产品管理相关API
-	router.HandleFunc("/api/products/list", api.CORSMiddleware(handlers.GetProductList)).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/products/add", api.CORSMiddleware(handlers.AddProduct)).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/products/delete", api.CORSMiddleware(handlers.DeleteProduct)).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/customer/products", api.CORSMiddleware(handlers.GetCustomerProducts)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/products/list", api.CORSMiddleware(middleware.Protect(handlers.GetProductList, middleware.Authenticated))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/products/add", api.CORSMiddleware(middleware.Protect(middleware.Audited(handlers.AddProduct, models.AuditActionCreate, middleware.AuditProduct, "id"), middleware.StoreAccess("store_id", models.CapProductsManage)))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/products/delete", api.CORSMiddleware(middleware.Protect(middleware.Audited(handlers.DeleteProduct, models.AuditActionDelete, middleware.AuditProduct, "product_id"), middleware.ProductAccess("product_id", models.CapProductsManage)))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/customer/products", api.CORSMiddleware(middleware.Protect(handlers.GetCustomerProducts, middleware.Authenticated))).Methods("GET", "OPTIONS")
 
 	// 添加下载报告的路由
-	router.HandleFunc("/api/download/reports/{filename}", api.CORSMiddleware(api.DownloadReport)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/download/reports/{filename}", api.CORSMiddleware(middleware.Protect(api.DownloadReport, middleware.ReportAccess("filename")))).Methods("GET", "OPTIONS")
 
 	// 添加调试API
-	router.HandleFunc("/api/debug/create-test-users", api.CORSMiddleware(api.CreateTestUsers)).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/debug/data", api.CORSMiddleware(api.DebugHandler)).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/debug/query", api.CORSMiddleware(api.ExecuteDebugQuery)).Methods("GET", "POST", "OPTIONS")
-	router.HandleFunc("/api/debug/store", api.CORSMiddleware(api.TestStoreData)).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/debug/permissions", api.CORSMiddleware(api.CheckPermissions)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/debug/create-test-users", api.CORSMiddleware(middleware.Protect(api.CreateTestUsers, middleware.AdminOnly))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/debug/data", api.CORSMiddleware(middleware.Protect(api.DebugHandler, middleware.AdminOnly))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/debug/query", api.CORSMiddleware(middleware.Protect(api.ExecuteDebugQuery, middleware.AdminOnly))).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/api/debug/store", api.CORSMiddleware(middleware.Protect(api.TestStoreData, middleware.AdminOnly))).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/debug/permissions", api.CORSMiddleware(middleware.Protect(api.CheckPermissions, middleware.AdminOnly))).Methods("GET", "OPTIONS")
 
 	log.Printf("服务器启动在 :8080")
 	err := http.ListenAndServe(":8080", router)
@@ -0,0 +1,317 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"account/backend/database"
+	"account/backend/models"
+	"account/backend/utils"
+)
+
+// AuditEntity 审计对象，说明对象保存在哪张表以及如何确定所属店铺
+type AuditEntity struct {
+	Type      string                    // 对象类型，写入审计日志的entity_type
+	Table     string                    // 读取变更前后数据的表
+	KeyColumn string                    // 按该列读取数据，为空时使用id
+	StoreOf   func(id int) (int, error) // 查询对象所属店铺，为空时从数据的store_id列或请求参数中获取
+}
+
+// 需要审计的数据对象
+var (
+	AuditAccount               = AuditEntity{Type: "account", Table: "accounts"}
+	AuditAccountType           = AuditEntity{Type: "account_type", Table: "account_types"}
+	AuditAccountTypePermission = AuditEntity{Type: "account_type_permission", Table: "account_type_permissions", KeyColumn: "account_type_id"}
+	AuditStore                 = AuditEntity{Type: "store", Table: "stores", StoreOf: func(id int) (int, error) { return id, nil }}
+	AuditCustomer              = AuditEntity{Type: "customer", Table: "customers"}
+	AuditWeightRecord          = AuditEntity{Type: "weight_record", Table: "weight_records", StoreOf: database.GetWeightRecordStoreID}
+	AuditProductUsage          = AuditEntity{Type: "product_usage", Table: "product_usages", StoreOf: database.GetProductUsageStoreID}
+	AuditProduct               = AuditEntity{Type: "product", Table: "products"}
+	AuditUser                  = AuditEntity{Type: "user", Table: "users"}
+	AuditUserStorePermission   = AuditEntity{Type: "user_store_permission", Table: "user_store_permissions", KeyColumn: "user_id"}
+	AuditDefaultSettings       = AuditEntity{Type: "default_settings", Table: "user_default_settings", KeyColumn: "user_id"}
+	AuditWeChatBinding         = AuditEntity{Type: "wechat_binding", Table: "user_identities"}
+	AuditRecurringEntry        = AuditEntity{Type: "recurring_entry", Table: "recurring_entries"}
+	AuditAttachment            = AuditEntity{Type: "attachment", Table: "account_attachments", StoreOf: database.GetAttachmentStoreID}
+	AuditPeriodClose           = AuditEntity{Type: "period_close", Table: "period_close_log"}
+	AuditBudget                = AuditEntity{Type: "budget", Table: "budgets"}
+	AuditExchangeRate          = AuditEntity{Type: "exchange_rate", Table: "exchange_rates"}
+	AuditSession               = AuditEntity{Type: "session", Table: "user_sessions"}
+	AuditLoginLock             = AuditEntity{Type: "login_lock", Table: "login_locks"} // 没有ID列，记录请求中的锁定类型和用户名或IP
+)
+
+// Audited 记录数据变更操作的审计日志，必须位于AuthMiddleware之后。
+// idParam指定对象ID参数：更新和删除从请求中读取，创建以及请求中没有ID的操作从成功响应的data中读取；
+// 为空时表示操作当前用户自己的数据。只有处理成功（响应code为200）才写入日志
+func Audited(next http.HandlerFunc, action string, entity AuditEntity, idParam string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next(w, r)
+			return
+		}
+
+		user := CurrentUser(r)
+		if user == nil {
+			next(w, r)
+			return
+		}
+
+		requestBody := readRequestBody(r)
+
+		var entityID int64
+		if idParam == "" {
+			entityID = user.ID
+		} else if action != models.AuditActionCreate {
+			if ids, err := paramIDs(r, idParam); err == nil && len(ids) > 0 {
+				entityID = int64(ids[0])
+			}
+		}
+
+		var before interface{}
+		if entityID > 0 && action != models.AuditActionCreate {
+			before = LoadAuditSnapshot(entity, entityID)
+		}
+		// 删除后对象可能已无法查询所属店铺，先确定店铺
+		storeID := auditStoreID(r, entity, entityID, before)
+
+		recorder := &auditResponseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		data, ok := recorder.successData()
+		if !ok {
+			return
+		}
+
+		if entityID == 0 && action != models.AuditActionDelete {
+			entityID = responseID(data, idParam)
+		}
+
+		var after interface{}
+		if action != models.AuditActionDelete {
+			if entityID > 0 {
+				after = LoadAuditSnapshot(entity, entityID)
+			}
+			if after == nil {
+				// 无法按ID读取时记录请求内容
+				after = sanitizeRequestBody(requestBody)
+			}
+		}
+		if storeID == 0 {
+			storeID = auditStoreID(r, entity, entityID, after)
+		}
+
+		RecordAudit(r, action, entity, entityID, storeID, before, after)
+	}
+}
+
+// RecordAudit 以当前用户的身份写入一条审计日志，用于批量导入等无法由Audited按单个对象记录的操作
+func RecordAudit(r *http.Request, action string, entity AuditEntity, entityID, storeID int64, before, after interface{}) {
+	user := CurrentUser(r)
+	if user == nil {
+		return
+	}
+	entry := models.AuditLog{
+		UserID:     user.ID,
+		Action:     action,
+		EntityType: entity.Type,
+		EntityID:   entityID,
+		StoreID:    storeID,
+		Before:     marshalAuditData(before),
+		After:      marshalAuditData(after),
+		IP:         ClientIP(r),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}
+	if err := database.CreateAuditLog(entry); err != nil {
+		log.Printf("写入审计日志失败: %v", err)
+	}
+}
+
+// ClientIP 获取客户端IP。只有直接连接的地址是TRUSTED_PROXIES中配置的反向代理时才使用X-Forwarded-For，
+// 从右向左跳过受信任的代理，取第一个不受信任的地址，避免客户端伪造IP绕过或触发按IP的登录锁定
+func ClientIP(r *http.Request) string {
+	ip := remoteIP(r)
+	proxies := trustedProxies()
+	if !isTrustedProxy(ip, proxies) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop, proxies) {
+			break
+		}
+	}
+	return ip
+}
+
+// remoteIP 直接连接的地址
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// trustedProxies 受信任的反向代理，TRUSTED_PROXIES为逗号分隔的IP或CIDR，未配置时不信任任何代理
+func trustedProxies() []*net.IPNet {
+	var proxies []*net.IPNet
+	for _, value := range strings.Split(utils.GetEnvWithDefault("TRUSTED_PROXIES", ""), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			log.Printf("警告: TRUSTED_PROXIES中的 %s 不是有效的IP或CIDR，已忽略", value)
+			continue
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies
+}
+
+// isTrustedProxy 判断地址是否为受信任的反向代理
+func isTrustedProxy(value string, proxies []*net.IPNet) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadAuditSnapshot 读取对象当前的数据，失败时只记录日志；处理函数调用RecordAudit前后用它读取变更前后的数据
+func LoadAuditSnapshot(entity AuditEntity, id int64) interface{} {
+	keyColumn := entity.KeyColumn
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+	snapshot, err := database.GetAuditSnapshot(entity.Table, keyColumn, id)
+	if err != nil {
+		log.Printf("读取审计数据失败: %v", err)
+		return nil
+	}
+	return snapshot
+}
+
+// auditStoreID 确定对象所属店铺：优先使用StoreOf，其次是数据中的store_id，最后是请求中的store_id参数
+func auditStoreID(r *http.Request, entity AuditEntity, entityID int64, snapshot interface{}) int64 {
+	if entity.StoreOf != nil && entityID > 0 {
+		if storeID, err := entity.StoreOf(int(entityID)); err == nil {
+			return int64(storeID)
+		}
+	}
+	if record, ok := snapshot.(map[string]interface{}); ok {
+		if storeID, ok := record["store_id"].(int64); ok && storeID > 0 {
+			return storeID
+		}
+	}
+	if ids, err := paramIDs(r, "store_id"); err == nil && len(ids) == 1 {
+		return int64(ids[0])
+	}
+	return 0
+}
+
+// readRequestBody 读取请求体，读取后恢复请求体供处理器使用
+func readRequestBody(r *http.Request) []byte {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewBuffer(body))
+	return body
+}
+
+// sanitizeRequestBody 解析JSON请求体并去掉密码字段
+func sanitizeRequestBody(body []byte) interface{} {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+	for key := range fields {
+		if strings.Contains(strings.ToLower(key), "password") {
+			delete(fields, key)
+		}
+	}
+	return fields
+}
+
+// responseID 从响应data中读取新建对象的ID
+func responseID(data map[string]interface{}, idParam string) int64 {
+	for _, key := range []string{idParam, "id"} {
+		if id, ok := data[key].(float64); ok && id > 0 {
+			return int64(id)
+		}
+	}
+	return 0
+}
+
+// marshalAuditData 将审计数据序列化为JSON，没有数据时返回nil
+func marshalAuditData(data interface{}) json.RawMessage {
+	if data == nil {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("序列化审计数据失败: %v", err)
+		return nil
+	}
+	return raw
+}
+
+// auditResponseRecorder 记录响应状态和内容，用于判断操作是否成功
+type auditResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *auditResponseRecorder) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *auditResponseRecorder) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// successData 响应成功时返回其中的data对象（非对象时为空）
+func (rw *auditResponseRecorder) successData() (map[string]interface{}, bool) {
+	if rw.status != http.StatusOK {
+		return nil, false
+	}
+	var resp struct {
+		Code int             `json:"code"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rw.body.Bytes(), &resp); err != nil || resp.Code != 200 {
+		return nil, false
+	}
+	data := map[string]interface{}{}
+	json.Unmarshal(resp.Data, &data)
+	return data, true
+}
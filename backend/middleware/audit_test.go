@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		trusted   string
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{name: "未配置代理时忽略X-Forwarded-For", remote: "198.51.100.9:5000", forwarded: "203.0.113.7", want: "198.51.100.9"},
+		{name: "直接连接的地址不是代理", trusted: "10.0.0.1", remote: "198.51.100.9:5000", forwarded: "203.0.113.7", want: "198.51.100.9"},
+		{name: "受信任的代理", trusted: "10.0.0.1", remote: "10.0.0.1:5000", forwarded: "203.0.113.7", want: "203.0.113.7"},
+		{name: "客户端伪造的地址在左侧", trusted: "10.0.0.0/8", remote: "10.0.0.1:5000", forwarded: "1.2.3.4, 203.0.113.7, 10.0.0.2", want: "203.0.113.7"},
+		{name: "代理未传递X-Forwarded-For", trusted: "10.0.0.1", remote: "10.0.0.1:5000", want: "10.0.0.1"},
+		{name: "无效的地址", trusted: "10.0.0.1", remote: "10.0.0.1:5000", forwarded: "203.0.113.7, garbage", want: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.trusted)
+			r := httptest.NewRequest("POST", "/api/login", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"account/backend/database"
+	"account/backend/models"
+	"account/backend/services"
+	"account/backend/utils"
+)
+
+// contextKey 请求上下文中使用的键类型，避免与其他包冲突
+type contextKey string
+
+const (
+	currentUserKey    contextKey = "currentUser"
+	currentSessionKey contextKey = "currentSession"
+)
+
+var userService services.UserService
+
+// AuthMiddleware 校验Authorization头中的令牌，并将当前用户写入请求上下文
+func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := extractToken(r)
+		if token == "" {
+			utils.RespondWithJSON(w, http.StatusUnauthorized, 401, "未登录或登录已失效", nil)
+			return
+		}
+
+		claims, err := utils.ParseToken(token)
+		if err != nil {
+			log.Printf("令牌校验失败: %v", err)
+			utils.RespondWithJSON(w, http.StatusUnauthorized, 401, err.Error(), nil)
+			return
+		}
+
+		// 会话被注销（退出登录、强制下线）后，未过期的访问令牌也立即失效
+		active, err := database.IsSessionActive(claims.SessionID)
+		if err != nil {
+			log.Printf("检查会话%d状态失败: %v", claims.SessionID, err)
+			utils.RespondWithJSON(w, http.StatusInternalServerError, 500, "检查登录状态失败", nil)
+			return
+		}
+		if !active {
+			utils.RespondWithJSON(w, http.StatusUnauthorized, 401, "登录已失效，请重新登录", nil)
+			return
+		}
+
+		// 每次请求都从数据库加载用户，确保已删除用户和角色变更立即生效
+		user, err := userService.GetUserByID(claims.UserID)
+		if err != nil {
+			log.Printf("令牌对应的用户%d不存在: %v", claims.UserID, err)
+			utils.RespondWithJSON(w, http.StatusUnauthorized, 401, "用户不存在或已被删除", nil)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), currentUserKey, user)
+		ctx = context.WithValue(ctx, currentSessionKey, claims.SessionID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// CurrentUser 获取经过认证的当前用户，未经过AuthMiddleware时返回nil
+func CurrentUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(currentUserKey).(*models.User)
+	return user
+}
+
+// CurrentUserID 获取当前用户ID，未认证时返回0
+func CurrentUserID(r *http.Request) int64 {
+	if user := CurrentUser(r); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// CurrentSessionID 获取当前请求所属的登录会话ID，未认证时返回0
+func CurrentSessionID(r *http.Request) int64 {
+	sessionID, _ := r.Context().Value(currentSessionKey).(int64)
+	return sessionID
+}
+
+// extractToken 从"Authorization: Bearer <token>"头中取出令牌
+func extractToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if header == "" {
+		return ""
+	}
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return header
+}
@@ -0,0 +1,410 @@
+package middleware
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"account/backend/database"
+	"account/backend/models"
+	"account/backend/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// Requirement 路由声明的访问要求，返回false表示当前用户无权访问
+type Requirement func(r *http.Request, user *models.User) (bool, error)
+
+// Protect 为路由加上令牌认证和访问要求校验，每个受保护的路由都必须声明至少一个要求。
+// 需要修改密码的用户在修改密码前不能访问
+func Protect(next http.HandlerFunc, requirements ...Requirement) http.HandlerFunc {
+	if len(requirements) == 0 {
+		panic("受保护的路由必须声明访问要求")
+	}
+	return AuthMiddleware(requirePasswordChanged(Authorize(next, requirements...)))
+}
+
+// ProtectPasswordChange 与Protect相同，但允许需要修改密码的用户访问，仅用于修改密码和退出登录
+func ProtectPasswordChange(next http.HandlerFunc, requirements ...Requirement) http.HandlerFunc {
+	if len(requirements) == 0 {
+		panic("受保护的路由必须声明访问要求")
+	}
+	return AuthMiddleware(Authorize(next, requirements...))
+}
+
+// requirePasswordChanged 新建或被重置密码的用户只能先修改密码，必须位于AuthMiddleware之后
+func requirePasswordChanged(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := CurrentUser(r)
+		if user != nil && user.MustChangePassword {
+			utils.RespondWithJSON(w, http.StatusForbidden, 403, "请先修改密码", map[string]interface{}{
+				"must_change_password": true,
+			})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Authorize 依次校验所有访问要求，全部满足才调用处理器，必须位于AuthMiddleware之后
+func Authorize(next http.HandlerFunc, requirements ...Requirement) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := CurrentUser(r)
+		if user == nil {
+			utils.RespondWithJSON(w, http.StatusUnauthorized, 401, "未登录或登录已失效", nil)
+			return
+		}
+
+		for _, requirement := range requirements {
+			allowed, err := requirement(r, user)
+			if errors.Is(err, sql.ErrNoRows) {
+				utils.RespondWithJSON(w, http.StatusNotFound, 404, "请求的资源不存在", nil)
+				return
+			}
+			if err != nil {
+				log.Printf("校验用户%d的访问权限失败: %v", user.ID, err)
+				utils.RespondWithJSON(w, http.StatusInternalServerError, 500, "检查用户权限失败", nil)
+				return
+			}
+			if !allowed {
+				log.Printf("权限拒绝: 用户%d访问 %s %s", user.ID, r.Method, r.URL.Path)
+				utils.RespondWithJSON(w, http.StatusForbidden, 403, "无权限执行此操作", nil)
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
+
+// Authenticated 任何已登录用户均可访问
+func Authenticated(r *http.Request, user *models.User) (bool, error) {
+	return true, nil
+}
+
+// AdminOnly 仅管理员可以访问
+func AdminOnly(r *http.Request, user *models.User) (bool, error) {
+	return isAdmin(user), nil
+}
+
+// SelfOrAdmin 参数指定的用户必须是当前用户本人，管理员不受限制
+func SelfOrAdmin(param string) Requirement {
+	return func(r *http.Request, user *models.User) (bool, error) {
+		if isAdmin(user) {
+			return true, nil
+		}
+		ids, err := paramIDs(r, param)
+		if err != nil {
+			return false, nil
+		}
+		for _, id := range ids {
+			if int64(id) != user.ID {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// StoreAccess 参数指定的店铺（支持逗号分隔的多个ID）必须是当前用户有权限的店铺，
+// 并且用户在这些店铺内的角色拥有全部指定能力，未传参数时不校验
+func StoreAccess(param string, capabilities ...models.Capability) Requirement {
+	return func(r *http.Request, user *models.User) (bool, error) {
+		storeIDs, err := paramIDs(r, param)
+		if err != nil {
+			return false, nil
+		}
+		for _, storeID := range storeIDs {
+			allowed, err := hasStoreAccess(user, storeID, capabilities)
+			if err != nil || !allowed {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// CustomerAccess 参数指定的客户必须属于当前用户有权限的店铺
+func CustomerAccess(param string, capabilities ...models.Capability) Requirement {
+	return resourceStoreAccess(param, database.GetCustomerStoreID, capabilities)
+}
+
+// ReportAccess 路由参数指定的客户报告文件所属客户必须属于当前用户有权限的店铺，
+// 文件名不是客户报告或客户不存在时返回404
+func ReportAccess(param string, capabilities ...models.Capability) Requirement {
+	return func(r *http.Request, user *models.User) (bool, error) {
+		customerID, ok := database.ReportCustomerID(mux.Vars(r)[param])
+		if !ok {
+			return false, sql.ErrNoRows
+		}
+		storeID, err := database.GetCustomerStoreID(customerID)
+		if err != nil {
+			return false, err
+		}
+		return hasStoreAccess(user, storeID, capabilities)
+	}
+}
+
+// RecurringEntryAccess 参数指定的周期账务模板必须属于当前用户有权限的店铺
+func RecurringEntryAccess(param string, capabilities ...models.Capability) Requirement {
+	return resourceStoreAccess(param, database.GetRecurringEntryStoreID, capabilities)
+}
+
+// BudgetAccess 参数指定的预算必须属于当前用户有权限的店铺
+func BudgetAccess(param string, capabilities ...models.Capability) Requirement {
+	return resourceStoreAccess(param, database.GetBudgetStoreID, capabilities)
+}
+
+// AttachmentAccess 参数指定的附件所属账目必须属于当前用户有权限的店铺
+func AttachmentAccess(param string, capabilities ...models.Capability) Requirement {
+	return resourceStoreAccess(param, database.GetAttachmentStoreID, capabilities)
+}
+
+// AccountAccess 参数指定的账目必须属于当前用户有权限的店铺，转账记录需要同时拥有双方店铺的权限。
+// 账目现有的账务类型（转账时包括对方记录的类型）也必须对当前用户可见，不可见时按账目不存在返回404
+func AccountAccess(param string, capabilities ...models.Capability) Requirement {
+	return func(r *http.Request, user *models.User) (bool, error) {
+		ids, err := paramIDs(r, param)
+		if err != nil {
+			return false, nil
+		}
+		for _, id := range ids {
+			items, err := database.GetAccountStoreTypes(id)
+			if err != nil {
+				return false, err
+			}
+			for _, item := range items {
+				allowed, err := hasStoreAccess(user, int(item.StoreID), capabilities)
+				if err != nil || !allowed {
+					return false, err
+				}
+				if isAdmin(user) || item.TypeID == 0 {
+					continue
+				}
+				visible, err := database.IsAccountTypeVisible(user.ID, item.TypeID, item.StoreID)
+				if err != nil {
+					return false, err
+				}
+				if !visible {
+					return false, sql.ErrNoRows
+				}
+			}
+		}
+		return true, nil
+	}
+}
+
+// ProductAccess 参数指定的产品必须属于当前用户有权限的店铺
+func ProductAccess(param string, capabilities ...models.Capability) Requirement {
+	return resourceStoreAccess(param, database.GetProductStoreID, capabilities)
+}
+
+// WeightRecordAccess 参数指定的体重记录必须属于当前用户有权限店铺的客户
+func WeightRecordAccess(param string, capabilities ...models.Capability) Requirement {
+	return resourceStoreAccess(param, database.GetWeightRecordStoreID, capabilities)
+}
+
+// ProductUsageAccess 参数指定的产品使用记录必须属于当前用户有权限店铺的客户
+func ProductUsageAccess(param string, capabilities ...models.Capability) Requirement {
+	return resourceStoreAccess(param, database.GetProductUsageStoreID, capabilities)
+}
+
+// AccountTypeVisible 参数指定的账务类型必须对当前用户在参数指定的店铺内可见，管理员不受限制
+func AccountTypeVisible(typeParam, storeParam string) Requirement {
+	return func(r *http.Request, user *models.User) (bool, error) {
+		if isAdmin(user) {
+			return true, nil
+		}
+		typeIDs, err := paramIDs(r, typeParam)
+		if err != nil {
+			return false, nil
+		}
+		storeIDs, err := paramIDs(r, storeParam)
+		if err != nil {
+			return false, nil
+		}
+		for _, typeID := range typeIDs {
+			for _, storeID := range storeIDs {
+				visible, err := database.IsAccountTypeVisible(user.ID, int64(typeID), int64(storeID))
+				if err != nil || !visible {
+					return false, err
+				}
+			}
+		}
+		return true, nil
+	}
+}
+
+// resourceStoreAccess 通过资源所属店铺判断访问权限，资源不存在时返回sql.ErrNoRows
+func resourceStoreAccess(param string, storeOf func(id int) (int, error), capabilities []models.Capability) Requirement {
+	return func(r *http.Request, user *models.User) (bool, error) {
+		ids, err := paramIDs(r, param)
+		if err != nil {
+			return false, nil
+		}
+		for _, id := range ids {
+			storeID, err := storeOf(id)
+			if err != nil {
+				return false, err
+			}
+			allowed, err := hasStoreAccess(user, storeID, capabilities)
+			if err != nil || !allowed {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// hasStoreAccess 检查用户是否分配了该店铺且店内角色拥有全部指定能力，管理员不受限制
+func hasStoreAccess(user *models.User, storeID int, capabilities []models.Capability) (bool, error) {
+	if isAdmin(user) {
+		return true, nil
+	}
+
+	role, assigned, err := database.GetUserStoreRole(int(user.ID), storeID)
+	if err != nil || !assigned {
+		return false, err
+	}
+
+	for _, capability := range capabilities {
+		if !role.Can(capability) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// IsAdmin 判断当前请求的用户是否为管理员，供处理器决定数据范围
+func IsAdmin(r *http.Request) bool {
+	user := CurrentUser(r)
+	return user != nil && isAdmin(user)
+}
+
+// CanAccessStore 判断当前请求的用户能否在店铺中执行指定操作，供需要逐条检查店铺的处理器使用
+func CanAccessStore(r *http.Request, storeID int64, capabilities ...models.Capability) (bool, error) {
+	user := CurrentUser(r)
+	if user == nil {
+		return false, nil
+	}
+	return hasStoreAccess(user, int(storeID), capabilities)
+}
+
+// isAdmin 判断用户是否为管理员，用户信息由AuthMiddleware从数据库加载
+func isAdmin(user *models.User) bool {
+	return user.Role == models.RoleAdmin
+}
+
+// paramIDs 从路径变量、查询参数或JSON请求体中读取ID参数，未传或为空值时返回空列表，格式错误时返回错误。
+// 处理器从请求体读取参数，因此请求体中有该参数时以请求体为准，且必须与路径变量或查询参数一致
+func paramIDs(r *http.Request, param string) ([]int, error) {
+	raw, ok := mux.Vars(r)[param]
+	if !ok {
+		raw = r.URL.Query().Get(param)
+	}
+	ids, err := parseIDs(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	value, inBody, err := bodyParam(r, param)
+	if err != nil || !inBody {
+		return ids, err
+	}
+	bodyIDs, err := parseIDs(value)
+	if err != nil {
+		return nil, err
+	}
+	if raw != "" && !sameIDs(ids, bodyIDs) {
+		return nil, errors.New("请求参数与请求体中的" + param + "不一致")
+	}
+	return bodyIDs, nil
+}
+
+// parseIDs 解析逗号分隔的ID列表
+func parseIDs(raw string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "0" || part == "undefined" || part == "null" {
+			continue
+		}
+		// 前端可能以浮点数形式传递ID
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || value < 0 {
+			return nil, errors.New("无效的ID参数: " + part)
+		}
+		ids = append(ids, int(value))
+	}
+	return ids, nil
+}
+
+// sameIDs 判断两个ID列表是否相同
+func sameIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// bodyParam 读取JSON请求体中的参数，读取后恢复请求体供处理器使用。
+// 与处理器使用encoding/json解码到结构体的规则一致：键名不区分大小写，同名的键以最后一个为准，
+// 只解析请求体中的第一个JSON值。请求体不是JSON对象时返回false，参数不是数字、字符串或null时返回错误
+func bodyParam(r *http.Request, param string) (string, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return "", false, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewBuffer(body))
+	if err != nil || len(body) == 0 {
+		return "", false, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return "", false, nil
+	}
+
+	var value string
+	found := false
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", false, nil
+		}
+		key, _ := token.(string)
+		var field interface{}
+		if err := decoder.Decode(&field); err != nil {
+			return "", false, nil
+		}
+		if !strings.EqualFold(key, param) {
+			continue
+		}
+
+		found = true
+		switch v := field.(type) {
+		case json.Number:
+			value = v.String()
+		case string:
+			value = v
+		case nil:
+			value = ""
+		default:
+			return "", false, errors.New("无效的ID参数: " + param)
+		}
+	}
+	return value, found, nil
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParamIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		body    string
+		want    []int
+		wantErr bool
+	}{
+		{name: "查询参数", target: "/?store_id=2", want: []int{2}},
+		{name: "逗号分隔", target: "/?store_id=1,%203", want: []int{1, 3}},
+		{name: "请求体", target: "/", body: `{"store_id":2}`, want: []int{2}},
+		{name: "查询参数与请求体一致", target: "/?store_id=2", body: `{"store_id":2}`, want: []int{2}},
+		{name: "查询参数与请求体不一致", target: "/?store_id=2", body: `{"store_id":1}`, wantErr: true},
+		{name: "键名不区分大小写且以最后一个为准", target: "/", body: `{"store_id":2,"STORE_ID":1}`, want: []int{1}},
+		{name: "只解析第一个JSON值", target: "/", body: `{"store_id":2} {"store_id":1}`, want: []int{2}},
+		{name: "请求体中为null", target: "/", body: `{"store_id":null}`, want: nil},
+		{name: "请求体中为对象", target: "/", body: `{"store_id":{"id":1}}`, wantErr: true},
+		{name: "浮点数形式", target: "/", body: `{"store_id":2.0}`, want: []int{2}},
+		{name: "无效的ID", target: "/?store_id=abc", wantErr: true},
+		{name: "非JSON请求体", target: "/?store_id=2", body: "store_id=1", want: []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.target, strings.NewReader(tt.body))
+			got, err := paramIDs(r, "store_id")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("paramIDs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !sameIDs(got, tt.want) {
+				t.Errorf("paramIDs() = %v, want %v", got, tt.want)
+			}
+
+			// 请求体读取后需要恢复供处理器使用
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != tt.body {
+				t.Errorf("请求体未恢复: %q", body)
+			}
+		})
+	}
+}
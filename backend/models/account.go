@@ -2,15 +2,53 @@ package models
 
 import "time"
 
+// DateTimeLayout 交易时间的格式；数据库中保存UTC时间，接口中为店铺时区的本地时间
+const DateTimeLayout = "2006-01-02 15:04:05"
+
 // Account 账务记录模型
 type Account struct {
 	ID             int64     `json:"id" db:"id"`
 	StoreID        int64     `json:"store_id" db:"store_id"`
 	UserID         int64     `json:"user_id" db:"user_id"`
 	TypeID         int64     `json:"type_id" db:"type_id"`
-	Amount         float64   `json:"amount" db:"amount"`
+	Amount         Money     `json:"amount" db:"amount"` // 单位为分，始终为正数
+	Currency       string    `json:"currency,omitempty" db:"currency"` // 币种，为空时使用店铺的币种
+	IsExpense      bool      `json:"is_expense" db:"-"`   // 收支方向，由账务类型决定
+	TransferPeerID int64     `json:"transfer_peer_id,omitempty" db:"transfer_peer_id"` // 店铺间转账时对方店铺的记录ID
 	Remark         string    `json:"remark" db:"remark"`
-	TransactionTime string    `json:"transaction_time" db:"transaction_time"`
+	TransactionTime string    `json:"transaction_time" db:"transaction_time"` // 店铺时区的本地时间
 	CreateTime     time.Time `json:"create_time" db:"create_time"`
 	UpdateTime     time.Time `json:"update_time" db:"update_time"`
-} 
\ No newline at end of file
+} 
+// AccountHistory 账务记录修改前的版本，每次修改保存一条
+type AccountHistory struct {
+	ID              int64     `json:"id" db:"id"`
+	AccountID       int64     `json:"account_id" db:"account_id"`
+	Version         int       `json:"version" db:"version"` // 从1开始，1为最初录入的内容
+	StoreID         int64     `json:"store_id" db:"store_id"`
+	UserID          int64     `json:"user_id" db:"user_id"`
+	TypeID          int64     `json:"type_id" db:"type_id"`
+	Amount          Money     `json:"amount" db:"amount"`
+	Currency        string    `json:"currency,omitempty" db:"currency"`
+	Remark          string    `json:"remark" db:"remark"`
+	TransactionTime string    `json:"transaction_time" db:"transaction_time"`
+	ChangedBy       int64     `json:"changed_by" db:"changed_by"` // 将该版本修改掉的用户
+	ChangedByName   string    `json:"changed_by_name" db:"-"`
+	ChangeTime      time.Time `json:"change_time" db:"change_time"`
+}
+
+// DeletedAccount 回收站中的账务记录
+type DeletedAccount struct {
+	Account
+	StoreName     string    `json:"store_name" db:"-"`
+	TypeName      string    `json:"type_name" db:"-"`
+	DeletedAt     time.Time `json:"deleted_at" db:"deleted_at"`
+	DeletedBy     int64     `json:"deleted_by" db:"deleted_by"`
+	DeletedByName string    `json:"deleted_by_name" db:"-"`
+}
+
+// Transfer 店铺间转账，由转出店铺的支出记录和转入店铺的收入记录组成
+type Transfer struct {
+	Out Account `json:"out"` // 转出店铺的记录
+	In  Account `json:"in"`  // 转入店铺的记录
+}
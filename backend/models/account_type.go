@@ -1,5 +1,17 @@
 package models
 
+// 账务类型的收支方向，对应account_types.category，账务记录的收支方向由其类型决定
+const (
+	AccountCategoryIncome  = 1 // 收入
+	AccountCategoryExpense = 2 // 支出
+)
+
+// 系统内置的账务类型，仅用于店铺间转账，不出现在类型列表中且不能修改
+const (
+	AccountTypeCodeTransferOut = "transfer_out" // 转出
+	AccountTypeCodeTransferIn  = "transfer_in"  // 转入
+)
+
 // AccountType 结构体
 type AccountType struct {
 	ID        int64  `json:"id"`
@@ -8,4 +20,24 @@ type AccountType struct {
 	Icon      string `json:"icon"`
 	Order     int    `json:"order"`
 	IsExpense bool   `json:"is_expense"`
-} 
\ No newline at end of file
+}
+
+// NormalizeCategory 统一收支方向：未指定有效的category时按is_expense确定，is_expense始终与category一致
+func (t *AccountType) NormalizeCategory() {
+	if t.Type != AccountCategoryIncome && t.Type != AccountCategoryExpense {
+		if t.IsExpense {
+			t.Type = AccountCategoryExpense
+		} else {
+			t.Type = AccountCategoryIncome
+		}
+	}
+	t.IsExpense = t.Type == AccountCategoryExpense
+}
+
+// AccountTypePermission 账务类型的查看权限，未设置任何用户和角色时所有人可见
+type AccountTypePermission struct {
+	AccountTypeID int64       `json:"account_type_id"`
+	UserIDs       []int64     `json:"user_ids"`    // 可查看的用户
+	StoreRoles    []StoreRole `json:"store_roles"` // 可查看的店内角色，按账目所属店铺的角色匹配
+	Restricted    bool        `json:"restricted"`  // 是否限制了查看范围
+}
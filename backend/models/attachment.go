@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Attachment 账务记录的附件，如收据照片、发票文件
+type Attachment struct {
+	ID             int64     `json:"id" db:"id"`
+	AccountID      int64     `json:"account_id" db:"account_id"`
+	FileName       string    `json:"file_name" db:"file_name"` // 上传时的原始文件名
+	ContentType    string    `json:"content_type" db:"content_type"`
+	Size           int64     `json:"size" db:"size"`
+	StorageKey     string    `json:"-" db:"storage_key"`   // 文件在存储中的路径
+	ThumbnailKey   string    `json:"-" db:"thumbnail_key"` // 缩略图在存储中的路径，非图片为空
+	HasThumbnail   bool      `json:"has_thumbnail" db:"-"`
+	UploadedBy     int64     `json:"uploaded_by" db:"uploaded_by"`
+	UploadedByName string    `json:"uploaded_by_name" db:"-"`
+	CreateTime     time.Time `json:"create_time" db:"create_time"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 审计日志的操作类型
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionImport  = "import"
+	AuditActionClose   = "close"
+	AuditActionReopen  = "reopen"
+	AuditActionRevoke  = "revoke" // 注销登录会话
+	AuditActionUnlock  = "unlock" // 解除登录锁定
+	AuditActionPurge   = "purge"  // 永久删除回收站中的记录
+	AuditActionSkip    = "skip"   // 周期账务因所在月份已结账跳过生成
+)
+
+// 后台任务写入的审计日志，user_id为AuditSystemUserID，method为AuditMethodSystem，path为任务名称
+const (
+	AuditSystemUserID int64 = 0
+	AuditMethodSystem       = "SYSTEM"
+)
+
+// AuditLog 数据变更审计记录，保存操作人、操作对象及变更前后的数据
+type AuditLog struct {
+	ID         int64           `json:"id" db:"id"`
+	UserID     int64           `json:"user_id" db:"user_id"`
+	Username   string          `json:"username" db:"-"`
+	Action     string          `json:"action" db:"action"`
+	EntityType string          `json:"entity_type" db:"entity_type"`
+	EntityID   int64           `json:"entity_id" db:"entity_id"`
+	StoreID    int64           `json:"store_id" db:"store_id"` // 所属店铺，无法确定时为0
+	Before     json.RawMessage `json:"before,omitempty" db:"before_data"`
+	After      json.RawMessage `json:"after,omitempty" db:"after_data"`
+	IP         string          `json:"ip" db:"ip"`
+	Method     string          `json:"method" db:"method"`
+	Path       string          `json:"path" db:"path"`
+	CreateTime time.Time       `json:"create_time" db:"create_time"`
+}
+
+// AuditLogFilter 审计日志查询条件，零值表示不限
+type AuditLogFilter struct {
+	UserID     int64
+	StoreID    int64
+	EntityType string
+	EntityID   int64
+	Action     string
+	StartDate  string // 格式 2006-01-02
+	EndDate    string
+	Page       int
+	PageSize   int
+}
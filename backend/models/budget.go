@@ -0,0 +1,69 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// 预算执行状态
+const (
+	BudgetStatusOK       = "ok"       // 未超支
+	BudgetStatusTrending = "trending" // 按当前进度预计到月底会超支
+	BudgetStatusOver     = "over"     // 已超支
+)
+
+// Budget 店铺的月度支出预算，TypeID为0时表示店铺全部支出类型的总预算
+type Budget struct {
+	ID         int64     `json:"id"`
+	StoreID    int64     `json:"store_id"`
+	StoreName  string    `json:"store_name"`
+	TypeID     int64     `json:"type_id"`
+	TypeName   string    `json:"type_name"`
+	Period     string    `json:"period"` // 格式 2006-01
+	Amount     Money     `json:"amount"`
+	Spent      Money     `json:"spent"` // 该月已发生的支出，只读
+	Note       string    `json:"note"`
+	CreatedBy  int64     `json:"created_by"`
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+// BudgetUsage 预算执行情况
+type BudgetUsage struct {
+	StoreID     int64   `json:"store_id,omitempty"`
+	StoreName   string  `json:"store_name,omitempty"`
+	TypeID      int64   `json:"type_id"`
+	TypeName    string  `json:"type_name"`
+	Period      string  `json:"period,omitempty"`
+	Budget      Money   `json:"budget"`                 // 报表中为按时间范围覆盖的天数折算后的预算
+	MonthBudget Money   `json:"month_budget,omitempty"` // 报表时间范围涉及月份的全月预算合计
+	Actual      Money   `json:"actual"`
+	Remaining   Money   `json:"remaining"`    // 剩余预算，超支时为负数
+	PercentUsed float64 `json:"percent_used"` // 已使用的百分比，保留两位小数
+	Projected   Money   `json:"projected"`    // 按当前进度预计的全月支出
+	Status      string  `json:"status"`
+}
+
+// NewBudgetUsage 按预算和实际支出计算执行情况，projected为预计支出，为0时按实际支出判断
+func NewBudgetUsage(budget, actual, projected Money) BudgetUsage {
+	if projected < actual {
+		projected = actual
+	}
+	usage := BudgetUsage{
+		Budget:    budget,
+		Actual:    actual,
+		Remaining: budget - actual,
+		Projected: projected,
+		Status:    BudgetStatusOK,
+	}
+	if budget > 0 {
+		usage.PercentUsed = math.Round(float64(actual)/float64(budget)*10000) / 100
+	}
+	switch {
+	case actual > budget:
+		usage.Status = BudgetStatusOver
+	case projected > budget:
+		usage.Status = BudgetStatusTrending
+	}
+	return usage
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultCurrency 未设置币种的店铺使用的默认币种
+const DefaultCurrency = "CNY"
+
+// NormalizeCurrency 将币种代码统一为大写的ISO 4217三位字母代码，格式无效时返回false
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", false
+		}
+	}
+	return code, true
+}
+
+// ExchangeRate 汇率，1单位FromCurrency折合Rate单位ToCurrency，从EffectiveDate起生效直到下一条汇率
+type ExchangeRate struct {
+	ID            int64     `json:"id"`
+	FromCurrency  string    `json:"from_currency"`
+	ToCurrency    string    `json:"to_currency"`
+	Rate          float64   `json:"rate"`
+	EffectiveDate string    `json:"effective_date"` // 格式 2006-01-02
+	CreatedBy     int64     `json:"created_by"`
+	CreateTime    time.Time `json:"create_time"`
+	UpdateTime    time.Time `json:"update_time"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// 第三方身份绑定状态
+const (
+	IdentityStatusPending  = "pending"  // 等待管理员审核
+	IdentityStatusActive   = "active"   // 已绑定，可直接登录
+	IdentityStatusRejected = "rejected" // 管理员已拒绝
+)
+
+// IdentityProviderWeChat 微信小程序登录
+const IdentityProviderWeChat = "wechat"
+
+// UserIdentity 第三方身份（如微信openid）与用户的绑定关系
+type UserIdentity struct {
+	ID                int64      `json:"id" db:"id"`
+	Provider          string     `json:"provider" db:"provider"`
+	Subject           string     `json:"subject" db:"subject"` // 第三方用户标识，微信为openid
+	UnionID           string     `json:"union_id,omitempty" db:"union_id"`
+	UserID            *int64     `json:"user_id,omitempty" db:"user_id"` // 待审核时为空
+	Username          string     `json:"username,omitempty" db:"-"`      // 绑定用户的用户名
+	RequestedUsername string     `json:"requested_username" db:"requested_username"`
+	Remark            string     `json:"remark" db:"remark"`
+	Status            string     `json:"status" db:"status"`
+	CreateTime        time.Time  `json:"create_time" db:"create_time"`
+	ReviewTime        *time.Time `json:"review_time,omitempty" db:"review_time"`
+	ReviewedBy        *int64     `json:"reviewed_by,omitempty" db:"reviewed_by"`
+}
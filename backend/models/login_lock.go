@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// 登录失败计数的维度
+const (
+	LoginLockScopeUsername = "username"  // 按用户名计数
+	LoginLockScopeIP       = "ip"        // 按客户端IP计数
+	LoginLockScopeWeChatIP = "wechat_ip" // 按客户端IP统计微信登录凭证无效和提交绑定申请的次数
+)
+
+// LoginLockKey 登录失败记录的计数维度和用户名或IP
+type LoginLockKey struct {
+	Scope string
+	Key   string
+}
+
+// LoginLock 某个用户名或客户端IP的登录失败记录，失败次数过多时被临时锁定
+type LoginLock struct {
+	Scope        string     `json:"scope" db:"scope"`
+	Key          string     `json:"key" db:"lock_key"`
+	FailedCount  int        `json:"failed_count" db:"failed_count"`
+	LastFailedAt time.Time  `json:"last_failed_at" db:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	Locked       bool       `json:"locked" db:"-"` // 当前是否处于锁定状态
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Money 以分为单位的金额，数据库中保存为整数，JSON中以两位小数的数字表示，避免浮点误差
+type Money int64
+
+// maxMoneyDigits 金额整数部分允许的最大位数，保证换算成分后不会溢出
+const maxMoneyDigits = 15
+
+// ErrInvalidMoney 金额格式错误
+var ErrInvalidMoney = errors.New("金额格式错误，最多保留两位小数")
+
+// ParseMoney 按十进制精确解析金额，如"12.3"、"-0.05"，不经过浮点数换算
+func ParseMoney(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+
+	integer, fraction, _ := strings.Cut(value, ".")
+	if (integer == "" && fraction == "") || len(integer) > maxMoneyDigits || len(fraction) > 2 {
+		return 0, ErrInvalidMoney
+	}
+	for _, c := range integer + fraction {
+		if c < '0' || c > '9' {
+			return 0, ErrInvalidMoney
+		}
+	}
+
+	fraction += strings.Repeat("0", 2-len(fraction))
+	cents, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMoney
+	}
+	if negative {
+		cents = -cents
+	}
+	return Money(cents), nil
+}
+
+// String 格式化为两位小数，如"-12.30"。按无符号数取绝对值，最小的int64也不会溢出
+func (m Money) String() string {
+	cents := uint64(m)
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return sign + strconv.FormatUint(cents/100, 10) + "." + strconv.FormatUint(cents%100+100, 10)[1:]
+}
+
+// MarshalJSON 输出为JSON数字，保留两位小数
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 接受JSON数字或字符串形式的金额
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	value, err := ParseMoney(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*m = value
+	return nil
+}
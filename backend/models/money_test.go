@@ -0,0 +1,135 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{input: "12.3", want: 1230},
+		{input: "12.30", want: 1230},
+		{input: "0.05", want: 5},
+		{input: ".5", want: 50},
+		{input: "5.", want: 500},
+		{input: "+7", want: 700},
+		{input: " 8.01 ", want: 801},
+		{input: "-0.05", want: -5},
+		{input: "-12.34", want: -1234},
+		{input: "-0", want: 0},
+		{input: "999999999999999.99", want: 99999999999999999},
+		{input: "-999999999999999.99", want: -99999999999999999},
+
+		// 超过两位小数时报错，不做四舍五入
+		{input: "1.005", wantErr: true},
+		{input: "0.125", wantErr: true},
+		{input: "1.000", wantErr: true},
+
+		// 不接受指数形式
+		{input: "1e2", wantErr: true},
+		{input: "1E-2", wantErr: true},
+		{input: "1.5e1", wantErr: true},
+
+		// 整数部分超过15位时报错，避免换算成分后溢出
+		{input: "1000000000000000", wantErr: true},
+		{input: "9223372036854775807", wantErr: true},
+		{input: "-92233720368547758.08", wantErr: true},
+
+		{input: "", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: ".", wantErr: true},
+		{input: "--1", wantErr: true},
+		{input: "1,000", wantErr: true},
+		{input: "1.2.3", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: "NaN", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMoney(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{money: 0, want: "0.00"},
+		{money: 5, want: "0.05"},
+		{money: 50, want: "0.50"},
+		{money: 1230, want: "12.30"},
+		{money: -5, want: "-0.05"},
+		{money: -105, want: "-1.05"},
+		{money: -1234, want: "-12.34"},
+		{money: 99999999999999999, want: "999999999999999.99"},
+		{money: math.MaxInt64, want: "92233720368547758.07"},
+		{money: math.MinInt64, want: "-92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := json.Marshal(tt.money)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("json.Marshal(%d) = %s, want %s", tt.money, got, tt.want)
+			}
+
+			// 输出的结果可以原样解析回来
+			if tt.money == math.MaxInt64 || tt.money == math.MinInt64 {
+				return
+			}
+			var parsed Money
+			if err := json.Unmarshal(got, &parsed); err != nil {
+				t.Fatalf("json.Unmarshal(%s) error = %v", got, err)
+			}
+			if parsed != tt.money {
+				t.Errorf("json.Unmarshal(%s) = %d, want %d", got, parsed, tt.money)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{input: `12.3`, want: 1230},
+		{input: `"12.30"`, want: 1230},
+		{input: `-0.05`, want: -5},
+		{input: `null`, want: 100}, // null时保持原值
+		{input: `1e3`, wantErr: true},
+		{input: `0.001`, wantErr: true},
+		{input: `"abc"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := Money(100)
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("json.Unmarshal(%s) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("json.Unmarshal(%s) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PeriodLayout 账期的格式，按自然月结账
+const PeriodLayout = "2006-01"
+
+// 结账记录的操作类型
+const (
+	PeriodActionClose  = "close"  // 结账
+	PeriodActionReopen = "reopen" // 重新开账
+)
+
+// ErrPeriodClosed 账务记录所在的账期已结账
+var ErrPeriodClosed = errors.New("账期已结账")
+
+// ErrTimezoneLocked 店铺已有结账的月份，修改时区会改变已结账记录的所属日期
+var ErrTimezoneLocked = errors.New("店铺已有结账的月份，不能修改时区")
+
+// PeriodClosedError 账期已结账的错误，说明是哪个店铺的哪个月
+type PeriodClosedError struct {
+	StoreID int64
+	Period  string
+}
+
+// Error 返回给用户的提示
+func (e *PeriodClosedError) Error() string {
+	return fmt.Sprintf("%s已结账，不能新增、修改或删除该月的账务记录，如需修改请联系管理员重新开账", e.Period)
+}
+
+// Is 使errors.Is(err, ErrPeriodClosed)成立
+func (e *PeriodClosedError) Is(target error) bool {
+	return target == ErrPeriodClosed
+}
+
+// ClosedPeriod 店铺已结账的月份
+type ClosedPeriod struct {
+	StoreID      int64     `json:"store_id" db:"store_id"`
+	StoreName    string    `json:"store_name" db:"-"`
+	Period       string    `json:"period" db:"period"`
+	ClosedBy     int64     `json:"closed_by" db:"closed_by"`
+	ClosedByName string    `json:"closed_by_name" db:"-"`
+	ClosedAt     time.Time `json:"closed_at" db:"closed_at"`
+	Note         string    `json:"note" db:"note"`
+}
+
+// PeriodCloseLog 结账和重新开账的操作记录
+type PeriodCloseLog struct {
+	ID         int64     `json:"id" db:"id"`
+	StoreID    int64     `json:"store_id" db:"store_id"`
+	StoreName  string    `json:"store_name" db:"-"`
+	Period     string    `json:"period" db:"period"`
+	Action     string    `json:"action" db:"action"`
+	UserID     int64     `json:"user_id" db:"user_id"`
+	Username   string    `json:"username" db:"-"`
+	Note       string    `json:"note" db:"note"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
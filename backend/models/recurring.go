@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// 周期账务的重复频率
+const (
+	RecurrenceWeekly  = "weekly"  // 每周
+	RecurrenceMonthly = "monthly" // 每月
+	RecurrenceYearly  = "yearly"  // 每年
+)
+
+// DateLayout 周期账务中日期字段的格式
+const DateLayout = "2006-01-02"
+
+// RecurringEntry 周期账务模板，到期时按模板自动生成账务记录，如房租、工资、订阅费
+type RecurringEntry struct {
+	ID         int64     `json:"id" db:"id"`
+	StoreID    int64     `json:"store_id" db:"store_id"`
+	StoreName  string    `json:"store_name" db:"-"`
+	TypeID     int64     `json:"type_id" db:"type_id"`
+	TypeName   string    `json:"type_name" db:"-"`
+	Amount     Money     `json:"amount" db:"amount"`
+	Remark     string    `json:"remark" db:"remark"`
+	Frequency  string    `json:"frequency" db:"frequency"`
+	DayOfMonth int       `json:"day_of_month" db:"day_of_month"` // 每月或每年的第几天，超过当月天数时取月末
+	Weekday    int       `json:"weekday" db:"weekday"`           // 每周的星期几，0为周日
+	Month      int       `json:"month" db:"month"`               // 每年的月份，1-12
+	StartDate  string    `json:"start_date" db:"start_date"`     // 格式 2006-01-02
+	EndDate    string    `json:"end_date" db:"end_date"`         // 为空表示不结束
+	NextDate   string    `json:"next_date" db:"next_date"`       // 下一次生成的日期，为空表示已结束
+	Paused     bool      `json:"paused" db:"paused"`
+	CreatedBy  int64     `json:"created_by" db:"created_by"` // 创建人，生成的账务记录以其为记录人
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+	UpdateTime time.Time `json:"update_time" db:"update_time"`
+}
+
+// ValidateSchedule 检查重复规则是否有效，返回错误提示，有效时返回空字符串
+func (e *RecurringEntry) ValidateSchedule() string {
+	switch e.Frequency {
+	case RecurrenceWeekly:
+		if e.Weekday < 0 || e.Weekday > 6 {
+			return "星期必须在0-6之间"
+		}
+	case RecurrenceMonthly:
+		if e.DayOfMonth < 1 || e.DayOfMonth > 31 {
+			return "日期必须在1-31之间"
+		}
+	case RecurrenceYearly:
+		if e.Month < 1 || e.Month > 12 {
+			return "月份必须在1-12之间"
+		}
+		if e.DayOfMonth < 1 || e.DayOfMonth > 31 {
+			return "日期必须在1-31之间"
+		}
+	default:
+		return "重复频率无效，可选weekly、monthly、yearly"
+	}
+	return ""
+}
+
+// Occurrence 返回from当天或之后的第一个生成日期，只比较日期部分
+func (e *RecurringEntry) Occurrence(from time.Time) time.Time {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+
+	switch e.Frequency {
+	case RecurrenceWeekly:
+		return from.AddDate(0, 0, (e.Weekday-int(from.Weekday())+7)%7)
+	case RecurrenceYearly:
+		next := clampedDate(from.Year(), time.Month(e.Month), e.DayOfMonth, from.Location())
+		if next.Before(from) {
+			next = clampedDate(from.Year()+1, time.Month(e.Month), e.DayOfMonth, from.Location())
+		}
+		return next
+	default:
+		next := clampedDate(from.Year(), from.Month(), e.DayOfMonth, from.Location())
+		if next.Before(from) {
+			next = clampedDate(from.Year(), from.Month()+1, e.DayOfMonth, from.Location())
+		}
+		return next
+	}
+}
+
+// clampedDate 返回指定年月的第day天，超过当月天数时取月末
+func clampedDate(year int, month time.Month, day int, loc *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRefreshTokenUsed 刷新令牌已被轮换、会话已注销或已过期，刷新失败
+var ErrRefreshTokenUsed = errors.New("刷新令牌已失效")
+
+// Session 登录会话，每个设备登录后生成一条，保存刷新令牌的哈希
+type Session struct {
+	ID               int64      `json:"id" db:"id"`
+	UserID           int64      `json:"user_id" db:"user_id"`
+	RefreshTokenHash string     `json:"-" db:"refresh_token_hash"` // 不在JSON响应中返回令牌
+	Device           string     `json:"device" db:"device"`
+	UserAgent        string     `json:"user_agent" db:"user_agent"`
+	IP               string     `json:"ip" db:"ip"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt       time.Time  `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt        time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	Current          bool       `json:"current" db:"-"` // 是否为发起请求的会话
+}
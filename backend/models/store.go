@@ -6,6 +6,8 @@ type Store struct {
 	Name    string `json:"name" db:"name"`
 	Address string `json:"address" db:"address"`
 	Phone   string `json:"phone" db:"phone"`
+	Currency string `json:"currency" db:"currency"` // 店铺记账使用的币种
+	Timezone string `json:"timezone" db:"timezone"` // IANA时区名称，为空时使用业务时区
 }
 
 // StorePermission 用户的店铺权限
@@ -13,4 +15,6 @@ type StorePermission struct {
 	StoreID      int64  `json:"store_id"`
 	StoreName    string `json:"store_name"`
 	HasPermission int    `json:"has_permission"` // 0-无权限, 1-有权限
+	Role         StoreRole    `json:"role"`         // 店铺内角色，无权限时为空
+	Capabilities []Capability `json:"capabilities"` // 角色拥有的能力
 } 
\ No newline at end of file
@@ -0,0 +1,100 @@
+package models
+
+// StoreRole 用户在某个店铺内的角色
+type StoreRole string
+
+const (
+	StoreRoleManager StoreRole = "manager" // 店长
+	StoreRoleCashier StoreRole = "cashier" // 收银员
+	StoreRoleViewer  StoreRole = "viewer"  // 查看者
+)
+
+// DefaultStoreRole 新分配店铺时未指定角色使用的默认角色，只授予权限最低的查看者。
+// 升级前已有的分配由数据库迁移设为店长，不受影响
+const DefaultStoreRole = StoreRoleViewer
+
+// Capability 店铺内的操作能力
+type Capability string
+
+const (
+	CapAccountsCreate Capability = "accounts.create" // 记账
+	CapAccountsDelete Capability = "accounts.delete" // 删除账目
+	CapCustomersEdit  Capability = "customers.edit"  // 编辑客户及其记录
+	CapProductsManage Capability = "products.manage" // 管理产品
+	CapReportsView    Capability = "reports.view"    // 查看报表
+	CapBudgetsManage  Capability = "budgets.manage"  // 管理预算
+)
+
+// StoreRoleInfo 店铺角色说明，用于前端展示可分配的角色
+type StoreRoleInfo struct {
+	Role         StoreRole    `json:"role"`
+	Name         string       `json:"name"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+// storeRoles 各店铺角色的名称和能力，按权限从高到低排列
+var storeRoles = []StoreRoleInfo{
+	{
+		Role: StoreRoleManager,
+		Name: "店长",
+		Capabilities: []Capability{
+			CapAccountsCreate, CapAccountsDelete, CapCustomersEdit, CapProductsManage, CapReportsView, CapBudgetsManage,
+		},
+	},
+	{
+		Role:         StoreRoleCashier,
+		Name:         "收银员",
+		Capabilities: []Capability{CapAccountsCreate, CapCustomersEdit},
+	},
+	{
+		Role:         StoreRoleViewer,
+		Name:         "查看者",
+		Capabilities: []Capability{CapReportsView},
+	},
+}
+
+// StoreRoles 返回所有可分配的店铺角色
+func StoreRoles() []StoreRoleInfo {
+	return storeRoles
+}
+
+// IsValid 检查是否为已定义的店铺角色
+func (r StoreRole) IsValid() bool {
+	for _, info := range storeRoles {
+		if info.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities 返回角色拥有的能力，未知角色没有任何能力
+func (r StoreRole) Capabilities() []Capability {
+	for _, info := range storeRoles {
+		if info.Role == r {
+			return info.Capabilities
+		}
+	}
+	return []Capability{}
+}
+
+// Can 检查角色是否拥有指定能力
+func (r StoreRole) Can(capability Capability) bool {
+	for _, c := range r.Capabilities() {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesWith 返回拥有指定能力的店铺角色
+func RolesWith(capability Capability) []StoreRole {
+	var roles []StoreRole
+	for _, info := range storeRoles {
+		if info.Role.Can(capability) {
+			roles = append(roles, info.Role)
+		}
+	}
+	return roles
+}
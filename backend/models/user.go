@@ -25,4 +25,5 @@ type User struct {
 	CreateTime time.Time `json:"create_time" db:"create_time"`
 	UpdateTime time.Time `json:"update_time" db:"update_time"`
 	LastLogin  time.Time `json:"last_login" db:"last_login"`
+	MustChangePassword bool `json:"must_change_password" db:"must_change_password"` // 修改密码前只能调用修改密码接口
 } 
\ No newline at end of file
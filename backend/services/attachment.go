@@ -0,0 +1,185 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"account/backend/database"
+	"account/backend/models"
+	"account/backend/utils"
+)
+
+// thumbnailSize 缩略图的最大宽高
+const thumbnailSize = 240
+
+// thumbnailMaxPixels 生成缩略图的图片最大像素数。图片解码时按声明的尺寸分配内存，
+// 很小的PNG或GIF也可以声明极大的尺寸，超过该像素数的图片不生成缩略图
+const thumbnailMaxPixels = 40_000_000
+
+// attachmentTypes 允许上传的附件类型及保存时使用的扩展名
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+var (
+	// ErrAttachmentTooLarge 附件超过大小限制
+	ErrAttachmentTooLarge = errors.New("附件大小超过限制")
+	// ErrAttachmentType 附件类型不允许上传
+	ErrAttachmentType = errors.New("只支持上传JPG、PNG、GIF、WEBP图片和PDF文件")
+)
+
+// AttachmentMaxSize 单个附件的最大字节数，通过环境变量ATTACHMENT_MAX_SIZE_MB配置，默认10MB
+func AttachmentMaxSize() int64 {
+	return int64(utils.GetIntEnvWithDefault("ATTACHMENT_MAX_SIZE_MB", 10)) << 20
+}
+
+// SaveAttachment 保存上传的附件并关联到账务记录，图片会同时生成缩略图。
+// 文件类型按内容判断，不信任客户端提供的类型
+func SaveAttachment(accountID, uploadedBy int64, fileName string, content io.Reader) (*models.Attachment, error) {
+	maxSize := AttachmentMaxSize()
+	data, err := io.ReadAll(io.LimitReader(content, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取附件失败: %v", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := attachmentTypes[contentType]
+	if !ok {
+		return nil, ErrAttachmentType
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	attachment := &models.Attachment{
+		AccountID:   accountID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  fmt.Sprintf("accounts/%d/%s%s", accountID, name, ext),
+		UploadedBy:  uploadedBy,
+		CreateTime:  time.Now(),
+	}
+
+	storage := AttachmentStorage()
+	if err := storage.Save(attachment.StorageKey, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("保存附件文件失败: %v", err)
+	}
+
+	if thumbnail, err := makeThumbnail(data); err == nil {
+		key := fmt.Sprintf("accounts/%d/%s_thumb.jpg", accountID, name)
+		if err := storage.Save(key, bytes.NewReader(thumbnail)); err != nil {
+			log.Printf("保存附件缩略图失败: %v", err)
+		} else {
+			attachment.ThumbnailKey = key
+			attachment.HasThumbnail = true
+		}
+	}
+
+	id, err := database.CreateAttachment(attachment)
+	if err != nil {
+		removeAttachmentFiles(attachment)
+		return nil, err
+	}
+	attachment.ID = id
+	return attachment, nil
+}
+
+// DeleteAttachment 删除附件记录和文件，附件不存在时返回sql.ErrNoRows
+func DeleteAttachment(id int64) error {
+	attachment, err := database.GetAttachment(id)
+	if err != nil {
+		return err
+	}
+	if err := database.DeleteAttachment(id); err != nil {
+		return err
+	}
+	removeAttachmentFiles(attachment)
+	return nil
+}
+
+// removeAttachmentFiles 删除附件及其缩略图文件，失败时只记录日志
+func removeAttachmentFiles(attachment *models.Attachment) {
+	storage := AttachmentStorage()
+	for _, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := storage.Delete(key); err != nil {
+			log.Printf("删除附件文件%s失败: %v", key, err)
+		}
+	}
+}
+
+// makeThumbnail 将图片等比缩小到thumbnailSize以内并编码为JPEG，无法解码或尺寸过大的文件返回错误
+func makeThumbnail(data []byte) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, errors.New("图片尺寸无效")
+	}
+	if int64(config.Width)*int64(config.Height) > thumbnailMaxPixels {
+		return nil, fmt.Errorf("图片尺寸%dx%d过大，不生成缩略图", config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, errors.New("图片尺寸无效")
+	}
+	scale := float64(thumbnailSize) / float64(max(width, height))
+	if scale > 1 {
+		scale = 1
+	}
+	dstWidth, dstHeight := max(int(float64(width)*scale), 1), max(int(float64(height)*scale), 1)
+
+	// 按最近邻取样缩放
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		srcY := bounds.Min.Y + y*height/dstHeight
+		for x := 0; x < dstWidth; x++ {
+			dst.Set(x, y, src.At(bounds.Min.X+x*width/dstWidth, srcY))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// randomName 生成随机文件名，避免文件名冲突和被猜测
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
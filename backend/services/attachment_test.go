@@ -0,0 +1,56 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// pngWithSize 生成一个1x1的PNG，并把IHDR中声明的尺寸改为width x height
+func pngWithSize(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// 8字节文件头之后是IHDR：4字节长度、4字节类型、13字节数据、4字节CRC
+	ihdr := data[16:29]
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestMakeThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 600, 300))); err != nil {
+		t.Fatal(err)
+	}
+	thumbnail, err := makeThumbnail(buf.Bytes())
+	if err != nil {
+		t.Fatalf("makeThumbnail() error = %v", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != thumbnailSize || config.Height != thumbnailSize/2 {
+		t.Errorf("缩略图尺寸为%dx%d", config.Width, config.Height)
+	}
+}
+
+func TestMakeThumbnailRejectsHugeImage(t *testing.T) {
+	// 声明的尺寸为10亿像素，解码会分配数GB内存
+	data := pngWithSize(t, 50000, 20000)
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil || config.Width != 50000 {
+		t.Fatalf("构造的PNG无效: %v", err)
+	}
+	if _, err := makeThumbnail(data); err == nil {
+		t.Fatal("声明尺寸过大的图片不应生成缩略图")
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+
+	"account/backend/database"
+	"account/backend/models"
+)
+
+// recordSystemAudit 记录后台任务对数据的修改，job为任务名称，失败时只记录日志
+func recordSystemAudit(job, action, entityType string, entityID, storeID int64, before, after interface{}) {
+	entry := models.AuditLog{
+		UserID:     models.AuditSystemUserID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		StoreID:    storeID,
+		Before:     marshalSystemAudit(before),
+		After:      marshalSystemAudit(after),
+		Method:     models.AuditMethodSystem,
+		Path:       job,
+	}
+	if err := database.CreateAuditLog(entry); err != nil {
+		log.Printf("写入%s的审计日志失败: %v", job, err)
+	}
+}
+
+// marshalSystemAudit 将审计数据序列化为JSON，没有数据时返回nil
+func marshalSystemAudit(data interface{}) json.RawMessage {
+	if data == nil {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("序列化审计数据失败: %v", err)
+		return nil
+	}
+	return raw
+}
+
+// auditRecordID 读取审计数据中的整数列，不存在时返回0
+func auditRecordID(record map[string]interface{}, column string) int64 {
+	id, _ := record[column].(int64)
+	return id
+}
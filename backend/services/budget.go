@@ -0,0 +1,32 @@
+package services
+
+import (
+	"time"
+
+	"account/backend/database"
+	"account/backend/models"
+)
+
+// BudgetAlerts 获取now所在月份已超支或按当前进度预计超支的预算。
+// 预计支出按已发生支出和本月已过天数线性推算；storeIDs为nil时不限店铺，非管理员只包含有权查看的类型
+func BudgetAlerts(storeIDs []int64, now time.Time, userID int64, isAdmin bool) ([]models.BudgetUsage, error) {
+	budgets, err := database.GetBudgets(storeIDs, now.Format(models.PeriodLayout), userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	daysInMonth := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location()).Day()
+	alerts := []models.BudgetUsage{}
+	for _, budget := range budgets {
+		projected := models.Money(int64(budget.Spent) * int64(daysInMonth) / int64(now.Day()))
+		usage := models.NewBudgetUsage(budget.Amount, budget.Spent, projected)
+		if usage.Status == models.BudgetStatusOK {
+			continue
+		}
+		usage.StoreID, usage.StoreName = budget.StoreID, budget.StoreName
+		usage.TypeID, usage.TypeName = budget.TypeID, budget.TypeName
+		usage.Period = budget.Period
+		alerts = append(alerts, usage)
+	}
+	return alerts, nil
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"log"
+
+	"account/backend/models"
+	"account/backend/utils"
+)
+
+// ReportCurrency 未指定报表币种且无法按店铺确定时使用的币种，通过环境变量REPORT_CURRENCY配置
+func ReportCurrency() string {
+	value := utils.GetEnvWithDefault("REPORT_CURRENCY", models.DefaultCurrency)
+	currency, ok := models.NormalizeCurrency(value)
+	if !ok {
+		log.Printf("警告: 环境变量 REPORT_CURRENCY 的值 %s 不是有效的币种代码，使用默认值 %s", value, models.DefaultCurrency)
+		return models.DefaultCurrency
+	}
+	return currency
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"account/backend/models"
+	"account/backend/utils"
+)
+
+// ErrInvalidLoginCode 第三方登录凭证无效或已过期
+var ErrInvalidLoginCode = errors.New("登录凭证无效或已过期")
+
+// ExternalIdentity 第三方平台返回的用户身份
+type ExternalIdentity struct {
+	Provider string
+	Subject  string // 第三方用户标识，微信为openid
+	UnionID  string
+}
+
+// IdentityProvider 第三方登录身份提供者，将客户端获取的登录凭证换成用户身份
+type IdentityProvider interface {
+	Name() string
+	Exchange(ctx context.Context, code string) (*ExternalIdentity, error)
+}
+
+// NewWeChatProviderFromEnv 根据环境变量创建微信身份提供者。
+// WECHAT_PROVIDER=fake 时使用本地模拟实现，便于开发和测试；否则需要配置WECHAT_APPID和WECHAT_SECRET
+func NewWeChatProviderFromEnv() IdentityProvider {
+	if utils.GetEnvWithDefault("WECHAT_PROVIDER", "") == "fake" {
+		log.Println("警告: 微信登录使用本地模拟实现，请勿在生产环境使用")
+		return &FakeWeChatProvider{}
+	}
+	return &WeChatProvider{
+		AppID:  utils.GetEnvWithDefault("WECHAT_APPID", ""),
+		Secret: utils.GetEnvWithDefault("WECHAT_SECRET", ""),
+	}
+}
+
+// WeChatProvider 调用微信code2session接口获取小程序用户的openid
+type WeChatProvider struct {
+	AppID      string
+	Secret     string
+	HTTPClient *http.Client
+}
+
+// code2sessionURL 微信小程序登录凭证校验接口
+const code2sessionURL = "https://api.weixin.qq.com/sns/jscode2session"
+
+// Name 返回身份提供者名称
+func (p *WeChatProvider) Name() string {
+	return models.IdentityProviderWeChat
+}
+
+// Exchange 使用wx.login获取的code换取openid
+func (p *WeChatProvider) Exchange(ctx context.Context, code string) (*ExternalIdentity, error) {
+	if p.AppID == "" || p.Secret == "" {
+		return nil, errors.New("未配置微信小程序AppID或Secret")
+	}
+
+	query := url.Values{}
+	query.Set("appid", p.AppID)
+	query.Set("secret", p.Secret)
+	query.Set("js_code", code)
+	query.Set("grant_type", "authorization_code")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, code2sessionURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := p.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求微信登录接口失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OpenID  string `json:"openid"`
+		UnionID string `json:"unionid"`
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析微信登录接口响应失败: %v", err)
+	}
+
+	switch result.ErrCode {
+	case 0:
+	case 40029, 40163: // code无效、code已被使用
+		return nil, ErrInvalidLoginCode
+	default:
+		return nil, fmt.Errorf("微信登录接口返回错误: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	if result.OpenID == "" {
+		return nil, errors.New("微信登录接口未返回openid")
+	}
+
+	return &ExternalIdentity{
+		Provider: models.IdentityProviderWeChat,
+		Subject:  result.OpenID,
+		UnionID:  result.UnionID,
+	}, nil
+}
+
+// FakeWeChatProvider 本地模拟的微信身份提供者，openid由code直接生成，同一code总是得到同一openid
+type FakeWeChatProvider struct{}
+
+// Name 返回身份提供者名称
+func (p *FakeWeChatProvider) Name() string {
+	return models.IdentityProviderWeChat
+}
+
+// Exchange 以"fake-"加code作为openid，code为空或为"invalid"时模拟凭证无效
+func (p *FakeWeChatProvider) Exchange(ctx context.Context, code string) (*ExternalIdentity, error) {
+	code = strings.TrimSpace(code)
+	if code == "" || code == "invalid" {
+		return nil, ErrInvalidLoginCode
+	}
+	return &ExternalIdentity{
+		Provider: models.IdentityProviderWeChat,
+		Subject:  "fake-" + code,
+	}, nil
+}
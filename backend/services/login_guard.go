@@ -0,0 +1,168 @@
+package services
+
+import (
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"account/backend/database"
+	"account/backend/models"
+	"account/backend/utils"
+)
+
+// LoginGuard 按用户名和客户端IP统计登录失败次数，失败过多时临时锁定，
+// 之后每次失败锁定时间加倍
+type LoginGuard struct{}
+
+// loginGuardConfig 登录锁定策略，通过环境变量配置
+type loginGuardConfig struct {
+	maxAttempts int           // 锁定前允许连续失败的次数
+	baseLock    time.Duration // 首次锁定的时长
+	maxLock     time.Duration // 锁定时长上限
+	window      time.Duration // 超过该时间没有再失败则重新计数
+}
+
+func getLoginGuardConfig() loginGuardConfig {
+	return loginGuardConfig{
+		maxAttempts: utils.GetIntEnvWithDefault("LOGIN_MAX_ATTEMPTS", 5),
+		baseLock:    time.Duration(utils.GetIntEnvWithDefault("LOGIN_LOCK_MINUTES", 1)) * time.Minute,
+		maxLock:     time.Duration(utils.GetIntEnvWithDefault("LOGIN_MAX_LOCK_MINUTES", 1440)) * time.Minute,
+		window:      time.Duration(utils.GetIntEnvWithDefault("LOGIN_ATTEMPT_WINDOW_MINUTES", 15)) * time.Minute,
+	}
+}
+
+// NormalizeLoginUsername 与登录查询保持一致，用户名不区分大小写并去除空格
+func NormalizeLoginUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// LockedUntil 检查用户名和IP是否处于锁定状态，返回最晚的解锁时间，未锁定时返回零值
+func (g *LoginGuard) LockedUntil(username, ip string) (time.Time, error) {
+	return lockedUntil(loginLockKeys(username, ip))
+}
+
+// WeChatLockedUntil 检查IP的微信登录是否处于锁定状态，未锁定时返回零值
+func (g *LoginGuard) WeChatLockedUntil(ip string) (time.Time, error) {
+	return lockedUntil(weChatLockKeys(ip))
+}
+
+// lockedUntil 返回keys中最晚的解锁时间，都未锁定时返回零值
+func lockedUntil(keys []models.LoginLockKey) (time.Time, error) {
+	var until time.Time
+	for _, key := range keys {
+		lock, err := database.GetLoginLock(key.Scope, key.Key)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if lock != nil && lock.Locked && lock.LockedUntil.After(until) {
+			until = *lock.LockedUntil
+		}
+	}
+	return until, nil
+}
+
+// ReserveAttempt 在校验密码前为用户名和IP各计一次失败，任一处于锁定状态时不计数并返回最晚的解锁时间。
+// 检查和计数在同一事务中完成，并发的请求也只能在锁定前校验允许的次数；登录成功后由RecordSuccess撤销
+func (g *LoginGuard) ReserveAttempt(username, ip string) (time.Time, error) {
+	return reserveAttempt(loginLockKeys(username, ip))
+}
+
+// ReserveWeChatAttempt 为IP计一次微信登录凭证无效或提交绑定申请，已处于锁定状态时不计数并返回解锁时间，
+// 避免未登录的客户端无限制地登记绑定申请。与账号密码登录分开计数，不影响该IP的密码登录
+func (g *LoginGuard) ReserveWeChatAttempt(ip string) (time.Time, error) {
+	return reserveAttempt(weChatLockKeys(ip))
+}
+
+// reserveAttempt 所有keys都未锁定时各计一次失败，达到次数上限后锁定；否则不计数并返回最晚的解锁时间
+func reserveAttempt(keys []models.LoginLockKey) (time.Time, error) {
+	config := getLoginGuardConfig()
+	now := time.Now()
+
+	var until time.Time
+	err := database.UpdateLoginLocks(keys, func(locks []*models.LoginLock) []models.LoginLock {
+		for _, lock := range locks {
+			if lock != nil && lock.LockedUntil != nil && lock.LockedUntil.After(now) && lock.LockedUntil.After(until) {
+				until = *lock.LockedUntil
+			}
+		}
+		if !until.IsZero() {
+			return nil
+		}
+
+		next := make([]models.LoginLock, len(keys))
+		for i, key := range keys {
+			next[i] = nextLoginLock(locks[i], key, config, now)
+			if next[i].LockedUntil != nil {
+				log.Printf("登录失败次数过多，锁定%s %s 至 %s", key.Scope, key.Key, next[i].LockedUntil.Format("2006-01-02 15:04:05"))
+			}
+		}
+		return next
+	})
+	return until, err
+}
+
+// nextLoginLock 计算再失败一次后的记录。窗口期从最后一次失败或锁定结束时起算，
+// 锁定结束后在窗口期内再次失败会继续累计，锁定时长随之加倍
+func nextLoginLock(lock *models.LoginLock, key models.LoginLockKey, config loginGuardConfig, now time.Time) models.LoginLock {
+	next := models.LoginLock{Scope: key.Scope, Key: key.Key}
+	if lock != nil {
+		next = *lock
+	}
+	lastActive := next.LastFailedAt
+	if next.LockedUntil != nil && next.LockedUntil.After(lastActive) {
+		lastActive = *next.LockedUntil
+	}
+	if next.FailedCount > 0 && now.Sub(lastActive) > config.window {
+		next.FailedCount = 0
+	}
+
+	next.FailedCount++
+	next.LastFailedAt = now
+	next.LockedUntil = nil
+	if next.FailedCount >= config.maxAttempts {
+		lockedUntil := now.Add(lockDuration(config, next.FailedCount-config.maxAttempts))
+		next.LockedUntil = &lockedUntil
+	}
+	return next
+}
+
+// RecordSuccess 登录成功后清除该用户名的失败记录；IP的记录保留到过期，只撤销本次预先计入的一次
+func (g *LoginGuard) RecordSuccess(username, ip string) error {
+	if _, err := database.ClearLoginLock(models.LoginLockScopeUsername, NormalizeLoginUsername(username)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return database.ReleaseLoginAttempt(models.LoginLockKey{Scope: models.LoginLockScopeIP, Key: ip}, getLoginGuardConfig().maxAttempts)
+}
+
+// lockDuration 计算第n次（从0开始）超限时的锁定时长，按指数递增并且不超过上限
+func lockDuration(config loginGuardConfig, exceeded int) time.Duration {
+	duration := float64(config.baseLock) * math.Pow(2, float64(exceeded))
+	if duration > float64(config.maxLock) {
+		return config.maxLock
+	}
+	return time.Duration(duration)
+}
+
+// loginLockKeys 返回需要计数的用户名和IP，为空的不计数
+func loginLockKeys(username, ip string) []models.LoginLockKey {
+	var keys []models.LoginLockKey
+	if username = NormalizeLoginUsername(username); username != "" {
+		keys = append(keys, models.LoginLockKey{Scope: models.LoginLockScopeUsername, Key: username})
+	}
+	if ip != "" {
+		keys = append(keys, models.LoginLockKey{Scope: models.LoginLockScopeIP, Key: ip})
+	}
+	return keys
+}
+
+// weChatLockKeys 返回微信登录需要计数的IP，为空时不计数
+func weChatLockKeys(ip string) []models.LoginLockKey {
+	if ip == "" {
+		return nil
+	}
+	return []models.LoginLockKey{{Scope: models.LoginLockScopeWeChatIP, Key: ip}}
+}
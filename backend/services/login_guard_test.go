@@ -0,0 +1,166 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"account/backend/database"
+	"account/backend/models"
+)
+
+func TestNextLoginLockEscalates(t *testing.T) {
+	config := loginGuardConfig{
+		maxAttempts: 3,
+		baseLock:    time.Minute,
+		maxLock:     24 * time.Hour,
+		window:      15 * time.Minute,
+	}
+	key := models.LoginLockKey{Scope: models.LoginLockScopeIP, Key: "203.0.113.7"}
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	var lock *models.LoginLock
+	fail := func() time.Duration {
+		next := nextLoginLock(lock, key, config, now)
+		lock = &next
+		if next.LockedUntil == nil {
+			return 0
+		}
+		return next.LockedUntil.Sub(now)
+	}
+
+	// 前两次失败不锁定，第三次锁定1分钟
+	for i := 0; i < 2; i++ {
+		if d := fail(); d != 0 {
+			t.Fatalf("第%d次失败不应锁定，实际锁定%s", i+1, d)
+		}
+		now = now.Add(time.Second)
+	}
+
+	// 每次在锁定结束后再失败，锁定时长持续加倍，即使锁定时长超过窗口期也不会重新计数
+	want := time.Minute
+	for i := 0; i < 8; i++ {
+		if d := fail(); d != want {
+			t.Fatalf("第%d次锁定时长为%s，期望%s", i+1, d, want)
+		}
+		now = lock.LockedUntil.Add(time.Second)
+		want *= 2
+	}
+	if want <= config.window {
+		t.Fatalf("测试的锁定时长%s应超过窗口期", want)
+	}
+}
+
+func TestNextLoginLockResetsAfterWindow(t *testing.T) {
+	config := loginGuardConfig{maxAttempts: 3, baseLock: time.Minute, maxLock: time.Hour, window: 15 * time.Minute}
+	key := models.LoginLockKey{Scope: models.LoginLockScopeUsername, Key: "clerk"}
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	// 锁定60分钟，结束后超过窗口期才再次失败，重新计数
+	lockedUntil := now.Add(time.Hour)
+	lock := &models.LoginLock{Scope: key.Scope, Key: key.Key, FailedCount: 9, LastFailedAt: now, LockedUntil: &lockedUntil}
+	next := nextLoginLock(lock, key, config, lockedUntil.Add(config.window+time.Second))
+	if next.FailedCount != 1 || next.LockedUntil != nil {
+		t.Fatalf("超过窗口期后应重新计数，实际失败次数%d，锁定至%v", next.FailedCount, next.LockedUntil)
+	}
+
+	// 窗口期内再次失败继续累计
+	next = nextLoginLock(lock, key, config, lockedUntil.Add(config.window-time.Second))
+	if next.FailedCount != 10 || next.LockedUntil == nil {
+		t.Fatalf("窗口期内应继续累计，实际失败次数%d", next.FailedCount)
+	}
+
+	// 上限生效
+	if d := next.LockedUntil.Sub(lockedUntil.Add(config.window - time.Second)); d != config.maxLock {
+		t.Fatalf("锁定时长应不超过上限，实际%s", d)
+	}
+}
+
+// useLoginLockTestDB 使用临时文件数据库，连接参数与正式环境一致，可以测试并发写入
+func useLoginLockTestDB(t *testing.T) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_txlock=immediate", filepath.Join(t.TempDir(), "test.db"))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+	})
+	if err := database.CreateLoginLockTables(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReserveAttemptConcurrent(t *testing.T) {
+	useLoginLockTestDB(t)
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "3")
+	guard := &LoginGuard{}
+
+	// 并发的请求中只有允许次数内的可以继续校验密码
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			until, err := guard.ReserveAttempt("clerk", "203.0.113.9")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if until.IsZero() {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 3 {
+		t.Errorf("允许校验密码的请求数 = %d, want 3", allowed)
+	}
+	lock, err := database.GetLoginLock(models.LoginLockScopeIP, "203.0.113.9")
+	if err != nil || lock == nil {
+		t.Fatalf("GetLoginLock() = %v, %v", lock, err)
+	}
+	if lock.FailedCount != 3 || !lock.Locked {
+		t.Errorf("失败次数 = %d, 锁定 = %v, want 3 锁定", lock.FailedCount, lock.Locked)
+	}
+}
+
+func TestRecordSuccessReleasesAttempt(t *testing.T) {
+	useLoginLockTestDB(t)
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "3")
+	guard := &LoginGuard{}
+	const ip = "203.0.113.10"
+
+	// 两次失败后第三次登录成功，撤销第三次的计数和由它造成的锁定
+	for i := 0; i < 3; i++ {
+		if until, err := guard.ReserveAttempt("clerk", ip); err != nil || !until.IsZero() {
+			t.Fatalf("第%d次ReserveAttempt() = %v, %v", i+1, until, err)
+		}
+	}
+	if err := guard.RecordSuccess("clerk", ip); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := database.GetLoginLock(models.LoginLockScopeIP, ip)
+	if err != nil || lock == nil {
+		t.Fatalf("GetLoginLock() = %v, %v", lock, err)
+	}
+	if lock.FailedCount != 2 || lock.Locked {
+		t.Errorf("IP失败次数 = %d, 锁定 = %v, want 2 未锁定", lock.FailedCount, lock.Locked)
+	}
+	if lock, _ := database.GetLoginLock(models.LoginLockScopeUsername, "clerk"); lock != nil {
+		t.Errorf("登录成功后用户名的失败记录未清除: %+v", lock)
+	}
+}
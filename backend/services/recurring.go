@@ -0,0 +1,141 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"account/backend/database"
+	"account/backend/models"
+)
+
+// maxRecurringCatchUp 每个模板一次最多补生成的记录数，避免开始日期过早时一次生成过多记录
+const maxRecurringCatchUp = 366
+
+// NextRecurringDate 返回date之后的下一个生成日期，超过结束日期时返回空字符串
+func NextRecurringDate(entry *models.RecurringEntry, date time.Time) string {
+	next := entry.Occurrence(date.AddDate(0, 0, 1)).Format(models.DateLayout)
+	if entry.EndDate != "" && next > entry.EndDate {
+		return ""
+	}
+	return next
+}
+
+// FirstRecurringDate 返回from当天或之后、且不早于开始日期的第一个生成日期，超过结束日期时返回空字符串
+func FirstRecurringDate(entry *models.RecurringEntry, from time.Time) string {
+	if start, err := time.ParseInLocation(models.DateLayout, entry.StartDate, time.Local); err == nil && start.After(from) {
+		from = start
+	}
+	first := entry.Occurrence(from).Format(models.DateLayout)
+	if entry.EndDate != "" && first > entry.EndDate {
+		return ""
+	}
+	return first
+}
+
+// PreviewRecurringDates 返回模板接下来的count个生成日期
+func PreviewRecurringDates(entry *models.RecurringEntry, count int) []string {
+	dates := []string{}
+	next := entry.NextDate
+	for len(dates) < count && next != "" {
+		date, err := time.ParseInLocation(models.DateLayout, next, time.Local)
+		if err != nil {
+			break
+		}
+		dates = append(dates, next)
+		next = NextRecurringDate(entry, date)
+	}
+	return dates
+}
+
+// MaterializeDueRecurringEntries 为所有到期的周期账务模板生成账务记录，包括服务停机期间错过的日期，返回生成的记录数。
+// 是否到期按模板所属店铺时区的当天日期判断
+func MaterializeDueRecurringEntries(now time.Time) (int, error) {
+	// UTC+14是最早进入新一天的时区，先取出所有店铺中可能到期的模板
+	latest := now.UTC().Add(14 * time.Hour).Format(models.DateLayout)
+	entries, err := database.GetDueRecurringEntries(latest)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	locations := map[int64]*time.Location{}
+	for i := range entries {
+		entry := &entries[i]
+		loc, ok := locations[entry.StoreID]
+		if !ok {
+			if loc, err = database.StoreLocation(entry.StoreID); err != nil {
+				log.Printf("获取店铺%d的时区失败: %v", entry.StoreID, err)
+				continue
+			}
+			locations[entry.StoreID] = loc
+		}
+		today := now.In(loc).Format(models.DateLayout)
+
+		for n := 0; n < maxRecurringCatchUp && entry.NextDate != "" && entry.NextDate <= today; n++ {
+			date, err := time.ParseInLocation(models.DateLayout, entry.NextDate, time.Local)
+			if err != nil {
+				log.Printf("周期账务%d的下一次生成日期%q无效: %v", entry.ID, entry.NextDate, err)
+				break
+			}
+			next := NextRecurringDate(entry, date)
+			accountID, skipped, err := database.MaterializeRecurringEntry(entry, entry.NextDate, next)
+			if err != nil {
+				log.Printf("周期账务%d生成%s的记录失败: %v", entry.ID, entry.NextDate, err)
+				break
+			}
+			if accountID > 0 {
+				created++
+				auditRecurringAccount(accountID, entry.StoreID)
+			}
+			if skipped {
+				auditRecurringSkip(entry, entry.NextDate, next)
+			}
+			entry.NextDate = next
+		}
+	}
+	return created, nil
+}
+
+// recurringSchedulerJob 周期账务生成任务在审计日志中的名称
+const recurringSchedulerJob = "recurring-scheduler"
+
+// auditRecurringAccount 记录周期账务生成的账务记录
+func auditRecurringAccount(accountID, storeID int64) {
+	after, err := database.GetAuditSnapshot("accounts", "id", accountID)
+	if err != nil {
+		log.Printf("读取周期账务生成的记录%d失败: %v", accountID, err)
+	}
+	recordSystemAudit(recurringSchedulerJob, models.AuditActionCreate, "account", accountID, storeID, nil, after)
+}
+
+// auditRecurringSkip 记录周期账务因所在月份已结账跳过的日期，便于反结账后手工补录
+func auditRecurringSkip(entry *models.RecurringEntry, date, nextDate string) {
+	recordSystemAudit(recurringSchedulerJob, models.AuditActionSkip, "recurring_entry", entry.ID, entry.StoreID, nil, map[string]interface{}{
+		"skipped_date": date,
+		"next_date":    nextDate,
+		"reason":       models.ErrPeriodClosed.Error(),
+	})
+}
+
+// StartRecurringScheduler 启动后台任务，立即生成一次到期的周期账务记录，之后按interval定时检查
+func StartRecurringScheduler(interval time.Duration) {
+	materialize := func() {
+		created, err := MaterializeDueRecurringEntries(time.Now())
+		if err != nil {
+			log.Printf("生成周期账务记录失败: %v", err)
+			return
+		}
+		if created > 0 {
+			log.Printf("已生成%d条周期账务记录", created)
+		}
+	}
+
+	go func() {
+		materialize()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			materialize()
+		}
+	}()
+}
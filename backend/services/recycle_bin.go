@@ -0,0 +1,68 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"account/backend/database"
+	"account/backend/models"
+	"account/backend/utils"
+)
+
+// RecycleBinRetentionDays 删除的账务记录在回收站中保留的天数，通过环境变量配置，小于等于0表示不自动清理
+func RecycleBinRetentionDays() int {
+	return utils.GetIntEnvWithDefault("RECYCLE_BIN_RETENTION_DAYS", 30)
+}
+
+// recycleBinPurgerJob 回收站清理任务在审计日志中的名称
+const recycleBinPurgerJob = "recycle-bin-purger"
+
+// PurgeRecycleBin 永久删除超过保留天数的回收站记录，返回删除的记录数。每条删除的记录写入一条审计日志
+func PurgeRecycleBin() (int64, error) {
+	days := RecycleBinRetentionDays()
+	if days <= 0 {
+		return 0, nil
+	}
+	before := time.Now().AddDate(0, 0, -days)
+
+	// 先取出附件，记录删除后再删除附件文件
+	attachments, err := database.GetPurgeableAttachments(before)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := database.PurgeDeletedAccounts(before)
+	if err != nil {
+		return 0, err
+	}
+	for i := range attachments {
+		removeAttachmentFiles(&attachments[i])
+	}
+	for _, record := range purged {
+		recordSystemAudit(recycleBinPurgerJob, models.AuditActionPurge, "account",
+			auditRecordID(record, "id"), auditRecordID(record, "store_id"), record, nil)
+	}
+	return int64(len(purged)), nil
+}
+
+// StartRecycleBinPurger 启动后台任务，立即清理一次，之后按interval定时清理过期的回收站记录
+func StartRecycleBinPurger(interval time.Duration) {
+	purge := func() {
+		purged, err := PurgeRecycleBin()
+		if err != nil {
+			log.Printf("清理回收站失败: %v", err)
+			return
+		}
+		if purged > 0 {
+			log.Printf("已永久删除%d条超过%d天的回收站记录", purged, RecycleBinRetentionDays())
+		}
+	}
+
+	go func() {
+		purge()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
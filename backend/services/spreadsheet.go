@@ -0,0 +1,255 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"account/backend/utils"
+)
+
+// ErrUnsupportedSpreadsheet 不支持的表格文件格式
+var ErrUnsupportedSpreadsheet = errors.New("只支持CSV和XLSX格式的文件")
+
+// ReadSpreadsheet 按文件扩展名读取CSV或XLSX文件的全部行，XLSX只读取第一个工作表。
+// 返回的行号与表格中的行号一致，中间的空行以空切片占位
+func ReadSpreadsheet(fileName string, data []byte) ([][]string, error) {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".csv":
+		return readCSV(data)
+	case ".xlsx":
+		return readXLSX(data)
+	default:
+		return nil, ErrUnsupportedSpreadsheet
+	}
+}
+
+// readCSV 读取UTF-8编码的CSV文件，兼容Excel保存时带的BOM
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("CSV文件需使用UTF-8编码")
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows := [][]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV文件格式错误: %v", err)
+		}
+		// csv.Reader会跳过空行，按记录所在的行号放置以保持行号一致
+		line, _ := reader.FieldPos(0)
+		for len(rows) < line-1 {
+			rows = append(rows, []string{})
+		}
+		rows = append(rows, record)
+	}
+}
+
+// xlsxSharedStrings sharedStrings.xml的结构，富文本的各段需要拼接
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+// xlsxSheet 工作表的结构，只读取单元格的位置、类型和值
+type xlsxSheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline struct {
+				Text string `xml:"t"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX 读取XLSX文件第一个工作表的单元格文本，数字按原样返回
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("XLSX文件格式错误")
+	}
+
+	var sharedStrings []string
+	if file := zipFile(archive, "xl/sharedStrings.xml"); file != nil {
+		var sst xlsxSharedStrings
+		if err := decodeZipXML(file, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			text := item.Text
+			for _, run := range item.Runs {
+				text += run.Text
+			}
+			sharedStrings = append(sharedStrings, text)
+		}
+	}
+
+	sheetFile := zipFile(archive, firstSheetPath(archive))
+	if sheetFile == nil {
+		return nil, errors.New("XLSX文件中没有工作表")
+	}
+	var sheet xlsxSheet
+	if err := decodeZipXML(sheetFile, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := [][]string{}
+	for _, row := range sheet.Rows {
+		rowIndex := row.Index
+		if rowIndex <= 0 {
+			rowIndex = len(rows) + 1
+		}
+		for len(rows) < rowIndex {
+			rows = append(rows, []string{})
+		}
+
+		cells := rows[rowIndex-1]
+		for i, cell := range row.Cells {
+			col := columnIndex(cell.Ref)
+			if col < 0 {
+				col = i
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(value)
+				if err != nil || index < 0 || index >= len(sharedStrings) {
+					return nil, errors.New("XLSX文件格式错误")
+				}
+				value = sharedStrings[index]
+			case "inlineStr":
+				value = cell.Inline.Text
+			}
+			cells[col] = value
+		}
+		rows[rowIndex-1] = cells
+	}
+	return rows, nil
+}
+
+// firstSheetPath 通过workbook.xml及其关系文件找到第一个工作表的路径
+func firstSheetPath(archive *zip.Reader) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	workbookFile, relsFile := zipFile(archive, "xl/workbook.xml"), zipFile(archive, "xl/_rels/workbook.xml.rels")
+	if workbookFile == nil || relsFile == nil ||
+		decodeZipXML(workbookFile, &workbook) != nil || decodeZipXML(relsFile, &rels) != nil ||
+		len(workbook.Sheets) == 0 {
+		return fallback
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RelID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/")
+			}
+			return path.Join("xl", rel.Target)
+		}
+	}
+	return fallback
+}
+
+// zipFile 按路径查找压缩包中的文件，不存在时返回nil
+func zipFile(archive *zip.Reader, name string) *zip.File {
+	for _, file := range archive.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// decodeZipXML 解析压缩包中的XML文件
+func decodeZipXML(file *zip.File, v interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("读取%s失败: %v", file.Name, err)
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(reader, 64<<20)).Decode(v); err != nil {
+		return fmt.Errorf("解析%s失败: %v", file.Name, err)
+	}
+	return nil
+}
+
+// columnIndex 将"B3"这样的单元格引用转换为从0开始的列序号，无法解析时返回-1
+func columnIndex(ref string) int {
+	col := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A') + 1
+	}
+	return col - 1
+}
+
+// ExcelSerialTime 将Excel的日期序列号（1900日期系统）转换为时间，用于未设置为文本格式的日期单元格
+func ExcelSerialTime(value string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(value, 64)
+	// 只接受1900年到9999年之间的序列号，避免把普通数字当作日期
+	if err != nil || serial < 1 || serial >= 2958466 {
+		return time.Time{}, false
+	}
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	seconds := int64(serial*86400 + 0.5)
+	return base.Add(time.Duration(seconds) * time.Second), true
+}
+
+// IsFormulaText 判断文本在Excel中是否会被当作公式执行，即以=、+、-、@、制表符或回车开头
+func IsFormulaText(text string) bool {
+	return text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0]))
+}
+
+// EscapeCSVText 在可能被Excel当作公式执行的文本前加上'，使其按文本显示，用于导出CSV的文本列
+func EscapeCSVText(text string) string {
+	if IsFormulaText(text) {
+		return "'" + text
+	}
+	return text
+}
+
+// ImportMaxSize 导入文件的最大字节数，通过环境变量IMPORT_MAX_SIZE_MB配置，默认10MB
+func ImportMaxSize() int64 {
+	return int64(utils.GetIntEnvWithDefault("IMPORT_MAX_SIZE_MB", 10)) << 20
+}
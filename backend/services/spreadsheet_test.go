@@ -0,0 +1,75 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"account/backend/models"
+)
+
+func TestEscapeCSVText(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"日常销售":              "日常销售",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1":                "'+1",
+		"-5元退款":             "'-5元退款",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"\tcmd":             "'\tcmd",
+		"\rcmd":             "'\rcmd",
+		"a=b":               "a=b",
+	}
+	for input, want := range tests {
+		if got := EscapeCSVText(input); got != want {
+			t.Errorf("EscapeCSVText(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestXLSXWriterQuotesFormulaText(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewXLSXWriter(&buf, "Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteHeader("备注", "金额"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRow("=1+1", models.Money(-500)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRow("正常备注", models.Money(500)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := zipFile(archive, "xl/worksheets/sheet1.xml")
+	if file == nil {
+		t.Fatal("缺少工作表")
+	}
+	reader, err := file.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	sheet, _ := io.ReadAll(reader)
+
+	for _, want := range []string{
+		`<c r="A2" s="4" t="inlineStr">`, // 公式文本使用引号前缀样式
+		`<c r="B2" s="1"><v>-5.00</v>`,   // 负数金额仍为数字
+		`<c r="A3" s="0" t="inlineStr">`,
+	} {
+		if !strings.Contains(string(sheet), want) {
+			t.Errorf("工作表中缺少 %s", want)
+		}
+	}
+}
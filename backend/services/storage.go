@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"account/backend/utils"
+)
+
+// FileStorage 文件存储接口，本地磁盘之外可以接入S3等兼容的对象存储
+type FileStorage interface {
+	// Save 保存文件内容，key为存储路径，已存在时覆盖
+	Save(key string, content io.Reader) error
+	// Open 打开文件，不存在时返回os.ErrNotExist
+	Open(key string) (io.ReadCloser, error)
+	// Delete 删除文件，不存在时不报错
+	Delete(key string) error
+}
+
+// ErrInvalidStorageKey 存储路径无效
+var ErrInvalidStorageKey = errors.New("无效的存储路径")
+
+// LocalStorage 将文件保存在本地目录下
+type LocalStorage struct {
+	Root string
+}
+
+// NewLocalStorage 创建以root为根目录的本地存储
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{Root: root}
+}
+
+// path 将存储路径转换为本地文件路径，不允许访问根目录之外的文件
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if key == "" || cleaned == "/" || strings.Contains(key, "..") {
+		return "", ErrInvalidStorageKey
+	}
+	return filepath.Join(s.Root, cleaned), nil
+}
+
+// Save 保存文件，先写入临时文件再重命名，避免留下不完整的文件
+func (s *LocalStorage) Save(key string, content io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open 打开文件
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete 删除文件
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// attachmentStorage 附件使用的存储，默认保存在./data/attachments，可通过ATTACHMENT_DIR修改
+var attachmentStorage FileStorage = NewLocalStorage(utils.GetEnvWithDefault("ATTACHMENT_DIR", "./data/attachments"))
+
+// AttachmentStorage 返回附件使用的存储
+func AttachmentStorage() FileStorage {
+	return attachmentStorage
+}
+
+// SetAttachmentStorage 替换附件使用的存储，需在启动服务前调用
+func SetAttachmentStorage(storage FileStorage) {
+	attachmentStorage = storage
+}
@@ -14,6 +14,9 @@ import (
 // UserService 用户相关服务
 type UserService struct{}
 
+// ErrInvalidCredentials 用户名不存在或密码错误，两种情况返回相同错误以免泄露用户名是否存在
+var ErrInvalidCredentials = errors.New("用户名或密码错误")
+
 // 登录认证
 func (s *UserService) Login(username, password string) (*models.User, error) {
 	var user models.User
@@ -25,14 +28,18 @@ func (s *UserService) Login(username, password string) (*models.User, error) {
 
 	// 修改查询语句，确保不区分大小写以及去除空格
 	query := `SELECT id, username, password, role, nickname, phone, email, avatar, 
-              create_time, update_time, last_login FROM users 
+              create_time, update_time, last_login, must_change_password FROM users 
               WHERE LOWER(TRIM(username)) = LOWER(TRIM(?))`
 	err := database.DB.QueryRow(query, username).Scan(
 		&userID, &user.Username, &hashedPassword, &role,
 		&nickname, &phone, &email, &avatar,
-		&createTime, &updateTime, &lastLogin,
+		&createTime, &updateTime, &lastLogin, &user.MustChangePassword,
 	)
 
+	if err == sql.ErrNoRows {
+		log.Printf("用户验证失败: 用户 %s 不存在", username)
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
 		log.Printf("用户验证失败: %v", err)
 		return nil, err
@@ -42,7 +49,7 @@ func (s *UserService) Login(username, password string) (*models.User, error) {
 	err = utils.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	if err != nil {
 		log.Printf("密码验证失败: %v", err)
-		return nil, errors.New("密码不正确")
+		return nil, ErrInvalidCredentials
 	}
 
 	// 设置用户信息
@@ -95,13 +102,13 @@ func (s *UserService) GetUserByID(id int64) (*models.User, error) {
 	var nullableLastLogin sql.NullTime
 
 	err := database.DB.QueryRow(`
-		SELECT id, username, nickname, role, phone, email, avatar, create_time, update_time, last_login
+		SELECT id, username, nickname, role, phone, email, avatar, create_time, update_time, last_login, must_change_password
 		FROM users
 		WHERE id = ?
 	`, id).Scan(
 		&user.ID, &user.Username, &nullableNickname, &user.Role,
 		&nullablePhone, &nullableEmail, &nullableAvatar, &user.CreateTime,
-		&user.UpdateTime, &nullableLastLogin,
+		&user.UpdateTime, &nullableLastLogin, &user.MustChangePassword,
 	)
 
 	if err != nil {
@@ -139,12 +146,12 @@ func ValidateUser(username, password string) (*models.User, error) {
 
 	// 修改查询语句，确保不区分大小写以及去除空格
 	query := `SELECT id, username, password, role, nickname, phone, email, avatar, 
-              create_time, update_time, last_login FROM users 
+              create_time, update_time, last_login, must_change_password FROM users 
               WHERE LOWER(TRIM(username)) = LOWER(TRIM(?))`
 	err := database.DB.QueryRow(query, username).Scan(
 		&userID, &user.Username, &hashedPassword, &role,
 		&nickname, &phone, &email, &avatar,
-		&createTime, &updateTime, &lastLogin,
+		&createTime, &updateTime, &lastLogin, &user.MustChangePassword,
 	)
 
 	if err != nil {
@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"unicode"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -13,4 +17,64 @@ func CompareHashAndPassword(hashedPassword, password []byte) error {
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
-} 
\ No newline at end of file
+}
+
+// MaxPasswordBytes bcrypt只使用密码的前72个字节，更长的密码会被拒绝
+const MaxPasswordBytes = 72
+
+// PasswordPolicy 密码强度要求，通过环境变量配置
+type PasswordPolicy struct {
+	MinLength  int `json:"min_length"`  // 最小长度
+	MinClasses int `json:"min_classes"` // 至少包含的字符类别数（小写字母、大写字母、数字、符号）
+}
+
+// GetPasswordPolicy 读取密码强度要求，默认至少8位并包含两类字符
+func GetPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:  GetIntEnvWithDefault("PASSWORD_MIN_LENGTH", 8),
+		MinClasses: GetIntEnvWithDefault("PASSWORD_MIN_CLASSES", 2),
+	}
+}
+
+// ValidatePassword 检查密码是否符合密码强度要求，设置或重置密码前都必须调用
+func ValidatePassword(password string) error {
+	policy := GetPasswordPolicy()
+
+	if password == "" {
+		return errors.New("密码不能为空")
+	}
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", policy.MinLength)
+	}
+	if len(password) > MaxPasswordBytes {
+		return fmt.Errorf("密码长度不能超过%d个字节", MaxPasswordBytes)
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsSpace(c):
+			return errors.New("密码不能包含空格")
+		default:
+			hasSymbol = true
+		}
+	}
+
+	classes := 0
+	for _, has := range []bool{hasLower, hasUpper, hasDigit, hasSymbol} {
+		if has {
+			classes++
+		}
+	}
+	if classes < policy.MinClasses {
+		return fmt.Errorf("密码需要至少包含小写字母、大写字母、数字、符号中的%d类", policy.MinClasses)
+	}
+
+	return nil
+}
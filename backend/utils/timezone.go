@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	_ "time/tzdata" // 内置时区数据，服务器缺少时区数据库时也能加载时区
+)
+
+// DefaultTimezone 未配置BUSINESS_TIMEZONE时使用的业务时区
+const DefaultTimezone = "Asia/Shanghai"
+
+var (
+	businessLocation     *time.Location
+	businessLocationOnce sync.Once
+)
+
+// BusinessLocation 业务时区，通过环境变量BUSINESS_TIMEZONE配置，未单独设置时区的店铺按业务时区划分交易日期
+func BusinessLocation() *time.Location {
+	businessLocationOnce.Do(func() {
+		name := GetEnvWithDefault("BUSINESS_TIMEZONE", DefaultTimezone)
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			log.Printf("警告: 环境变量 BUSINESS_TIMEZONE 的值 %s 不是有效的时区，使用默认值 %s", name, DefaultTimezone)
+			loc, _ = time.LoadLocation(DefaultTimezone)
+		}
+		businessLocation = loc
+	})
+	return businessLocation
+}
+
+// LoadLocation 按IANA名称（如Asia/Shanghai）加载时区，name为空时返回业务时区
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return BusinessLocation(), nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("不支持的时区: %s", name)
+	}
+	return time.LoadLocation(name)
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenClaims 令牌中携带的用户身份信息
+type TokenClaims struct {
+	UserID    int64 `json:"uid"`
+	SessionID int64 `json:"sid"`
+	Role      int   `json:"role"`
+	ExpiresAt int64 `json:"exp"`
+	IssuedAt  int64 `json:"iat"`
+}
+
+var (
+	// ErrInvalidToken 令牌格式错误或签名不匹配
+	ErrInvalidToken = errors.New("无效的令牌")
+	// ErrExpiredToken 令牌已过期
+	ErrExpiredToken = errors.New("令牌已过期")
+
+	tokenSecret     []byte
+	tokenSecretOnce sync.Once
+)
+
+// getTokenSecret 获取签名密钥，未配置TOKEN_SECRET时随机生成（重启后旧令牌失效）
+func getTokenSecret() []byte {
+	tokenSecretOnce.Do(func() {
+		secret := GetEnvWithDefault("TOKEN_SECRET", "")
+		if secret == "" {
+			buf := make([]byte, 32)
+			if _, err := rand.Read(buf); err != nil {
+				log.Fatalf("生成令牌密钥失败: %v", err)
+			}
+			secret = hex.EncodeToString(buf)
+			log.Println("警告: 未设置TOKEN_SECRET环境变量，已生成临时密钥，服务重启后需要重新登录")
+		}
+		tokenSecret = []byte(secret)
+	})
+	return tokenSecret
+}
+
+// TokenTTL 返回访问令牌有效期，可通过TOKEN_EXPIRE_HOURS配置，默认24小时
+func TokenTTL() time.Duration {
+	return time.Duration(GetIntEnvWithDefault("TOKEN_EXPIRE_HOURS", 24)) * time.Hour
+}
+
+// RefreshTokenTTL 返回刷新令牌有效期，可通过REFRESH_TOKEN_EXPIRE_DAYS配置，默认30天
+func RefreshTokenTTL() time.Duration {
+	return time.Duration(GetIntEnvWithDefault("REFRESH_TOKEN_EXPIRE_DAYS", 30)) * 24 * time.Hour
+}
+
+// GenerateToken 为用户的某个登录会话签发带过期时间的访问令牌
+func GenerateToken(userID, sessionID int64, role int, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := TokenClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Role:      role,
+		ExpiresAt: expiresAt.Unix(),
+		IssuedAt:  now.Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	return encodedPayload + "." + signToken(encodedPayload), expiresAt, nil
+}
+
+// ParseToken 校验令牌签名和有效期，返回其中的身份信息
+func ParseToken(token string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(signToken(parts[0])), []byte(parts[1])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims TokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
+
+// GenerateRefreshToken 生成随机刷新令牌，返回令牌原文及用于存储的哈希
+func GenerateRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken 计算刷新令牌的SHA-256哈希，数据库中只保存哈希值
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// signToken 使用HMAC-SHA256对令牌负载签名
+func signToken(encodedPayload string) string {
+	mac := hmac.New(sha256.New, getTokenSecret())
+	mac.Write([]byte(encodedPayload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}